@@ -0,0 +1,233 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is one entry of the audit log.
+// Each record holds the hash of the previous record, so removing or changing
+// a record breaks the chain.
+type Record struct {
+	Seq       uint64            `json:"seq"`
+	Timestamp int64             `json:"timestamp"`
+	Actor     string            `json:"actor"`
+	Command   string            `json:"command"`
+	Args      map[string]string `json:"args,omitempty"`
+	Result    string            `json:"result"`
+	TxID      string            `json:"tx_id,omitempty"`
+	Simulated bool              `json:"simulated,omitempty"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash"`
+}
+
+// Log is an append-only audit log, stored as JSON lines.
+type Log struct {
+	lk sync.Mutex
+
+	path     string
+	lastSeq  uint64
+	lastHash string
+}
+
+// Open opens the audit log at the given path, the file is created if it doesn't exist.
+func Open(path string) (*Log, error) {
+	l := &Log{path: path}
+
+	err := readRecords(path, func(rec *Record) error {
+		l.lastSeq = rec.Seq
+		l.lastHash = rec.Hash
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Path returns the file path of the audit log.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append sets the sequence and the hashes of the record and writes it at the end of the log.
+func (l *Log) Append(rec *Record) error {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	rec.Seq = l.lastSeq + 1
+	rec.PrevHash = l.lastHash
+	if rec.Timestamp == 0 {
+		rec.Timestamp = time.Now().Unix()
+	}
+
+	hash, err := rec.calcHash()
+	if err != nil {
+		return err
+	}
+	rec.Hash = hash
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	l.lastSeq = rec.Seq
+	l.lastHash = rec.Hash
+
+	return nil
+}
+
+// Verify checks the hash chain of the audit log and returns the number of valid records.
+func Verify(path string) (int, error) {
+	count := 0
+	prevHash := ""
+
+	err := readRecords(path, func(rec *Record) error {
+		if rec.Seq != uint64(count+1) {
+			return fmt.Errorf("record %d: expected sequence %d", rec.Seq, count+1)
+		}
+
+		if rec.PrevHash != prevHash {
+			return fmt.Errorf("record %d: previous hash doesn't match", rec.Seq)
+		}
+
+		hash, err := rec.calcHash()
+		if err != nil {
+			return err
+		}
+
+		if rec.Hash != hash {
+			return fmt.Errorf("record %d: hash doesn't match, the record is modified", rec.Seq)
+		}
+
+		count++
+		prevHash = rec.Hash
+
+		return nil
+	})
+
+	return count, err
+}
+
+// Filter selects the records in a search. Empty fields match everything.
+type Filter struct {
+	Actor   string
+	Command string
+	TxID    string
+	Text    string
+	Since   time.Time
+	Until   time.Time
+}
+
+func (f *Filter) match(rec *Record) bool {
+	if f.Actor != "" && rec.Actor != f.Actor {
+		return false
+	}
+
+	if f.Command != "" && rec.Command != f.Command {
+		return false
+	}
+
+	if f.TxID != "" && rec.TxID != f.TxID {
+		return false
+	}
+
+	if !f.Since.IsZero() && rec.Timestamp < f.Since.Unix() {
+		return false
+	}
+
+	if !f.Until.IsZero() && rec.Timestamp > f.Until.Unix() {
+		return false
+	}
+
+	if f.Text != "" {
+		data, _ := json.Marshal(rec.Args)
+		if !strings.Contains(string(data), f.Text) && !strings.Contains(rec.Result, f.Text) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Search returns the records of the audit log that match the filter.
+func Search(path string, filter Filter) ([]*Record, error) {
+	records := []*Record{}
+	err := readRecords(path, func(rec *Record) error {
+		if filter.match(rec) {
+			records = append(records, rec)
+		}
+
+		return nil
+	})
+
+	return records, err
+}
+
+func (rec *Record) calcHash() (string, error) {
+	cpy := *rec
+	cpy.Hash = ""
+
+	data, err := json.Marshal(cpy)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func readRecords(path string, fn func(rec *Record) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		rec := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), rec); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
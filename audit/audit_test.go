@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setup(t *testing.T) *Log {
+	t.Helper()
+
+	l, err := Open(path.Join(t.TempDir(), "audit.jsonl"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, l.Append(&Record{
+		Actor:     "discord:123",
+		Command:   "claim",
+		Args:      map[string]string{"testnet-addr": "tpc1p..."},
+		Result:    "success: Reward claimed successfully",
+		TxID:      "tx-1",
+		Timestamp: 1000,
+	}))
+	assert.NoError(t, l.Append(&Record{
+		Actor:     "discord:456",
+		Command:   "booster-whitelist",
+		Args:      map[string]string{"twitter-username": "abcd"},
+		Result:    "error: not found",
+		Timestamp: 2000,
+	}))
+
+	return l
+}
+
+func TestAppend(t *testing.T) {
+	l := setup(t)
+
+	count, err := Verify(l.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	t.Run("reopen continues the chain", func(t *testing.T) {
+		reopened, err := Open(l.Path())
+		assert.NoError(t, err)
+
+		rec := &Record{Actor: "cli", Command: "claim", Result: "error: timeout"}
+		assert.NoError(t, reopened.Append(rec))
+		assert.Equal(t, uint64(3), rec.Seq)
+		assert.NotZero(t, rec.Timestamp)
+
+		count, err := Verify(l.Path())
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("modified record", func(t *testing.T) {
+		l := setup(t)
+
+		data, err := os.ReadFile(l.Path())
+		assert.NoError(t, err)
+		data = []byte(strings.Replace(string(data), "tx-1", "tx-2", 1))
+		assert.NoError(t, os.WriteFile(l.Path(), data, 0o600))
+
+		count, err := Verify(l.Path())
+		assert.EqualError(t, err, "record 1: hash doesn't match, the record is modified")
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("removed record", func(t *testing.T) {
+		l := setup(t)
+
+		data, err := os.ReadFile(l.Path())
+		assert.NoError(t, err)
+		lines := strings.SplitN(string(data), "\n", 2)
+		assert.NoError(t, os.WriteFile(l.Path(), []byte(lines[1]), 0o600))
+
+		_, err = Verify(l.Path())
+		assert.EqualError(t, err, "record 2: expected sequence 1")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := Verify(path.Join(t.TempDir(), "audit.jsonl"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestSearch(t *testing.T) {
+	l := setup(t)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		count  int
+	}{
+		{"everything", Filter{}, 2},
+		{"actor", Filter{Actor: "discord:123"}, 1},
+		{"command", Filter{Command: "booster-whitelist"}, 1},
+		{"transaction", Filter{TxID: "tx-1"}, 1},
+		{"text in arguments", Filter{Text: "abcd"}, 1},
+		{"text in result", Filter{Text: "error"}, 1},
+		{"since", Filter{Since: time.Unix(1500, 0)}, 1},
+		{"until", Filter{Until: time.Unix(1500, 0)}, 1},
+		{"no match", Filter{Actor: "discord:123", Command: "booster-whitelist"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := Search(l.Path(), tt.filter)
+			assert.NoError(t, err)
+			assert.Len(t, records, tt.count)
+		})
+	}
+}
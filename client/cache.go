@@ -0,0 +1,152 @@
+package client
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The queries that are cached by the client manager.
+const (
+	QueryBlockchainInfo    = "blockchain_info"
+	QueryLastBlockTime     = "last_block_time"
+	QueryNetworkInfo       = "network_info"
+	QueryCirculatingSupply = "circulating_supply"
+)
+
+// queryTTLs are the TTLs of the cached queries, by the number of the blocks.
+// The network info is not blockchain data, but it doesn't change much in a few blocks.
+// The circulating supply needs seven queries and it changes a little by each block.
+var queryTTLs = map[string]int{
+	QueryBlockchainInfo:    1,
+	QueryLastBlockTime:     1,
+	QueryNetworkInfo:       3,
+	QueryCirculatingSupply: 6,
+}
+
+// blockQueries are the queries that are invalidated by a new block, before their TTL is passed.
+var blockQueries = []string{QueryBlockchainInfo, QueryLastBlockTime}
+
+// CacheStats are the hits and the misses of a cached query.
+type CacheStats struct {
+	Query  string
+	Hits   uint64
+	Misses uint64
+}
+
+// HitRate returns the share of the calls that are answered by the cache, from 0 to 1.
+func (s *CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+type cacheEntry struct {
+	value     any
+	expiresAt time.Time
+}
+
+// queryCache is a read-through cache of the queries, that is shared by all the callers of the client manager.
+// The TTLs are tied to the block time, and a zero block time disables the cache.
+type queryCache struct {
+	lk sync.Mutex
+
+	blockTime time.Duration
+	entries   map[string]*cacheEntry
+	stats     map[string]*CacheStats
+}
+
+func newQueryCache(blockTime time.Duration) *queryCache {
+	return &queryCache{
+		blockTime: blockTime,
+		entries:   make(map[string]*cacheEntry),
+		stats:     make(map[string]*CacheStats),
+	}
+}
+
+func (c *queryCache) enabled() bool {
+	return c.blockTime > 0
+}
+
+func (c *queryCache) get(query string) (any, bool) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	stats, ok := c.stats[query]
+	if !ok {
+		stats = &CacheStats{Query: query}
+		c.stats[query] = stats
+	}
+
+	entry, ok := c.entries[query]
+	if !ok || time.Now().After(entry.expiresAt) {
+		stats.Misses++
+
+		return nil, false
+	}
+	stats.Hits++
+
+	return entry.value, true
+}
+
+func (c *queryCache) set(query string, value any) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	c.entries[query] = &cacheEntry{
+		value:     value,
+		expiresAt: time.Now().Add(time.Duration(queryTTLs[query]) * c.blockTime),
+	}
+}
+
+// invalidateBlockQueries removes the queries that are changed by a new block.
+func (c *queryCache) invalidateBlockQueries() {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	for query := range c.entries {
+		if slices.Contains(blockQueries, query) {
+			delete(c.entries, query)
+		}
+	}
+}
+
+// snapshot returns a copy of the stats, sorted by the query.
+func (c *queryCache) snapshot() []*CacheStats {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	stats := make([]*CacheStats, 0, len(c.stats))
+	for _, s := range c.stats {
+		copied := *s
+		stats = append(stats, &copied)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Query < stats[j].Query
+	})
+
+	return stats
+}
+
+// cached answers the query from the cache, or calls the fetch function and caches its result.
+// The errors are not cached.
+func cached[T any](cm *Mgr, query string, fetch func() (T, error)) (T, error) {
+	if !cm.cache.enabled() {
+		return fetch()
+	}
+
+	if value, ok := cm.cache.get(query); ok {
+		return value.(T), nil
+	}
+
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+	cm.cache.set(query, value)
+
+	return value, nil
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pactus "github.com/pactus-project/pactus/www/grpc/gen/go"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQueryCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("queries are shared until their TTL", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local")
+		clientMgr.EnableCache(20 * time.Millisecond)
+
+		clients[0].EXPECT().GetBlockchainInfo(ctx).Return(&pactus.GetBlockchainInfoResponse{LastBlockHeight: 100}, nil)
+		clients[0].EXPECT().GetBlockchainInfo(ctx).Return(&pactus.GetBlockchainInfoResponse{LastBlockHeight: 101}, nil)
+
+		for i := 0; i < 3; i++ {
+			info, err := clientMgr.GetBlockchainInfo(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, uint32(100), info.LastBlockHeight)
+		}
+
+		time.Sleep(30 * time.Millisecond)
+		info, err := clientMgr.GetBlockchainInfo(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(101), info.LastBlockHeight)
+
+		stats := clientMgr.CacheStats()
+		assert.Equal(t, []*CacheStats{{Query: QueryBlockchainInfo, Hits: 2, Misses: 2}}, stats)
+		assert.Equal(t, 0.5, stats[0].HitRate())
+	})
+
+	t.Run("new block invalidates the block queries", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local")
+		clientMgr.EnableCache(time.Hour)
+
+		clients[0].EXPECT().GetBlockchainInfo(ctx).Return(&pactus.GetBlockchainInfoResponse{LastBlockHeight: 100}, nil)
+		clients[0].EXPECT().GetNetworkInfo(ctx).Return(&pactus.GetNetworkInfoResponse{ConnectedPeersCount: 5}, nil)
+		_, err := clientMgr.GetBlockchainInfo(ctx)
+		assert.NoError(t, err)
+		_, err = clientMgr.GetNetworkInfo(ctx)
+		assert.NoError(t, err)
+
+		clients[0].EXPECT().GetBlockchainHeight(ctx).Return(uint32(101), nil)
+		clients[0].EXPECT().GetBlock(ctx, uint32(101)).Return(&pactus.GetBlockResponse{Height: 101}, nil)
+		clientMgr.publishNewBlocks(ctx, 100)
+
+		clients[0].EXPECT().GetBlockchainInfo(ctx).Return(&pactus.GetBlockchainInfoResponse{LastBlockHeight: 101}, nil)
+		info, err := clientMgr.GetBlockchainInfo(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(101), info.LastBlockHeight)
+
+		netInfo, err := clientMgr.GetNetworkInfo(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(5), netInfo.ConnectedPeersCount)
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local")
+		clientMgr.EnableCache(time.Hour)
+		notFound := status.Error(codes.NotFound, "not found")
+
+		clients[0].EXPECT().GetBlockchainInfo(ctx).Return(nil, notFound)
+		clients[0].EXPECT().GetBlockchainInfo(ctx).Return(&pactus.GetBlockchainInfoResponse{LastBlockHeight: 100}, nil)
+
+		_, err := clientMgr.GetBlockchainInfo(ctx)
+		assert.ErrorIs(t, err, notFound)
+
+		info, err := clientMgr.GetBlockchainInfo(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(100), info.LastBlockHeight)
+	})
+
+	t.Run("cache is disabled by default", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local")
+
+		clients[0].EXPECT().GetNetworkInfo(ctx).Return(&pactus.GetNetworkInfoResponse{}, nil).Times(2)
+
+		_, err := clientMgr.GetNetworkInfo(ctx)
+		assert.NoError(t, err)
+		_, err = clientMgr.GetNetworkInfo(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, clientMgr.CacheStats())
+	})
+}
@@ -2,12 +2,13 @@ package client
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 
 	"github.com/kehiy/RoboPac/log"
+	"github.com/pactus-project/pactus/types/tx/payload"
 	pactus "github.com/pactus-project/pactus/www/grpc/gen/go"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 type Client struct {
@@ -17,14 +18,18 @@ type Client struct {
 	conn              *grpc.ClientConn
 }
 
-func NewClient(endpoint string) (*Client, error) {
-	conn, err := grpc.Dial(endpoint,
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewClient(endpoint string, opts *NodeOptions, connCfg *ConnConfig) (*Client, error) {
+	dialOpts, err := dialOptions(opts, connCfg)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Info("establishing new connection", "addr", endpoint)
+	conn, err := grpc.Dial(endpoint, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("establishing new connection", "addr", endpoint, "tls", opts.TLS)
 
 	return &Client{
 		blockchainClient:  pactus.NewBlockchainClient(conn),
@@ -50,6 +55,22 @@ func (c *Client) GetBlockchainHeight(ctx context.Context) (uint32, error) {
 	return blockchainInfo.LastBlockHeight, nil
 }
 
+func (c *Client) GetBlockHash(ctx context.Context, height uint32) (string, error) {
+	blockHash, err := c.blockchainClient.GetBlockHash(ctx, &pactus.GetBlockHashRequest{Height: height})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(blockHash.Hash), nil
+}
+
+// GetBlock returns the block at the height, with its transactions.
+func (c *Client) GetBlock(ctx context.Context, height uint32) (*pactus.GetBlockResponse, error) {
+	return c.blockchainClient.GetBlock(ctx, &pactus.GetBlockRequest{
+		Height:    height,
+		Verbosity: pactus.BlockVerbosity_BLOCK_TRANSACTIONS,
+	})
+}
+
 func (c *Client) GetNetworkInfo(ctx context.Context) (*pactus.GetNetworkInfoResponse, error) {
 	networkInfo, err := c.networkClient.GetNetworkInfo(ctx, &pactus.GetNetworkInfoRequest{})
 	if err != nil {
@@ -96,9 +117,14 @@ func (c *Client) GetValidatorInfoByNumber(ctx context.Context, num int32) (*pact
 }
 
 func (c *Client) TransactionData(ctx context.Context, hash string) (*pactus.TransactionInfo, error) {
+	id, err := hex.DecodeString(hash)
+	if err != nil {
+		return nil, err
+	}
+
 	data, err := c.transactionClient.GetTransaction(ctx,
 		&pactus.GetTransactionRequest{
-			Id:        []byte(hash),
+			Id:        id,
 			Verbosity: pactus.TransactionVerbosity_TRANSACTION_DATA,
 		})
 	if err != nil {
@@ -131,9 +157,15 @@ func (c *Client) GetNodeInfo(ctx context.Context) (*pactus.GetNodeInfoResponse,
 	return info, err
 }
 
+// GetTransactionData returns the transaction by its hex encoded ID.
 func (c *Client) GetTransactionData(ctx context.Context, txID string) (*pactus.GetTransactionResponse, error) {
+	id, err := hex.DecodeString(txID)
+	if err != nil {
+		return nil, err
+	}
+
 	return c.transactionClient.GetTransaction(ctx, &pactus.GetTransactionRequest{
-		Id:        []byte(txID),
+		Id:        id,
 		Verbosity: pactus.TransactionVerbosity_TRANSACTION_DATA,
 	})
 }
@@ -149,6 +181,31 @@ func (c *Client) GetBalance(ctx context.Context, address string) (int64, error)
 	return account.Account.Balance, nil
 }
 
+// CalculateFee returns the fee of a transaction with the amount and the payload type.
+func (c *Client) CalculateFee(ctx context.Context, amount int64, payloadType payload.Type) (int64, error) {
+	res, err := c.transactionClient.CalculateFee(ctx, &pactus.CalculateFeeRequest{
+		Amount:      amount,
+		PayloadType: pactus.PayloadType(payloadType),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.Fee, nil
+}
+
+// BroadcastTransaction sends the signed transaction and returns its hex encoded ID.
+func (c *Client) BroadcastTransaction(ctx context.Context, signedRawTx []byte) (string, error) {
+	res, err := c.transactionClient.BroadcastTransaction(ctx, &pactus.BroadcastTransactionRequest{
+		SignedRawTransaction: signedRawTx,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(res.Id), nil
+}
+
 func (c *Client) Close() error {
 	return c.conn.Close()
 }
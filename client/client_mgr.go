@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -12,25 +14,38 @@ import (
 	pactus "github.com/pactus-project/pactus/www/grpc/gen/go"
 )
 
+// healthCheckInterval is how often the height and the latency of the nodes are checked.
+const healthCheckInterval = time.Minute
+
+// ErrNoClient is returned when no client is added to the manager.
+var ErrNoClient = errors.New("no RPC node is added")
+
+// Mgr sends the queries to the healthiest node.
+// If a node fails, the query is sent to the next healthy node, so one node being down doesn't fail the queries.
 type Mgr struct {
 	valMapLock sync.RWMutex
 	valMap     map[string]*pactus.PeerInfo
 
-	ctx     context.Context
-	clients []IClient
+	ctx    context.Context
+	nodes  []*node
+	events *Bus
+	cache  *queryCache
 }
 
 func NewClientMgr(ctx context.Context) *Mgr {
 	return &Mgr{
-		clients:    make([]IClient, 0),
+		nodes:      make([]*node, 0),
 		valMap:     make(map[string]*pactus.PeerInfo),
 		valMapLock: sync.RWMutex{},
 		ctx:        ctx,
+		events:     NewBus(),
+		cache:      newQueryCache(0),
 	}
 }
 
 func (cm *Mgr) Start() {
 	ticker := time.NewTicker(30 * time.Minute)
+	healthTicker := time.NewTicker(healthCheckInterval)
 
 	go func() {
 		for {
@@ -41,6 +56,9 @@ func (cm *Mgr) Start() {
 			case <-ticker.C:
 				logger.Info("updating validator map started")
 				cm.updateValMap()
+
+			case <-healthTicker.C:
+				cm.checkHealth()
 			}
 		}
 	}()
@@ -49,9 +67,9 @@ func (cm *Mgr) Start() {
 }
 
 func (cm *Mgr) Stop() {
-	for addr, c := range cm.clients {
-		if err := c.Close(); err != nil {
-			log.Error("could not close connection to RPC node", "err", err, "RPCAddr", addr)
+	for _, n := range cm.nodes {
+		if err := n.client.Close(); err != nil {
+			log.Error("could not close connection to RPC node", "err", err, "RPCAddr", n.name)
 		}
 	}
 }
@@ -59,11 +77,15 @@ func (cm *Mgr) Stop() {
 func (cm *Mgr) updateValMap() {
 	freshValMap := make(map[string]*pactus.PeerInfo)
 
-	for _, c := range cm.clients {
-		networkInfo, err := c.GetNetworkInfo(cm.ctx)
+	for _, n := range cm.nodes {
+		start := time.Now()
+		networkInfo, err := n.client.GetNetworkInfo(cm.ctx)
 		if err != nil {
+			cm.recordFailure(n, err)
+
 			continue
 		}
+		n.succeeded(time.Since(start))
 
 		if networkInfo == nil {
 			logger.Warn("network info is nil")
@@ -92,125 +114,271 @@ func (cm *Mgr) updateValMap() {
 	logger.Info("validator map updated successfully")
 }
 
-// AddClient should call before Start.
-func (cm *Mgr) AddClient(c IClient) {
-	cm.clients = append(cm.clients, c)
+// checkHealth checks the height and the latency of all the nodes, even the ones with an open circuit.
+func (cm *Mgr) checkHealth() {
+	for _, n := range cm.nodes {
+		start := time.Now()
+		height, err := n.client.GetBlockchainHeight(cm.ctx)
+		if err != nil {
+			cm.recordFailure(n, err)
+
+			continue
+		}
+		n.succeeded(time.Since(start))
+		n.setHeight(height)
+	}
 }
 
-// NOTE: local client is always the first client.
-func (cm *Mgr) getLocalClient() IClient {
-	return cm.clients[0]
+// AddClient should call before Start.
+// The name of the client is shown in its health, e.g. the address of the node.
+func (cm *Mgr) AddClient(name string, c IClient) {
+	cm.nodes = append(cm.nodes, newNode(name, c))
 }
 
-func (cm *Mgr) GetRandomClient() IClient {
-	for _, c := range cm.clients {
-		return c
-	}
+// EnableCache caches the hot queries for a few blocks, it should call before Start.
+// The block time is the TTL of the queries that change by each block.
+func (cm *Mgr) EnableCache(blockTime time.Duration) {
+	cm.cache = newQueryCache(blockTime)
+}
 
-	return nil
+// CacheStats returns the hits and the misses of the cached queries.
+func (cm *Mgr) CacheStats() []*CacheStats {
+	return cm.cache.snapshot()
 }
 
-func (cm *Mgr) GetBlockchainInfo() (*pactus.GetBlockchainInfoResponse, error) {
-	localClient := cm.getLocalClient()
-	info, err := localClient.GetBlockchainInfo(cm.ctx)
-	if err != nil {
-		return nil, err
+func (cm *Mgr) recordFailure(n *node, err error) {
+	if n.failed(err) {
+		logger.Warn("circuit of the node is opened", "node", n.name, "err", err)
 	}
-	return info, nil
 }
 
-func (cm *Mgr) GetBlockchainHeight() (uint32, error) {
-	localClient := cm.getLocalClient()
-	height, err := localClient.GetBlockchainHeight(cm.ctx)
-	if err != nil {
-		return 0, err
+// maxHeight returns the height of the highest node.
+func (cm *Mgr) maxHeight() uint32 {
+	maxHeight := uint32(0)
+	for _, n := range cm.nodes {
+		n.lk.Lock()
+		maxHeight = max(maxHeight, n.height)
+		n.lk.Unlock()
 	}
-	return height, nil
+
+	return maxHeight
 }
 
-func (cm *Mgr) GetLastBlockTime() (uint32, uint32) {
-	localClient := cm.getLocalClient()
-	lastBlockTime, lastBlockHeight, err := localClient.LastBlockTime(cm.ctx)
-	if err != nil {
-		return 0, 0
+// Health returns the health of the nodes, in the order that they are added.
+func (cm *Mgr) Health() []*NodeHealth {
+	now := time.Now()
+	maxHeight := cm.maxHeight()
+
+	health := make([]*NodeHealth, 0, len(cm.nodes))
+	for _, n := range cm.nodes {
+		health = append(health, n.health(now, maxHeight))
 	}
 
-	return lastBlockTime, lastBlockHeight
+	return health
 }
 
-func (cm *Mgr) GetNetworkInfo() (*pactus.GetNetworkInfoResponse, error) {
-	for _, c := range cm.clients {
-		info, err := c.GetNetworkInfo(cm.ctx)
-		if err != nil {
-			continue
+// candidates returns the nodes in the order that they are tried.
+// The available nodes are sorted by their height lag and latency, the local node is the first one on ties.
+// The nodes with an open circuit are tried at last, so the queries are sent even if all the circuits are open.
+func (cm *Mgr) candidates() []*node {
+	now := time.Now()
+	maxHeight := cm.maxHeight()
+
+	type candidate struct {
+		node   *node
+		health *NodeHealth
+	}
+
+	available := make([]candidate, 0, len(cm.nodes))
+	open := make([]candidate, 0)
+	for _, n := range cm.nodes {
+		c := candidate{node: n, health: n.health(now, maxHeight)}
+		if n.isAvailable(now) {
+			available = append(available, c)
+		} else {
+			open = append(open, c)
+		}
+	}
+
+	sort.SliceStable(available, func(i, j int) bool {
+		hi, hj := available[i].health, available[j].health
+		if hi.IsHealthy() != hj.IsHealthy() {
+			return hi.IsHealthy()
 		}
-		return info, nil
+
+		return hi.Latency < hj.Latency
+	})
+
+	sort.SliceStable(open, func(i, j int) bool {
+		return open[i].health.OpenUntil.Before(open[j].health.OpenUntil)
+	})
+
+	nodes := make([]*node, 0, len(cm.nodes))
+	for _, c := range append(available, open...) {
+		nodes = append(nodes, c.node)
 	}
 
-	return nil, errors.New("unable to get network info")
+	return nodes
 }
 
-func (cm *Mgr) FindPublicKey(address string, firstVal bool) (string, error) {
-	peerInfo, err := cm.GetPeerInfo(address)
-	if err != nil {
-		return "", err
+// withFailover calls the function with the healthiest node, and with the next ones if the node fails.
+// The errors that are caused by the request, like a validator that is not found, are returned right away.
+func withFailover[T any](ctx context.Context, cm *Mgr, fn func(c IClient) (T, error)) (T, error) {
+	var zero T
+	if len(cm.nodes) == 0 {
+		return zero, ErrNoClient
 	}
 
-	for i, addr := range peerInfo.ConsensusAddress {
-		if addr == address {
-			if firstVal && i != 0 {
-				return "", errors.New("please enter the first validator address")
-			}
-			return peerInfo.ConsensusKeys[i], nil
+	var err error
+	for _, n := range cm.candidates() {
+		start := time.Now()
+		var res T
+		res, err = fn(n.client)
+		if err == nil {
+			n.succeeded(time.Since(start))
+
+			return res, nil
 		}
+
+		// The invalid requests are answered by the node, but they are not counted in its health.
+		if !isNodeFailure(err) {
+			return res, err
+		}
+
+		if ctx.Err() != nil {
+			return zero, err
+		}
+
+		cm.recordFailure(n, err)
+		logger.Debug("node failed, trying the next one", "node", n.name, "err", err)
 	}
 
-	panic("unreachable")
+	return zero, err
 }
 
-func (cm *Mgr) GetPeerInfo(address string) (*pactus.PeerInfo, error) {
-	cm.valMapLock.Lock()
-	defer cm.valMapLock.Unlock()
-
-	peerInfo, ok := cm.valMap[address]
-	if !ok {
-		return nil, fmt.Errorf("peer does not exist with this address: %v", address)
+// GetRandomClient returns the healthiest client.
+func (cm *Mgr) GetRandomClient() IClient {
+	for _, n := range cm.candidates() {
+		return n.client
 	}
 
-	return peerInfo, nil
+	return nil
+}
+
+func (cm *Mgr) GetBlockchainInfo(ctx context.Context) (*pactus.GetBlockchainInfoResponse, error) {
+	return cached(cm, QueryBlockchainInfo, func() (*pactus.GetBlockchainInfoResponse, error) {
+		return withFailover(ctx, cm, func(c IClient) (*pactus.GetBlockchainInfoResponse, error) {
+			return c.GetBlockchainInfo(ctx)
+		})
+	})
+}
+
+func (cm *Mgr) GetBlockchainHeight(ctx context.Context) (uint32, error) {
+	return withFailover(ctx, cm, func(c IClient) (uint32, error) {
+		return c.GetBlockchainHeight(ctx)
+	})
 }
 
-func (cm *Mgr) GetValidatorInfo(address string) (*pactus.GetValidatorResponse, error) {
-	localClient := cm.getLocalClient()
-	val, err := localClient.GetValidatorInfo(cm.ctx, address)
+func (cm *Mgr) GetLastBlockTime(ctx context.Context) (uint32, uint32) {
+	type blockTime struct {
+		time   uint32
+		height uint32
+	}
+
+	bt, err := cached(cm, QueryLastBlockTime, func() (blockTime, error) {
+		return withFailover(ctx, cm, func(c IClient) (blockTime, error) {
+			lastBlockTime, lastBlockHeight, err := c.LastBlockTime(ctx)
+
+			return blockTime{time: lastBlockTime, height: lastBlockHeight}, err
+		})
+	})
 	if err != nil {
-		return nil, err
+		return 0, 0
 	}
-	return val, nil
+
+	return bt.time, bt.height
 }
 
-func (cm *Mgr) GetValidatorInfoByNumber(num int32) (*pactus.GetValidatorResponse, error) {
-	localClient := cm.getLocalClient()
-	val, err := localClient.GetValidatorInfoByNumber(cm.ctx, num)
+func (cm *Mgr) GetNetworkInfo(ctx context.Context) (*pactus.GetNetworkInfoResponse, error) {
+	return cached(cm, QueryNetworkInfo, func() (*pactus.GetNetworkInfoResponse, error) {
+		return withFailover(ctx, cm, func(c IClient) (*pactus.GetNetworkInfoResponse, error) {
+			return c.GetNetworkInfo(ctx)
+		})
+	})
+}
+
+func (cm *Mgr) FindPublicKey(ctx context.Context, address string, firstVal bool) (string, error) {
+	peerInfo, err := cm.GetPeerInfo(ctx, address)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+
+	i := slices.Index(peerInfo.ConsensusAddress, address)
+	if i < 0 || i >= len(peerInfo.ConsensusKeys) {
+		return "", fmt.Errorf("no public key is found for the address: %v", address)
+	}
+
+	if firstVal && i != 0 {
+		return "", errors.New("please enter the first validator address")
 	}
-	return val, nil
+
+	return peerInfo.ConsensusKeys[i], nil
 }
 
-func (cm *Mgr) GetTransactionData(txID string) (*pactus.GetTransactionResponse, error) {
-	localClient := cm.getLocalClient()
-	txData, err := localClient.GetTransactionData(cm.ctx, txID)
+// GetPeerInfo returns the peer of the validator address from the validator map.
+// A validator that is connected after the map is updated is looked up in the network info of the nodes.
+func (cm *Mgr) GetPeerInfo(ctx context.Context, address string) (*pactus.PeerInfo, error) {
+	cm.valMapLock.RLock()
+	peerInfo, ok := cm.valMap[address]
+	cm.valMapLock.RUnlock()
+	if ok {
+		return peerInfo, nil
+	}
+
+	networkInfo, err := cm.GetNetworkInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return txData, nil
+
+	for _, p := range networkInfo.ConnectedPeers {
+		if slices.Contains(p.ConsensusAddress, address) {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("peer does not exist with this address: %v", address)
+}
+
+func (cm *Mgr) GetValidatorInfo(ctx context.Context, address string) (*pactus.GetValidatorResponse, error) {
+	return withFailover(ctx, cm, func(c IClient) (*pactus.GetValidatorResponse, error) {
+		return c.GetValidatorInfo(ctx, address)
+	})
 }
 
-func (cm *Mgr) GetCirculatingSupply() (int64, error) {
-	localClient := cm.getLocalClient()
+func (cm *Mgr) GetValidatorInfoByNumber(ctx context.Context, num int32) (*pactus.GetValidatorResponse, error) {
+	return withFailover(ctx, cm, func(c IClient) (*pactus.GetValidatorResponse, error) {
+		return c.GetValidatorInfoByNumber(ctx, num)
+	})
+}
+
+func (cm *Mgr) GetTransactionData(ctx context.Context, txID string) (*pactus.GetTransactionResponse, error) {
+	return withFailover(ctx, cm, func(c IClient) (*pactus.GetTransactionResponse, error) {
+		return c.GetTransactionData(ctx, txID)
+	})
+}
+
+// GetCirculatingSupply calculates the circulating supply by the balances of the reserved addresses.
+// All the queries are sent to the same node, so they are from the same height.
+func (cm *Mgr) GetCirculatingSupply(ctx context.Context) (int64, error) {
+	return cached(cm, QueryCirculatingSupply, func() (int64, error) {
+		return withFailover(ctx, cm, func(c IClient) (int64, error) {
+			return circulatingSupply(ctx, c)
+		})
+	})
+}
 
-	height, err := localClient.GetBlockchainInfo(cm.ctx)
+func circulatingSupply(ctx context.Context, localClient IClient) (int64, error) {
+	height, err := localClient.GetBlockchainInfo(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -225,32 +393,32 @@ func (cm *Mgr) GetCirculatingSupply() (int64, error) {
 	var addr5Out int64 = 0 // warm wallet
 	var addr6Out int64 = 0 // warm wallet
 
-	balance1, err := localClient.GetBalance(cm.ctx, "pc1z2r0fmu8sg2ffa0tgrr08gnefcxl2kq7wvquf8z")
+	balance1, err := localClient.GetBalance(ctx, "pc1z2r0fmu8sg2ffa0tgrr08gnefcxl2kq7wvquf8z")
 	if err == nil {
 		addr1Out = 8_400_000_000_000_000 - balance1
 	}
 
-	balance2, err := localClient.GetBalance(cm.ctx, "pc1zprhnvcsy3pthekdcu28cw8muw4f432hkwgfasv")
+	balance2, err := localClient.GetBalance(ctx, "pc1zprhnvcsy3pthekdcu28cw8muw4f432hkwgfasv")
 	if err == nil {
 		addr2Out = 6_300_000_000_000_000 - balance2
 	}
 
-	balance3, err := localClient.GetBalance(cm.ctx, "pc1znn2qxsugfrt7j4608zvtnxf8dnz8skrxguyf45")
+	balance3, err := localClient.GetBalance(ctx, "pc1znn2qxsugfrt7j4608zvtnxf8dnz8skrxguyf45")
 	if err == nil {
 		addr3Out = 4_200_000_000_000_000 - balance3
 	}
 
-	balance4, err := localClient.GetBalance(cm.ctx, "pc1zs64vdggjcshumjwzaskhfn0j9gfpkvche3kxd3")
+	balance4, err := localClient.GetBalance(ctx, "pc1zs64vdggjcshumjwzaskhfn0j9gfpkvche3kxd3")
 	if err == nil {
 		addr4Out = 2_100_000_000_000_000 - balance4
 	}
 
-	balance5, err := localClient.GetBalance(cm.ctx, "pc1zuavu4sjcxcx9zsl8rlwwx0amnl94sp0el3u37g")
+	balance5, err := localClient.GetBalance(ctx, "pc1zuavu4sjcxcx9zsl8rlwwx0amnl94sp0el3u37g")
 	if err == nil {
 		addr5Out = 420_000_000_000_000 - balance5
 	}
 
-	balance6, err := localClient.GetBalance(cm.ctx, "pc1zf0gyc4kxlfsvu64pheqzmk8r9eyzxqvxlk6s6t")
+	balance6, err := localClient.GetBalance(ctx, "pc1zf0gyc4kxlfsvu64pheqzmk8r9eyzxqvxlk6s6t")
 	if err == nil {
 		addr6Out = 210_000_000_000_000 - balance6
 	}
@@ -2,11 +2,16 @@ package client
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	pactus "github.com/pactus-project/pactus/www/grpc/gen/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	gomock "go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func setup(t *testing.T) (*Mgr, *MockIClient) {
@@ -15,9 +20,9 @@ func setup(t *testing.T) (*Mgr, *MockIClient) {
 	mockClient := NewMockIClient(ctrl)
 
 	clientMgr := NewClientMgr(context.Background())
-	clientMgr.AddClient(mockClient)
+	clientMgr.AddClient("local", mockClient)
 
-	mockClient.EXPECT().GetNetworkInfo(clientMgr.ctx).Return(
+	mockClient.EXPECT().GetNetworkInfo(gomock.Any()).Return(
 		&pactus.GetNetworkInfoResponse{
 			ConnectedPeers: []*pactus.PeerInfo{
 				{
@@ -39,28 +44,348 @@ func setup(t *testing.T) (*Mgr, *MockIClient) {
 
 func TestFindPublicKey(t *testing.T) {
 	clientMgr, _ := setup(t)
+	ctx := context.Background()
 
 	t.Run("not found", func(t *testing.T) {
-		pubKey, err := clientMgr.FindPublicKey("not-exists", false)
+		pubKey, err := clientMgr.FindPublicKey(ctx, "not-exists", false)
 		assert.Error(t, err)
 		assert.Empty(t, pubKey)
 	})
 
 	t.Run("not first", func(t *testing.T) {
-		pubKey, err := clientMgr.FindPublicKey("addr-4", true)
+		pubKey, err := clientMgr.FindPublicKey(ctx, "addr-4", true)
 		assert.Error(t, err)
 		assert.Empty(t, pubKey)
 	})
 
 	t.Run("first-ok", func(t *testing.T) {
-		pubKey, err := clientMgr.FindPublicKey("addr-3", true)
+		pubKey, err := clientMgr.FindPublicKey(ctx, "addr-3", true)
 		assert.NoError(t, err)
 		assert.Equal(t, pubKey, "pubKey-3")
 	})
 
 	t.Run("any-ok", func(t *testing.T) {
-		pubKey, err := clientMgr.FindPublicKey("addr-4", false)
+		pubKey, err := clientMgr.FindPublicKey(ctx, "addr-4", false)
 		assert.NoError(t, err)
 		assert.Equal(t, pubKey, "pubKey-4")
 	})
+
+	t.Run("validator connected after the map is updated", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local")
+		clients[0].EXPECT().GetNetworkInfo(ctx).Return(&pactus.GetNetworkInfoResponse{
+			ConnectedPeers: []*pactus.PeerInfo{
+				{ConsensusKeys: []string{"pubKey-5"}, ConsensusAddress: []string{"addr-5", "addr-6"}},
+			},
+		}, nil).Times(2)
+
+		pubKey, err := clientMgr.FindPublicKey(ctx, "addr-5", true)
+		assert.NoError(t, err)
+		assert.Equal(t, "pubKey-5", pubKey)
+
+		_, err = clientMgr.FindPublicKey(ctx, "addr-6", false)
+		assert.EqualError(t, err, "no public key is found for the address: addr-6")
+	})
+}
+
+func setupNodes(t *testing.T, names ...string) (*Mgr, []*MockIClient) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+
+	clientMgr := NewClientMgr(context.Background())
+	clients := make([]*MockIClient, 0, len(names))
+	for _, name := range names {
+		c := NewMockIClient(ctrl)
+		clientMgr.AddClient(name, c)
+		clients = append(clients, c)
+	}
+
+	return clientMgr, clients
+}
+
+func TestFailover(t *testing.T) {
+	ctx := context.Background()
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	t.Run("next node is tried if the node fails", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local", "remote")
+
+		clients[0].EXPECT().GetValidatorInfo(ctx, "addr").Return(nil, unavailable)
+		clients[1].EXPECT().GetValidatorInfo(ctx, "addr").Return(&pactus.GetValidatorResponse{}, nil)
+
+		val, err := clientMgr.GetValidatorInfo(ctx, "addr")
+		assert.NoError(t, err)
+		assert.NotNil(t, val)
+
+		health := clientMgr.Health()
+		assert.Equal(t, "local", health[0].Name)
+		assert.Equal(t, unavailable.Error(), health[0].LastError)
+		assert.False(t, health[0].CircuitOpen)
+		assert.Empty(t, health[1].LastError)
+	})
+
+	t.Run("request errors are not sent to the other nodes", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local", "remote")
+		notFound := status.Error(codes.NotFound, "validator not found")
+
+		clients[0].EXPECT().GetValidatorInfo(ctx, "addr").Return(nil, notFound)
+
+		_, err := clientMgr.GetValidatorInfo(ctx, "addr")
+		assert.ErrorIs(t, err, notFound)
+		assert.Empty(t, clientMgr.Health()[0].LastError)
+		assert.Zero(t, clientMgr.Health()[0].Latency)
+	})
+
+	t.Run("authentication errors are sent to the other nodes", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local", "remote")
+		unauthenticated := status.Error(codes.Unauthenticated, "invalid authorization")
+		permissionDenied := status.Error(codes.PermissionDenied, "permission denied")
+
+		clients[0].EXPECT().GetValidatorInfo(ctx, "addr").Return(nil, unauthenticated)
+		clients[1].EXPECT().GetValidatorInfo(ctx, "addr").Return(nil, permissionDenied)
+
+		_, err := clientMgr.GetValidatorInfo(ctx, "addr")
+		assert.ErrorIs(t, err, permissionDenied)
+
+		health := clientMgr.Health()
+		assert.Equal(t, unauthenticated.Error(), health[0].LastError)
+		assert.Equal(t, permissionDenied.Error(), health[1].LastError)
+	})
+
+	t.Run("all nodes fail", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local", "remote")
+
+		clients[0].EXPECT().GetTransactionData(ctx, "tx-id").Return(nil, unavailable)
+		clients[1].EXPECT().GetTransactionData(ctx, "tx-id").Return(nil, unavailable)
+
+		_, err := clientMgr.GetTransactionData(ctx, "tx-id")
+		assert.ErrorIs(t, err, unavailable)
+	})
+
+	t.Run("no client", func(t *testing.T) {
+		clientMgr := NewClientMgr(ctx)
+
+		_, err := clientMgr.GetBlockchainHeight(ctx)
+		assert.ErrorIs(t, err, ErrNoClient)
+	})
+
+	t.Run("circuit is opened after consecutive failures", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local", "remote")
+
+		clients[0].EXPECT().GetBlockchainHeight(ctx).Return(uint32(0), unavailable).Times(failureThreshold)
+		clients[1].EXPECT().GetBlockchainHeight(ctx).Return(uint32(100), nil).Times(failureThreshold + 2)
+
+		for i := 0; i < failureThreshold+2; i++ {
+			height, err := clientMgr.GetBlockchainHeight(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, uint32(100), height)
+		}
+
+		health := clientMgr.Health()
+		assert.True(t, health[0].CircuitOpen)
+		assert.False(t, health[0].IsHealthy())
+		assert.WithinDuration(t, time.Now().Add(minOpenDuration), health[0].OpenUntil, time.Second)
+	})
+
+	t.Run("nodes with an open circuit are tried at last", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local", "remote")
+		for _, n := range clientMgr.nodes {
+			for i := 0; i < failureThreshold; i++ {
+				n.failed(unavailable)
+			}
+		}
+
+		gomock.InOrder(
+			clients[0].EXPECT().GetBlockchainInfo(ctx).Return(nil, unavailable),
+			clients[1].EXPECT().GetBlockchainInfo(ctx).Return(&pactus.GetBlockchainInfoResponse{}, nil),
+		)
+
+		_, err := clientMgr.GetBlockchainInfo(ctx)
+		assert.NoError(t, err)
+	})
+
+	t.Run("lagging nodes are tried after the others", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local", "remote")
+
+		clients[0].EXPECT().GetBlockchainHeight(clientMgr.ctx).Return(uint32(100), nil)
+		clients[1].EXPECT().GetBlockchainHeight(clientMgr.ctx).Return(uint32(100+maxHeightLag+1), nil)
+		clientMgr.checkHealth()
+
+		assert.Equal(t, uint32(maxHeightLag+1), clientMgr.Health()[0].HeightLag)
+		assert.False(t, clientMgr.Health()[0].IsHealthy())
+		assert.Equal(t, clients[1], clientMgr.GetRandomClient())
+
+		clients[1].EXPECT().GetNetworkInfo(ctx).Return(&pactus.GetNetworkInfoResponse{NetworkName: "mainnet"}, nil)
+
+		info, err := clientMgr.GetNetworkInfo(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "mainnet", info.NetworkName)
+	})
+}
+
+func TestFlappingNode(t *testing.T) {
+	n := newNode("flapping", nil)
+	err := errors.New("connection reset")
+
+	openDurations := []time.Duration{}
+	for i := 0; i < 7; i++ {
+		for j := 0; j < failureThreshold-1; j++ {
+			assert.False(t, n.failed(err))
+		}
+		assert.True(t, n.failed(err))
+		openDurations = append(openDurations, time.Until(n.openUntil).Round(time.Second))
+
+		// The node comes back for a moment.
+		n.succeeded(time.Millisecond)
+	}
+
+	assert.Equal(t, []time.Duration{
+		minOpenDuration, 2 * minOpenDuration, 4 * minOpenDuration, 8 * minOpenDuration,
+		16 * minOpenDuration, maxOpenDuration, maxOpenDuration,
+	}, openDurations)
+}
+
+func TestCheckConsistency(t *testing.T) {
+	ctx := context.Background()
+
+	chainInfo := func(height uint32, power int64, validators int32) *pactus.GetBlockchainInfoResponse {
+		return &pactus.GetBlockchainInfoResponse{
+			LastBlockHeight: height,
+			LastBlockHash:   []byte{byte(height)},
+			TotalPower:      power,
+			TotalValidators: validators,
+		}
+	}
+
+	t.Run("nodes agree", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local", "remote-1", "remote-2")
+
+		clients[0].EXPECT().GetBlockchainInfo(ctx).Return(chainInfo(100, 1000, 10), nil)
+		clients[1].EXPECT().GetBlockchainInfo(ctx).Return(chainInfo(101, 1000, 10), nil)
+		clients[2].EXPECT().GetBlockchainInfo(ctx).Return(chainInfo(100, 1000, 10), nil)
+		for _, c := range clients {
+			c.EXPECT().GetBlockHash(ctx, uint32(100)).Return("hash-100", nil)
+		}
+
+		report := clientMgr.CheckConsistency(ctx, 10)
+		assert.True(t, report.IsConsistent())
+		assert.Equal(t, uint32(100), report.MajorityHeight)
+		assert.Equal(t, uint32(100), report.CommonHeight)
+		assert.Equal(t, "64", report.Snapshot("local").LastBlockHash)
+		assert.Equal(t, uint32(101), clientMgr.Health()[1].Height)
+	})
+
+	t.Run("lagging, forked and divergent nodes", func(t *testing.T) {
+		clientMgr, clients := setupNodes(t, "local", "remote-1", "remote-2", "remote-3", "remote-4")
+		unavailable := status.Error(codes.Unavailable, "connection refused")
+
+		clients[0].EXPECT().GetBlockchainInfo(ctx).Return(chainInfo(80, 1000, 10), nil)
+		clients[1].EXPECT().GetBlockchainInfo(ctx).Return(chainInfo(100, 1000, 10), nil)
+		clients[2].EXPECT().GetBlockchainInfo(ctx).Return(chainInfo(100, 1000, 10), nil)
+		clients[3].EXPECT().GetBlockchainInfo(ctx).Return(chainInfo(100, 2000, 11), nil)
+		clients[4].EXPECT().GetBlockchainInfo(ctx).Return(nil, unavailable)
+		clients[0].EXPECT().GetBlockHash(ctx, uint32(80)).Return("hash-80", nil)
+		clients[1].EXPECT().GetBlockHash(ctx, uint32(80)).Return("hash-80", nil)
+		clients[2].EXPECT().GetBlockHash(ctx, uint32(80)).Return("hash-80", nil)
+		clients[3].EXPECT().GetBlockHash(ctx, uint32(80)).Return("fork-80", nil)
+
+		report := clientMgr.CheckConsistency(ctx, 10)
+		assert.False(t, report.IsConsistent())
+		assert.Equal(t, uint32(100), report.MajorityHeight)
+		assert.Equal(t, uint32(80), report.CommonHeight)
+		assert.Equal(t, uint32(20), report.Snapshot("local").Lag)
+		assert.Equal(t, []string{"local"}, report.Lagging)
+		assert.Equal(t, []string{"remote-3"}, report.Forked)
+		assert.Equal(t, []string{"remote-3"}, report.Divergent)
+		assert.Equal(t, []string{"remote-4"}, report.Unreachable)
+		assert.ErrorIs(t, report.Snapshot("remote-4").Err, unavailable)
+	})
+}
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	all := bus.Subscribe(10)
+	bonds := bus.Subscribe(1, EventBond)
+
+	bus.Publish(&Event{Type: EventNewBlock, Height: 1})
+	bus.Publish(&Event{Type: EventBond, Height: 1, TxID: "tx-1"})
+	bus.Publish(&Event{Type: EventBond, Height: 1, TxID: "tx-2"})
+
+	assert.Len(t, all.C, 3)
+	assert.Len(t, bonds.C, 1, "the full subscriber misses the event")
+	assert.Equal(t, "tx-1", (<-bonds.C).TxID)
+
+	bonds.Unsubscribe()
+	bonds.Unsubscribe()
+	_, open := <-bonds.C
+	assert.False(t, open)
+
+	bus.Publish(&Event{Type: EventBond, Height: 2})
+	assert.Len(t, all.C, 4)
+}
+
+func TestPublishNewBlocks(t *testing.T) {
+	ctx := context.Background()
+	clientMgr, clients := setupNodes(t, "local")
+	sub := clientMgr.Events().Subscribe(10)
+
+	block := &pactus.GetBlockResponse{
+		Height:    101,
+		Hash:      []byte{0x01},
+		BlockTime: 1700000000,
+		Txs: []*pactus.TransactionInfo{
+			{
+				Id:  []byte{0xaa},
+				Fee: 1000,
+				Payload: &pactus.TransactionInfo_Bond{
+					Bond: &pactus.PayloadBond{Sender: "sender", Receiver: "validator", Stake: 5000},
+				},
+			},
+			{
+				Id: []byte{0xbb},
+				Payload: &pactus.TransactionInfo_Sortition{
+					Sortition: &pactus.PayloadSortition{Address: "validator"},
+				},
+			},
+		},
+	}
+
+	t.Run("follower starts from the current height", func(t *testing.T) {
+		clients[0].EXPECT().GetBlockchainHeight(ctx).Return(uint32(100), nil)
+
+		assert.Equal(t, uint32(100), clientMgr.publishNewBlocks(ctx, 0))
+		assert.Empty(t, sub.C)
+	})
+
+	t.Run("new blocks are published", func(t *testing.T) {
+		clients[0].EXPECT().GetBlockchainHeight(ctx).Return(uint32(101), nil)
+		clients[0].EXPECT().GetBlock(ctx, uint32(101)).Return(block, nil)
+
+		assert.Equal(t, uint32(101), clientMgr.publishNewBlocks(ctx, 100))
+		require.Len(t, sub.C, 3)
+
+		newBlock := <-sub.C
+		assert.Equal(t, EventNewBlock, newBlock.Type)
+		assert.Equal(t, "01", newBlock.BlockHash)
+		assert.Equal(t, 2, newBlock.TxCount)
+
+		bond := <-sub.C
+		assert.Equal(t, EventBond, bond.Type)
+		assert.Equal(t, "aa", bond.TxID)
+		assert.Equal(t, "validator", bond.Receiver)
+		assert.Equal(t, int64(5000), bond.Amount)
+		assert.Equal(t, uint32(101), bond.Height)
+
+		sortition := <-sub.C
+		assert.Equal(t, EventSortition, sortition.Type)
+		assert.Equal(t, "validator", sortition.Receiver)
+	})
+
+	t.Run("failed block is retried", func(t *testing.T) {
+		clients[0].EXPECT().GetBlockchainHeight(ctx).Return(uint32(103), nil)
+		clients[0].EXPECT().GetBlock(ctx, uint32(102)).Return(&pactus.GetBlockResponse{Height: 102}, nil)
+		clients[0].EXPECT().GetBlock(ctx, uint32(103)).Return(nil, status.Error(codes.Unavailable, "connection refused"))
+
+		assert.Equal(t, uint32(102), clientMgr.publishNewBlocks(ctx, 101))
+		assert.Len(t, sub.C, 1)
+	})
 }
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+)
+
+// NodeSnapshot is the state of the blockchain, as it is seen by a node.
+type NodeSnapshot struct {
+	Name            string
+	Height          uint32
+	LastBlockHash   string
+	TotalPower      int64
+	TotalValidators int32
+	// Lag is the number of the blocks that the node is behind the majority height.
+	Lag uint32
+	// CommonHash is the hash of the block at the common height of the report.
+	CommonHash string
+	// Err is the error of the node, if it is not reachable.
+	Err error
+}
+
+// ConsistencyReport compares the state of the blockchain between all the nodes.
+// The lists are the names of the nodes, in the order that they are added.
+type ConsistencyReport struct {
+	CheckedAt time.Time
+	Snapshots []*NodeSnapshot
+	// MajorityHeight is the height that the majority of the reachable nodes have reached.
+	MajorityHeight uint32
+	// CommonHeight is the height that all the reachable nodes have reached.
+	// The hashes of the block at this height are compared, to find the forks.
+	CommonHeight uint32
+	// Lagging are the nodes that are more than the max lag behind the majority height.
+	Lagging []string
+	// Forked are the nodes that have a different block at the common height than the majority of the nodes.
+	Forked []string
+	// Divergent are the nodes that report a different total power or validator count
+	// than the majority of the nodes at the same height.
+	Divergent   []string
+	Unreachable []string
+}
+
+// IsConsistent returns true if all the nodes are reachable and agree with each other.
+func (r *ConsistencyReport) IsConsistent() bool {
+	return len(r.Lagging) == 0 && len(r.Forked) == 0 &&
+		len(r.Divergent) == 0 && len(r.Unreachable) == 0
+}
+
+// Snapshot returns the snapshot of the node by its name, or nil if the node is not added.
+func (r *ConsistencyReport) Snapshot(name string) *NodeSnapshot {
+	for _, s := range r.Snapshots {
+		if s.Name == name {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// CheckConsistency queries the same data from all the nodes in parallel, and compares them.
+// The nodes with an open circuit are queried too, so the report shows the state of all the nodes.
+func (cm *Mgr) CheckConsistency(ctx context.Context, maxLag uint32) *ConsistencyReport {
+	report := &ConsistencyReport{
+		CheckedAt:   time.Now(),
+		Snapshots:   make([]*NodeSnapshot, len(cm.nodes)),
+		Lagging:     []string{},
+		Forked:      []string{},
+		Divergent:   []string{},
+		Unreachable: []string{},
+	}
+
+	cm.forEachNode(func(i int, n *node) {
+		report.Snapshots[i] = cm.snapshot(ctx, n)
+	})
+
+	reachable := make([]*NodeSnapshot, 0, len(report.Snapshots))
+	for _, s := range report.Snapshots {
+		if s.Err != nil {
+			report.Unreachable = append(report.Unreachable, s.Name)
+
+			continue
+		}
+		reachable = append(reachable, s)
+	}
+	if len(reachable) == 0 {
+		return report
+	}
+
+	heights := make([]uint32, 0, len(reachable))
+	for _, s := range reachable {
+		heights = append(heights, s.Height)
+	}
+	slices.Sort(heights)
+	report.MajorityHeight = heights[len(heights)/2]
+	report.CommonHeight = heights[0]
+
+	for _, s := range reachable {
+		if s.Height < report.MajorityHeight {
+			s.Lag = report.MajorityHeight - s.Height
+		}
+		if s.Lag > maxLag {
+			report.Lagging = append(report.Lagging, s.Name)
+		}
+	}
+
+	report.Divergent = divergentNodes(reachable)
+
+	if report.CommonHeight > 0 {
+		cm.forEachNode(func(i int, n *node) {
+			s := report.Snapshots[i]
+			if s.Err != nil {
+				return
+			}
+			s.CommonHash, s.Err = n.client.GetBlockHash(ctx, report.CommonHeight)
+		})
+
+		hashes := make([]string, 0, len(reachable))
+		for _, s := range reachable {
+			if s.Err != nil {
+				report.Unreachable = append(report.Unreachable, s.Name)
+
+				continue
+			}
+			hashes = append(hashes, s.CommonHash)
+		}
+
+		majorityHash := majority(hashes)
+		for _, s := range reachable {
+			if s.Err == nil && s.CommonHash != majorityHash {
+				report.Forked = append(report.Forked, s.Name)
+			}
+		}
+	}
+
+	return report
+}
+
+// forEachNode calls the function for all the nodes in parallel, and waits for them.
+func (cm *Mgr) forEachNode(fn func(i int, n *node)) {
+	wg := sync.WaitGroup{}
+	for i, n := range cm.nodes {
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+			fn(i, n)
+		}(i, n)
+	}
+	wg.Wait()
+}
+
+func (cm *Mgr) snapshot(ctx context.Context, n *node) *NodeSnapshot {
+	s := &NodeSnapshot{Name: n.name}
+
+	start := time.Now()
+	info, err := n.client.GetBlockchainInfo(ctx)
+	if err != nil {
+		if isNodeFailure(err) {
+			cm.recordFailure(n, err)
+		}
+		s.Err = err
+
+		return s
+	}
+	n.succeeded(time.Since(start))
+	n.setHeight(info.LastBlockHeight)
+
+	s.Height = info.LastBlockHeight
+	s.LastBlockHash = hex.EncodeToString(info.LastBlockHash)
+	s.TotalPower = info.TotalPower
+	s.TotalValidators = info.TotalValidators
+
+	return s
+}
+
+// divergentNodes returns the nodes that report a different total power or validator count
+// than the majority of the nodes at the same height.
+// The nodes at different heights are not compared, because the stake changes with the blocks.
+func divergentNodes(snapshots []*NodeSnapshot) []string {
+	type stake struct {
+		power      int64
+		validators int32
+	}
+
+	byHeight := make(map[uint32][]stake)
+	for _, s := range snapshots {
+		byHeight[s.Height] = append(byHeight[s.Height], stake{s.TotalPower, s.TotalValidators})
+	}
+
+	divergent := []string{}
+	for _, s := range snapshots {
+		if majority(byHeight[s.Height]) != (stake{s.TotalPower, s.TotalValidators}) {
+			divergent = append(divergent, s.Name)
+		}
+	}
+
+	return divergent
+}
+
+// majority returns the most common value. On ties, it is the one that reaches the count first.
+func majority[T comparable](values []T) T {
+	var best T
+	counts := make(map[T]int)
+	for _, v := range values {
+		counts[v]++
+		if counts[v] > counts[best] {
+			best = v
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pactus-project/pactus/util/logger"
+)
+
+type EventType string
+
+const (
+	EventNewBlock  EventType = "new_block"
+	EventTransfer  EventType = "transfer"
+	EventBond      EventType = "bond"
+	EventUnbond    EventType = "unbond"
+	EventWithdraw  EventType = "withdraw"
+	EventSortition EventType = "sortition"
+)
+
+// Event is a new block, or a transaction in a new block.
+// The transaction fields are empty for the new block events.
+type Event struct {
+	Type      EventType
+	Height    uint32
+	BlockHash string
+	BlockTime time.Time
+	// TxCount is the number of the transactions in the block.
+	TxCount int
+
+	TxID string
+	// Sender is the sender of a transfer or a bond, or the validator of a withdraw.
+	Sender string
+	// Receiver is the receiver of a transfer or a withdraw, or the validator of a bond, an unbond or a sortition.
+	Receiver string
+	Amount   int64
+	Fee      int64
+}
+
+// Subscription receives the events of its types from the bus.
+type Subscription struct {
+	C <-chan *Event
+
+	ch    chan *Event
+	types []EventType
+	bus   *Bus
+}
+
+// Unsubscribe removes the subscription from the bus and closes its channel.
+func (s *Subscription) Unsubscribe() {
+	s.bus.lk.Lock()
+	defer s.bus.lk.Unlock()
+
+	idx := slices.Index(s.bus.subs, s)
+	if idx < 0 {
+		return
+	}
+	s.bus.subs = slices.Delete(s.bus.subs, idx, idx+1)
+	close(s.ch)
+}
+
+func (s *Subscription) wants(typ EventType) bool {
+	return len(s.types) == 0 || slices.Contains(s.types, typ)
+}
+
+// Bus is an in-process publish-subscribe bus for the blockchain events.
+// A subscriber that doesn't keep up misses the events, it doesn't block the others.
+type Bus struct {
+	lk   sync.RWMutex
+	subs []*Subscription
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subs: make([]*Subscription, 0),
+	}
+}
+
+// Subscribe subscribes to the events of the types, or to all the events if no type is given.
+// The size is the number of the events that are buffered for the subscriber.
+func (b *Bus) Subscribe(size int, types ...EventType) *Subscription {
+	ch := make(chan *Event, size)
+	sub := &Subscription{
+		C:     ch,
+		ch:    ch,
+		types: types,
+		bus:   b,
+	}
+
+	b.lk.Lock()
+	b.subs = append(b.subs, sub)
+	b.lk.Unlock()
+
+	return sub
+}
+
+// Publish sends the event to the subscribers of its type. It never blocks.
+func (b *Bus) Publish(e *Event) {
+	b.lk.RLock()
+	defer b.lk.RUnlock()
+
+	for _, sub := range b.subs {
+		if !sub.wants(e.Type) {
+			continue
+		}
+
+		select {
+		case sub.ch <- e:
+		default:
+			logger.Warn("event subscriber is full, the event is dropped", "type", e.Type, "height", e.Height)
+		}
+	}
+}
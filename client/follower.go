@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/pactus-project/pactus/util/logger"
+	pactus "github.com/pactus-project/pactus/www/grpc/gen/go"
+)
+
+// maxCatchUpBlocks is the number of the missed blocks that are published, when the follower falls behind.
+// The older blocks are skipped.
+const maxCatchUpBlocks = 100
+
+// Events returns the bus that the block follower publishes the events to.
+func (cm *Mgr) Events() *Bus {
+	return cm.events
+}
+
+// FollowBlocks polls the new blocks every interval, and publishes their events to the bus.
+// The blocks are read from the healthiest node, that is the local node while it is healthy.
+// The follower starts from the current height, the old blocks are not published.
+func (cm *Mgr) FollowBlocks(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		lastHeight := uint32(0)
+		for {
+			select {
+			case <-cm.ctx.Done():
+				return
+
+			case <-ticker.C:
+				lastHeight = cm.publishNewBlocks(cm.ctx, lastHeight)
+			}
+		}
+	}()
+}
+
+// publishNewBlocks publishes the events of the blocks after the last height.
+// It returns the height of the last published block, or the current height if the last height is zero.
+func (cm *Mgr) publishNewBlocks(ctx context.Context, lastHeight uint32) uint32 {
+	height, err := cm.GetBlockchainHeight(ctx)
+	if err != nil {
+		logger.Warn("unable to get the blockchain height for the block follower", "err", err)
+
+		return lastHeight
+	}
+
+	if lastHeight == 0 || height <= lastHeight {
+		return max(lastHeight, height)
+	}
+
+	if height-lastHeight > maxCatchUpBlocks {
+		logger.Warn("block follower fell behind, the missed blocks are skipped",
+			"from", lastHeight+1, "to", height-maxCatchUpBlocks)
+		lastHeight = height - maxCatchUpBlocks
+	}
+
+	for h := lastHeight + 1; h <= height; h++ {
+		block, err := withFailover(ctx, cm, func(c IClient) (*pactus.GetBlockResponse, error) {
+			return c.GetBlock(ctx, h)
+		})
+		if err != nil {
+			logger.Warn("unable to get the block for the block follower", "err", err, "height", h)
+
+			return h - 1
+		}
+
+		cm.cache.invalidateBlockQueries()
+		for _, e := range blockEvents(block) {
+			cm.events.Publish(e)
+		}
+	}
+
+	return height
+}
+
+// blockEvents decodes the block into a new block event, and an event for each of its transactions.
+func blockEvents(block *pactus.GetBlockResponse) []*Event {
+	newBlock := &Event{
+		Type:      EventNewBlock,
+		Height:    block.Height,
+		BlockHash: hex.EncodeToString(block.Hash),
+		BlockTime: time.Unix(int64(block.BlockTime), 0),
+		TxCount:   len(block.Txs),
+	}
+
+	events := []*Event{newBlock}
+	for _, tx := range block.Txs {
+		e := &Event{
+			Height:    newBlock.Height,
+			BlockHash: newBlock.BlockHash,
+			BlockTime: newBlock.BlockTime,
+			TxCount:   newBlock.TxCount,
+			TxID:      hex.EncodeToString(tx.Id),
+			Fee:       tx.Fee,
+		}
+
+		switch payload := tx.Payload.(type) {
+		case *pactus.TransactionInfo_Transfer:
+			e.Type = EventTransfer
+			e.Sender = payload.Transfer.Sender
+			e.Receiver = payload.Transfer.Receiver
+			e.Amount = payload.Transfer.Amount
+
+		case *pactus.TransactionInfo_Bond:
+			e.Type = EventBond
+			e.Sender = payload.Bond.Sender
+			e.Receiver = payload.Bond.Receiver
+			e.Amount = payload.Bond.Stake
+
+		case *pactus.TransactionInfo_Unbond:
+			e.Type = EventUnbond
+			e.Receiver = payload.Unbond.Validator
+
+		case *pactus.TransactionInfo_Withdraw:
+			e.Type = EventWithdraw
+			e.Sender = payload.Withdraw.From
+			e.Receiver = payload.Withdraw.To
+			e.Amount = payload.Withdraw.Amount
+
+		case *pactus.TransactionInfo_Sortition:
+			e.Type = EventSortition
+			e.Receiver = payload.Sortition.Address
+
+		default:
+			logger.Debug("unknown transaction payload is skipped", "txID", e.TxID, "height", e.Height)
+
+			continue
+		}
+
+		events = append(events, e)
+	}
+
+	return events
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// failureThreshold is the number of the consecutive failures that open the circuit of a node.
+	failureThreshold = 3
+	// The circuit of a node is open for minOpenDuration at first, and it is doubled each time
+	// the node fails again after that, up to maxOpenDuration. So the flapping nodes are left out longer.
+	minOpenDuration = 30 * time.Second
+	maxOpenDuration = 10 * time.Minute
+	// maxHeightLag is the number of the blocks that a node can be behind the highest node, before it is lagging.
+	maxHeightLag = 10
+)
+
+// NodeHealth is the health of a node, as it is seen by the client manager.
+type NodeHealth struct {
+	Name string
+	// Latency is the moving average of the latency of the successful calls.
+	Latency     time.Duration
+	Height      uint32
+	HeightLag   uint32
+	LastError   string
+	LastErrorAt time.Time
+	// CircuitOpen is true if the node is left out, because of its consecutive failures.
+	CircuitOpen bool
+	OpenUntil   time.Time
+}
+
+// IsHealthy returns true if the node is used for the calls and it is not lagging.
+func (h *NodeHealth) IsHealthy() bool {
+	return !h.CircuitOpen && h.HeightLag <= maxHeightLag
+}
+
+// node is a client with its health.
+type node struct {
+	lk sync.Mutex
+
+	name   string
+	client IClient
+
+	latency   time.Duration
+	height    uint32
+	lastErr   error
+	lastErrAt time.Time
+	failures  int
+	trips     int
+	openUntil time.Time
+}
+
+func newNode(name string, c IClient) *node {
+	return &node{
+		name:   name,
+		client: c,
+	}
+}
+
+// isAvailable returns true if the circuit of the node is closed, or its open duration is passed.
+// After the open duration, the node is tried again and one more failure opens the circuit again.
+func (n *node) isAvailable(now time.Time) bool {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	return !now.Before(n.openUntil)
+}
+
+func (n *node) succeeded(latency time.Duration) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.failures = 0
+	if n.latency == 0 {
+		n.latency = latency
+	} else {
+		n.latency = (4*n.latency + latency) / 5
+	}
+
+	// The node is not flapping anymore, if it is stable long enough after its circuit is closed.
+	if n.trips > 0 && time.Since(n.openUntil) > maxOpenDuration {
+		n.trips = 0
+	}
+}
+
+// failed records the failure of the node. It returns true if the circuit of the node is opened.
+func (n *node) failed(err error) bool {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	now := time.Now()
+	n.lastErr = err
+	n.lastErrAt = now
+	n.failures++
+
+	if n.failures < failureThreshold {
+		return false
+	}
+
+	openDuration := maxOpenDuration
+	if n.trips < 5 {
+		openDuration = min(minOpenDuration<<n.trips, maxOpenDuration)
+	}
+	n.trips++
+	n.openUntil = now.Add(openDuration)
+
+	return true
+}
+
+func (n *node) setHeight(height uint32) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.height = height
+}
+
+func (n *node) health(now time.Time, maxHeight uint32) *NodeHealth {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	h := &NodeHealth{
+		Name:        n.name,
+		Latency:     n.latency,
+		Height:      n.height,
+		HeightLag:   maxHeight - n.height,
+		LastErrorAt: n.lastErrAt,
+		CircuitOpen: now.Before(n.openUntil),
+		OpenUntil:   n.openUntil,
+	}
+	if n.lastErr != nil {
+		h.LastError = n.lastErr.Error()
+	}
+
+	return h
+}
+
+// requestFailureCodes are the gRPC codes that the node returns for the invalid requests.
+// The authentication errors are not here, they are caused by the options of the node,
+// so the other nodes are tried.
+var requestFailureCodes = []codes.Code{
+	codes.NotFound, codes.InvalidArgument, codes.AlreadyExists,
+	codes.FailedPrecondition, codes.OutOfRange,
+}
+
+// isNodeFailure returns true if the error is caused by the node, not by the request.
+// For example a validator that is not found is a valid answer, and it is not asked from the other nodes.
+func isNodeFailure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	return !slices.Contains(requestFailureCodes, status.Code(err))
+}
@@ -9,6 +9,8 @@ import (
 type IClient interface {
 	GetBlockchainInfo(context.Context) (*pactus.GetBlockchainInfoResponse, error)
 	GetBlockchainHeight(context.Context) (uint32, error)
+	GetBlockHash(context.Context, uint32) (string, error)
+	GetBlock(context.Context, uint32) (*pactus.GetBlockResponse, error)
 	LastBlockTime(context.Context) (uint32, uint32, error)
 	GetNetworkInfo(context.Context) (*pactus.GetNetworkInfoResponse, error)
 	GetValidatorInfo(context.Context, string) (*pactus.GetValidatorResponse, error)
@@ -69,6 +69,36 @@ func (mr *MockIClientMockRecorder) GetBalance(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockIClient)(nil).GetBalance), arg0, arg1)
 }
 
+// GetBlock mocks base method.
+func (m *MockIClient) GetBlock(arg0 context.Context, arg1 uint32) (*pactus.GetBlockResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlock", arg0, arg1)
+	ret0, _ := ret[0].(*pactus.GetBlockResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlock indicates an expected call of GetBlock.
+func (mr *MockIClientMockRecorder) GetBlock(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlock", reflect.TypeOf((*MockIClient)(nil).GetBlock), arg0, arg1)
+}
+
+// GetBlockHash mocks base method.
+func (m *MockIClient) GetBlockHash(arg0 context.Context, arg1 uint32) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlockHash", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlockHash indicates an expected call of GetBlockHash.
+func (mr *MockIClientMockRecorder) GetBlockHash(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlockHash", reflect.TypeOf((*MockIClient)(nil).GetBlockHash), arg0, arg1)
+}
+
 // GetBlockchainHeight mocks base method.
 func (m *MockIClient) GetBlockchainHeight(arg0 context.Context) (uint32, error) {
 	m.ctrl.T.Helper()
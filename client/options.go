@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// NodeOptions are the connection options of a node, for the nodes behind a TLS-terminating proxy.
+type NodeOptions struct {
+	// TLS connects to the node over TLS. The system CA pool is used if the CA file is not set.
+	TLS    bool   `json:"tls"`
+	CAFile string `json:"ca_file"`
+	// CertFile and KeyFile are the client certificate, for the proxies that require mutual TLS.
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	// ServerName overrides the name that the certificate of the node is checked against.
+	ServerName string `json:"server_name"`
+	// Metadata is sent with every call, like the authorization header of a proxy.
+	Metadata map[string]string `json:"metadata"`
+}
+
+// BasicCheck checks that the files are set for the TLS connections only, and the client certificate is complete.
+func (o *NodeOptions) BasicCheck() error {
+	if !o.TLS && (o.CAFile != "" || o.CertFile != "" || o.KeyFile != "" || o.ServerName != "") {
+		return errors.New("the TLS files and server name are set, but TLS is off")
+	}
+
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return errors.New("both the cert file and the key file of the client certificate should be set")
+	}
+
+	return nil
+}
+
+// ConnConfig is the keepalive and the reconnect backoff of the node connections.
+type ConnConfig struct {
+	// KeepaliveTime is how long the connection can be idle before it is pinged, zero disables the keepalive.
+	// The nodes close the connections that ping more often than they allow, 5 minutes by default.
+	KeepaliveTime time.Duration
+	// KeepaliveTimeout is how long the ping can wait for its answer, before the connection is closed.
+	KeepaliveTimeout time.Duration
+	// BackoffBaseDelay and BackoffMaxDelay are the first and the longest delays of reconnecting to a node.
+	BackoffBaseDelay time.Duration
+	BackoffMaxDelay  time.Duration
+}
+
+// dialOptions returns the dial options of a node connection.
+func dialOptions(opts *NodeOptions, connCfg *ConnConfig) ([]grpc.DialOption, error) {
+	if err := opts.BasicCheck(); err != nil {
+		return nil, err
+	}
+
+	dialOpts := []grpc.DialOption{}
+	if opts.TLS {
+		tlsConfig, err := loadTLSConfig(opts)
+		if err != nil {
+			return nil, err
+		}
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	if len(opts.Metadata) > 0 {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(&metadataCredentials{
+			metadata:   opts.Metadata,
+			requireTLS: opts.TLS,
+		}))
+	}
+
+	if connCfg.KeepaliveTime > 0 {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    connCfg.KeepaliveTime,
+			Timeout: connCfg.KeepaliveTimeout,
+		}))
+	}
+
+	backoffCfg := backoff.DefaultConfig
+	if connCfg.BackoffBaseDelay > 0 {
+		backoffCfg.BaseDelay = connCfg.BackoffBaseDelay
+	}
+	if connCfg.BackoffMaxDelay > 0 {
+		backoffCfg.MaxDelay = connCfg.BackoffMaxDelay
+	}
+	dialOpts = append(dialOpts, grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoffCfg}))
+
+	return dialOpts, nil
+}
+
+func loadTLSConfig(opts *NodeOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: opts.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the CA file: %w", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate is found in the CA file: %s", opts.CAFile)
+		}
+	}
+
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// metadataCredentials sends the metadata of a node with every call.
+type metadataCredentials struct {
+	metadata   map[string]string
+	requireTLS bool
+}
+
+func (c *metadataCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	return c.metadata, nil
+}
+
+func (c *metadataCredentials) RequireTransportSecurity() bool {
+	return c.requireTLS
+}
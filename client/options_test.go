@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	pactus "github.com/pactus-project/pactus/www/grpc/gen/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authBlockchainServer answers the blockchain info to the calls with the authorization metadata.
+type authBlockchainServer struct {
+	pactus.UnimplementedBlockchainServer
+}
+
+func (*authBlockchainServer) GetBlockchainInfo(ctx context.Context,
+	_ *pactus.GetBlockchainInfoRequest,
+) (*pactus.GetBlockchainInfoResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if auth := md.Get("authorization"); len(auth) == 0 || auth[0] != "Basic dXNlcjpwYXNz" {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization")
+	}
+
+	return &pactus.GetBlockchainInfoResponse{LastBlockHeight: 100}, nil
+}
+
+// tlsNode starts a node behind TLS, with a self-signed certificate for localhost.
+// It returns the address of the node and the path of its CA file.
+func tlsNode(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	caFile := path.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	})))
+	pactus.RegisterBlockchainServer(server, &authBlockchainServer{})
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String(), caFile
+}
+
+func TestNodeOptions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addr, caFile := tlsNode(t)
+	connCfg := &ConnConfig{KeepaliveTime: time.Minute, KeepaliveTimeout: time.Second}
+
+	t.Run("TLS with metadata", func(t *testing.T) {
+		c, err := NewClient(addr, &NodeOptions{
+			TLS:        true,
+			CAFile:     caFile,
+			ServerName: "localhost",
+			Metadata:   map[string]string{"authorization": "Basic dXNlcjpwYXNz"},
+		}, connCfg)
+		require.NoError(t, err)
+		defer func() { _ = c.Close() }()
+
+		height, err := c.GetBlockchainHeight(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(100), height)
+	})
+
+	t.Run("TLS without metadata", func(t *testing.T) {
+		c, err := NewClient(addr, &NodeOptions{TLS: true, CAFile: caFile, ServerName: "localhost"}, connCfg)
+		require.NoError(t, err)
+		defer func() { _ = c.Close() }()
+
+		_, err = c.GetBlockchainHeight(ctx)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := NewClient(addr, &NodeOptions{CAFile: caFile}, connCfg)
+		assert.EqualError(t, err, "the TLS files and server name are set, but TLS is off")
+
+		_, err = NewClient(addr, &NodeOptions{TLS: true, CAFile: path.Join(t.TempDir(), "ca.pem")}, connCfg)
+		assert.ErrorContains(t, err, "unable to read the CA file")
+
+		_, err = NewClient(addr, &NodeOptions{TLS: true, CertFile: caFile, KeyFile: caFile}, connCfg)
+		assert.ErrorContains(t, err, "unable to load the client certificate")
+	})
+}
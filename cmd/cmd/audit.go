@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kehiy/RoboPac/audit"
+	"github.com/spf13/cobra"
+)
+
+func AuditCommand(parentCmd *cobra.Command) {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Verifies and searches the audit log",
+	}
+	parentCmd.AddCommand(auditCmd)
+
+	pathOpt := auditCmd.PersistentFlags().StringP("path", "p", "", "the audit log path")
+	_ = auditCmd.MarkPersistentFlagRequired("path")
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Checks the hash chain of the audit log",
+	}
+	auditCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Run = func(cmd *cobra.Command, _ []string) {
+		count, err := audit.Verify(*pathOpt)
+		if err != nil {
+			kill(cmd, fmt.Errorf("audit log is not valid after %d records: %w", count, err))
+		}
+
+		cmd.Printf("audit log is valid, %d records verified\n", count)
+	}
+
+	searchCmd := &cobra.Command{
+		Use:   "search",
+		Short: "Searches the records of the audit log",
+	}
+	auditCmd.AddCommand(searchCmd)
+
+	actorOpt := searchCmd.Flags().String("actor", "", "the actor, e.g. discord:123456789 or cli")
+	commandOpt := searchCmd.Flags().String("command", "", "the command name, e.g. claim")
+	txOpt := searchCmd.Flags().String("tx", "", "the transaction ID")
+	textOpt := searchCmd.Flags().String("text", "", "a text in the arguments or the result")
+	sinceOpt := searchCmd.Flags().String("since", "", "records after this date (2006-01-02 or RFC3339)")
+	untilOpt := searchCmd.Flags().String("until", "", "records before this date (2006-01-02 or RFC3339)")
+
+	searchCmd.Run = func(cmd *cobra.Command, _ []string) {
+		since, err := parseTime(*sinceOpt)
+		if err != nil {
+			kill(cmd, err)
+		}
+
+		until, err := parseTime(*untilOpt)
+		if err != nil {
+			kill(cmd, err)
+		}
+
+		records, err := audit.Search(*pathOpt, audit.Filter{
+			Actor:   *actorOpt,
+			Command: *commandOpt,
+			TxID:    *txOpt,
+			Text:    *textOpt,
+			Since:   since,
+			Until:   until,
+		})
+		if err != nil {
+			kill(cmd, err)
+		}
+
+		for _, rec := range records {
+			args := make([]string, 0, len(rec.Args))
+			for name, value := range rec.Args {
+				args = append(args, fmt.Sprintf("%s=%s", name, value))
+			}
+			slices.Sort(args)
+
+			cmd.Printf("#%d %s %s %s [%s] %s", rec.Seq, time.Unix(rec.Timestamp, 0).Format(time.RFC3339),
+				rec.Actor, rec.Command, strings.Join(args, " "), rec.Result)
+			if rec.TxID != "" {
+				cmd.Printf(" tx=%s", rec.TxID)
+			}
+			cmd.Println()
+		}
+		cmd.Printf("%d records found\n", len(records))
+	}
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -2,7 +2,12 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/kehiy/RoboPac/config"
@@ -20,6 +25,7 @@ func run(cmd *cobra.Command, args []string) {
 	log.Info("initializing repl...")
 
 	envOpt := cmd.Flags().StringP("env", "e", ".env", "the env file path")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
 	config, err := config.Load(*envOpt)
 	if err != nil {
 		log.Panic("can't load config env", "err", err, "path", *envOpt)
@@ -34,16 +40,38 @@ func run(cmd *cobra.Command, args []string) {
 		log.Panic("could not start discord bot", "err", err)
 	}
 
+	botEngine.RegisterNotifier(engine.FrontendCLI, &replNotifier{cmd: cmd})
 	botEngine.Start()
 
 	log.Info("repl started")
+	cmd.Println("available commands:")
+	for _, c := range botEngine.Commands() {
+		cmd.Printf("  %-60s %s\n", c.Usage(), c.Desc)
+	}
+
 	reader := bufio.NewReader(os.Stdin)
 
 	for {
 		cmd.Print(PROMPT)
 
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSuffix(input, "\n")
+		input, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			log.Error("can't read the input", "err", err)
+
+			return
+		}
+
+		// The last line of the input may not end with a newline, so it is run before exiting.
+		input = strings.TrimSpace(input)
+		if input == "" {
+			if errors.Is(err, io.EOF) {
+				cmd.Println("exiting from repl")
+
+				return
+			}
+
+			continue
+		}
 
 		if strings.ToLower(input) == "exit" {
 			cmd.Println("exiting from repl")
@@ -51,21 +79,67 @@ func run(cmd *cobra.Command, args []string) {
 			return
 		}
 
-		response, err := botEngine.Run(input)
+		// Ctrl+C cancels the running command, not the REPL.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		res, err := botEngine.Run(ctx, engine.NewCLICaller(), input)
+		stop()
+
+		if jsonOutput {
+			printJSON(cmd, res, err)
+
+			continue
+		}
+
 		if err != nil {
 			cmd.PrintErr(err)
+
+			continue
 		}
 
-		cmd.Print(response)
+		cmd.Print(res.String())
 	}
 }
 
+// replNotifier prints the engine alerts in the REPL.
+type replNotifier struct {
+	cmd *cobra.Command
+}
+
+func (n *replNotifier) Notify(alert *engine.Alert) {
+	if alert.UserID != "" {
+		n.cmd.PrintErrf("\n[%s] %s (to %s): %s\n", alert.Status, alert.Title, alert.UserID, alert.Message)
+
+		return
+	}
+
+	n.cmd.PrintErrf("\n[%s] %s: %s\n", alert.Status, alert.Title, alert.Message)
+}
+
+func printJSON(cmd *cobra.Command, res *engine.Result, err error) {
+	var out any = res
+	if err != nil {
+		out = map[string]string{"error": err.Error()}
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		cmd.PrintErr(err)
+
+		return
+	}
+
+	cmd.Print(string(data))
+}
+
 func main() {
 	rootCmd := &cobra.Command{
 		Use:     "robopac-cmd",
 		Version: "0.0.1",
 		Run:     run,
 	}
+	rootCmd.Flags().Bool("json", false, "print the command results in JSON format")
+
+	AuditCommand(rootCmd)
 
 	err := rootCmd.Execute()
 	if err != nil {
@@ -38,7 +38,7 @@ func RunCommand(parentCmd *cobra.Command) {
 		botEngine.Start()
 
 		discordBot, err := discord.NewDiscordBot(botEngine, config.DiscordBotCfg.DiscordToken,
-			config.DiscordBotCfg.DiscordGuildID)
+			config.DiscordBotCfg.DiscordGuildID, config.DiscordBotCfg.AdminChannelID)
 		if err != nil {
 			log.Panic("could not start discord bot", "err", err)
 		}
@@ -0,0 +1,266 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/kehiy/RoboPac/eligibility"
+	"github.com/kehiy/RoboPac/nowpayments"
+	"github.com/kehiy/RoboPac/turboswap"
+)
+
+const (
+	// BoosterCampaignID is the ID of the Validator Booster Program.
+	// Its participants are kept in the `twitter_campaign.json` file of the store.
+	BoosterCampaignID = "booster"
+
+	// ProviderNowPayments is the NowPayments payment provider.
+	ProviderNowPayments = nowpayments.ProviderName
+	// ProviderTurboswap is the Turboswap payment provider.
+	ProviderTurboswap = turboswap.ProviderName
+)
+
+const defaultPaymentExpiryDays = 7
+
+// campaignIDPattern keeps the campaign IDs usable in file names and command arguments.
+var campaignIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Campaign defines a program that sells staked PAC packages to Twitter users.
+// Campaigns run side by side, each one with its own participants in the store.
+type Campaign struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Memo is the memo of the payout transactions, the title is used if it is empty.
+	Memo string `json:"memo"`
+	// Provider is the payment provider that the packages are paid with.
+	Provider string `json:"provider"`
+	// MaxPackages is the cap of the packages, zero means no cap.
+	MaxPackages int `json:"max_packages"`
+	// StartAt and EndAt limit the time that new packages can be bought. Zero means no limit.
+	StartAt time.Time `json:"start_at"`
+	EndAt   time.Time `json:"end_at"`
+	// PaymentExpiryDays is the number of days that the discount code can be paid,
+	// after that the package is released. It is 7 days by default.
+	PaymentExpiryDays int `json:"payment_expiry_days"`
+	// WhitelistURL is shown to the users that are not eligible, to ask for whitelisting.
+	WhitelistURL string `json:"whitelist_url"`
+	// RuleSet is the name of the eligibility rule set of the campaign, the default rule set is used if it is empty.
+	RuleSet    string       `json:"rule_set"`
+	PriceTiers []PriceTier  `json:"price_tiers"`
+	Rewards    []RewardTier `json:"rewards"`
+
+	// Eligibility is the rule of the rule set, that the users are checked against.
+	Eligibility *eligibility.Rule `json:"-"`
+}
+
+// PriceTier is the price of a package in USD, while less than MaxPackages packages are sold.
+// The last tier can have zero MaxPackages to cover the rest of the packages.
+type PriceTier struct {
+	MaxPackages int `json:"max_packages"`
+	Price       int `json:"price"`
+}
+
+// RewardTier is the amount of PAC that accounts with at least MinFollowers followers receive.
+type RewardTier struct {
+	MinFollowers int   `json:"min_followers"`
+	AmountInPAC  int64 `json:"amount_in_pac"`
+}
+
+// DefaultCampaigns returns the Validator Booster Program, used when no campaigns file is set.
+func DefaultCampaigns() []*Campaign {
+	return []*Campaign{
+		{
+			ID:                BoosterCampaignID,
+			Title:             "Pactus Validator Booster Program ✨",
+			Memo:              "Booster Program",
+			Provider:          ProviderNowPayments,
+			MaxPackages:       500,
+			PaymentExpiryDays: defaultPaymentExpiryDays,
+			WhitelistURL:      "https://forms.gle/fMaN1xtE322RBEYX8",
+			RuleSet:           DefaultRuleSet,
+			Eligibility:       DefaultRuleSets()[DefaultRuleSet],
+			PriceTiers: []PriceTier{
+				{MaxPackages: 100, Price: 30},
+				{MaxPackages: 200, Price: 40},
+				{MaxPackages: 0, Price: 50},
+			},
+			Rewards: []RewardTier{
+				{MinFollowers: 0, AmountInPAC: 150},
+				{MinFollowers: 1001, AmountInPAC: 200},
+			},
+		},
+	}
+}
+
+// LoadCampaigns loads the campaigns from a JSON file, and sets their eligibility rules from the rule sets.
+// If the path is empty, the default campaigns are returned.
+func LoadCampaigns(path string, ruleSets map[string]*eligibility.Rule) ([]*Campaign, error) {
+	if path == "" {
+		campaigns := DefaultCampaigns()
+		for _, c := range campaigns {
+			if err := c.setEligibility(ruleSets); err != nil {
+				return nil, fmt.Errorf("campaign `%s`: %w", c.ID, err)
+			}
+		}
+
+		return campaigns, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	campaigns := []*Campaign{}
+	if err := json.Unmarshal(data, &campaigns); err != nil {
+		return nil, err
+	}
+
+	if len(campaigns) == 0 {
+		return nil, errors.New("no campaign is defined")
+	}
+
+	ids := make(map[string]bool)
+	for _, c := range campaigns {
+		if err := c.BasicCheck(); err != nil {
+			return nil, fmt.Errorf("campaign `%s`: %w", c.ID, err)
+		}
+
+		if ids[c.ID] {
+			return nil, fmt.Errorf("campaign `%s` is defined twice", c.ID)
+		}
+		ids[c.ID] = true
+
+		if err := c.setEligibility(ruleSets); err != nil {
+			return nil, fmt.Errorf("campaign `%s`: %w", c.ID, err)
+		}
+
+		if c.Memo == "" {
+			c.Memo = c.Title
+		}
+
+		if c.PaymentExpiryDays == 0 {
+			c.PaymentExpiryDays = defaultPaymentExpiryDays
+		}
+		c.sortTiers()
+	}
+
+	return campaigns, nil
+}
+
+// BasicCheck checks the campaign definition.
+func (c *Campaign) BasicCheck() error {
+	if !campaignIDPattern.MatchString(c.ID) {
+		return errors.New("id should only have lowercase letters, digits and dashes")
+	}
+
+	// The claim payouts are journaled with the `claim` kind.
+	if c.ID == "claim" {
+		return errors.New("id `claim` is reserved")
+	}
+
+	if c.Provider != ProviderNowPayments && c.Provider != ProviderTurboswap {
+		return fmt.Errorf("unknown payment provider: %s", c.Provider)
+	}
+
+	if len(c.PriceTiers) == 0 {
+		return errors.New("no price tier is defined")
+	}
+
+	if len(c.Rewards) == 0 {
+		return errors.New("no reward is defined")
+	}
+
+	if c.PaymentExpiryDays < 0 {
+		return errors.New("payment expiry days is negative")
+	}
+
+	if !c.StartAt.IsZero() && !c.EndAt.IsZero() && c.EndAt.Before(c.StartAt) {
+		return errors.New("end date is before the start date")
+	}
+
+	return nil
+}
+
+// setEligibility sets the eligibility rule of the campaign from its rule set.
+func (c *Campaign) setEligibility(ruleSets map[string]*eligibility.Rule) error {
+	if c.RuleSet == "" {
+		c.RuleSet = DefaultRuleSet
+	}
+
+	rule, ok := ruleSets[c.RuleSet]
+	if !ok {
+		return fmt.Errorf("unknown rule set: %s", c.RuleSet)
+	}
+	c.Eligibility = rule
+
+	return nil
+}
+
+// sortTiers sorts the price tiers by their packages, the open-ended tier is the last one.
+// The reward tiers are sorted by their followers.
+func (c *Campaign) sortTiers() {
+	maxPackages := func(tier PriceTier) int {
+		if tier.MaxPackages == 0 {
+			return math.MaxInt
+		}
+
+		return tier.MaxPackages
+	}
+
+	sort.Slice(c.PriceTiers, func(i, j int) bool {
+		return maxPackages(c.PriceTiers[i]) < maxPackages(c.PriceTiers[j])
+	})
+
+	sort.Slice(c.Rewards, func(i, j int) bool {
+		return c.Rewards[i].MinFollowers < c.Rewards[j].MinFollowers
+	})
+}
+
+// IsOpen checks if new packages can be bought at the given time.
+func (c *Campaign) IsOpen(now time.Time) error {
+	if !c.StartAt.IsZero() && now.Before(c.StartAt) {
+		return fmt.Errorf("the campaign starts at %s", c.StartAt.Format(time.DateOnly))
+	}
+
+	if !c.EndAt.IsZero() && !now.Before(c.EndAt) {
+		return errors.New("program is finished")
+	}
+
+	return nil
+}
+
+// PaymentExpiry returns the time that the discount code created at createdAt expires.
+func (c *Campaign) PaymentExpiry(createdAt int64) time.Time {
+	return time.Unix(createdAt, 0).AddDate(0, 0, c.PaymentExpiryDays)
+}
+
+// Price returns the price of the next package, when soldPackages packages are already sold.
+// It returns zero if no tier covers it.
+func (c *Campaign) Price(soldPackages int) int {
+	for _, tier := range c.PriceTiers {
+		if tier.MaxPackages == 0 || soldPackages < tier.MaxPackages {
+			return tier.Price
+		}
+	}
+
+	return 0
+}
+
+// Reward returns the amount of PAC for an account with the given followers.
+func (c *Campaign) Reward(followers int) int64 {
+	amount := int64(0)
+	for _, tier := range c.Rewards {
+		if followers >= tier.MinFollowers {
+			amount = tier.AmountInPAC
+		}
+	}
+
+	return amount
+}
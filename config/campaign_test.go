@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/kehiy/RoboPac/eligibility"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCampaignPrice(t *testing.T) {
+	booster := DefaultCampaigns()[0]
+
+	for i := 0; i < 500; i++ {
+		switch {
+		case i < 100:
+			assert.Equal(t, 30, booster.Price(i))
+		case i < 200:
+			assert.Equal(t, 40, booster.Price(i))
+		default:
+			assert.Equal(t, 50, booster.Price(i))
+		}
+	}
+
+	t.Run("no open-ended tier", func(t *testing.T) {
+		c := &Campaign{PriceTiers: []PriceTier{{MaxPackages: 10, Price: 5}}}
+
+		assert.Equal(t, 5, c.Price(9))
+		assert.Zero(t, c.Price(10))
+	})
+}
+
+func TestCampaignReward(t *testing.T) {
+	booster := DefaultCampaigns()[0]
+
+	assert.Equal(t, int64(150), booster.Reward(0))
+	assert.Equal(t, int64(150), booster.Reward(1000))
+	assert.Equal(t, int64(200), booster.Reward(1001))
+}
+
+func TestCampaignIsOpen(t *testing.T) {
+	now := time.Now()
+	c := &Campaign{StartAt: now.Add(time.Hour), EndAt: now.Add(2 * time.Hour)}
+
+	assert.ErrorContains(t, c.IsOpen(now), "the campaign starts at")
+	assert.NoError(t, c.IsOpen(now.Add(90*time.Minute)))
+	assert.EqualError(t, c.IsOpen(now.Add(2*time.Hour)), "program is finished")
+
+	assert.NoError(t, (&Campaign{}).IsOpen(now))
+}
+
+func TestLoadCampaigns(t *testing.T) {
+	campaignsPath := path.Join(t.TempDir(), "campaigns.json")
+	ruleSets := map[string]*eligibility.Rule{
+		DefaultRuleSet: {Type: eligibility.TypeWhitelisted},
+		"open":         {Type: eligibility.TypeTwitterFollowers, Min: 100},
+	}
+
+	t.Run("default campaigns", func(t *testing.T) {
+		campaigns, err := LoadCampaigns("", DefaultRuleSets())
+		assert.NoError(t, err)
+		assert.Len(t, campaigns, 1)
+		assert.Equal(t, BoosterCampaignID, campaigns[0].ID)
+		assert.Equal(t, DefaultRuleSets()[DefaultRuleSet], campaigns[0].Eligibility)
+	})
+
+	t.Run("valid campaigns", func(t *testing.T) {
+		data := `[
+			{
+				"id": "summer",
+				"title": "Summer Campaign",
+				"provider": "nowpayments",
+				"max_packages": 100,
+				"start_at": "2024-06-01T00:00:00Z",
+				"end_at": "2024-09-01T00:00:00Z",
+				"rule_set": "open",
+				"price_tiers": [{"max_packages": 0, "price": 20}, {"max_packages": 50, "price": 10}],
+				"rewards": [{"min_followers": 500, "amount_in_pac": 100}, {"min_followers": 0, "amount_in_pac": 80}]
+			}
+		]`
+		assert.NoError(t, os.WriteFile(campaignsPath, []byte(data), 0o600))
+
+		campaigns, err := LoadCampaigns(campaignsPath, ruleSets)
+		assert.NoError(t, err)
+		assert.Len(t, campaigns, 1)
+
+		summer := campaigns[0]
+		assert.Equal(t, "Summer Campaign", summer.Memo)
+		assert.Equal(t, 7, summer.PaymentExpiryDays)
+		assert.Equal(t, time.Unix(100, 0).AddDate(0, 0, 7), summer.PaymentExpiry(100))
+		assert.Equal(t, ruleSets["open"], summer.Eligibility)
+		assert.Equal(t, 10, summer.Price(49))
+		assert.Equal(t, 20, summer.Price(50))
+		assert.Equal(t, int64(80), summer.Reward(499))
+		assert.Equal(t, int64(100), summer.Reward(500))
+		assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), summer.StartAt)
+	})
+
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"no campaign", `[]`, "no campaign is defined"},
+		{"invalid id", `[{"id": "Summer Campaign"}]`, "id should only have lowercase letters"},
+		{"reserved id", `[{"id": "claim"}]`, "id `claim` is reserved"},
+		{"unknown provider", `[{"id": "summer", "provider": "paypal"}]`, "unknown payment provider: paypal"},
+		{"no price", `[{"id": "summer", "provider": "nowpayments"}]`, "no price tier is defined"},
+		{
+			"unknown rule set",
+			`[{"id": "a", "provider": "nowpayments", "price_tiers": [{"price": 1}], "rewards": [{"amount_in_pac": 1}],
+			   "rule_set": "strict"}]`,
+			"campaign `a`: unknown rule set: strict",
+		},
+		{
+			"duplicated id",
+			`[{"id": "a", "provider": "nowpayments", "price_tiers": [{"price": 1}], "rewards": [{"amount_in_pac": 1}]},
+			  {"id": "a", "provider": "nowpayments", "price_tiers": [{"price": 1}], "rewards": [{"amount_in_pac": 1}]}]`,
+			"campaign `a` is defined twice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, os.WriteFile(campaignsPath, []byte(tt.data), 0o600))
+
+			_, err := LoadCampaigns(campaignsPath, ruleSets)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoadRuleSets(t *testing.T) {
+	ruleSetsPath := path.Join(t.TempDir(), "rule_sets.json")
+
+	t.Run("default rule sets", func(t *testing.T) {
+		ruleSets, err := LoadRuleSets("")
+		assert.NoError(t, err)
+		assert.Contains(t, ruleSets, DefaultRuleSet)
+		assert.NoError(t, ruleSets[DefaultRuleSet].BasicCheck())
+	})
+
+	t.Run("valid rule sets", func(t *testing.T) {
+		data := `{
+			"default": {"type": "twitter_followers", "min": 200},
+			"veterans": {
+				"type": "all",
+				"rules": [
+					{"type": "discord_member_age", "min": 90},
+					{"type": "any", "rules": [{"type": "twitter_verified"}, {"type": "whitelisted"}]}
+				]
+			}
+		}`
+		assert.NoError(t, os.WriteFile(ruleSetsPath, []byte(data), 0o600))
+
+		ruleSets, err := LoadRuleSets(ruleSetsPath)
+		assert.NoError(t, err)
+		assert.Len(t, ruleSets, 2)
+		assert.Equal(t, 200, ruleSets["default"].Min)
+		assert.Equal(t, eligibility.TypeDiscordMemberAge, ruleSets["veterans"].Rules[0].Type)
+		assert.Len(t, ruleSets["veterans"].Rules[1].Rules, 2)
+	})
+
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"no rule set", `{}`, "no rule set is defined"},
+		{"empty rule set", `{"default": null}`, "rule set `default` is empty"},
+		{"unknown type", `{"default": {"type": "karma"}}`, "rule set `default`: unknown rule type: karma"},
+		{"no sub-rules", `{"default": {"type": "any"}}`, "the rule `any` has no rules"},
+		{
+			"sub-rules of a simple rule",
+			`{"default": {"type": "whitelisted", "rules": [{"type": "twitter_verified"}]}}`,
+			"the rule `whitelisted` can't have rules",
+		},
+		{
+			"negative minimum",
+			`{"default": {"type": "all", "rules": [{"type": "twitter_age", "min": -1}]}}`,
+			"the minimum of the rule `twitter_age` is negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, os.WriteFile(ruleSetsPath, []byte(tt.data), 0o600))
+
+			_, err := LoadRuleSets(ruleSetsPath)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
@@ -1,12 +1,21 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/kehiy/RoboPac/client"
+	"github.com/kehiy/RoboPac/eligibility"
 	"github.com/kehiy/RoboPac/nowpayments"
+	"github.com/kehiy/RoboPac/turboswap"
+	"github.com/kehiy/RoboPac/twitter_api"
+	"github.com/kehiy/RoboPac/webhook"
 	"github.com/pactus-project/pactus/util"
 )
 
@@ -18,20 +27,79 @@ type Config struct {
 	NetworkNodes      []string
 	LocalNode         string
 	StorePath         string
-	AuthIDs           []string
-	DiscordBotCfg     DiscordBotConfig
-	TwitterAPICfg     TwitterAPIConfig
-	NowPaymentsConfig nowpayments.Config
+	AuditLogPath      string
+	PayoutJournalPath string
+	DryRun            bool
+	Roles             map[string]RoleMembers
+	CommandTimeout    time.Duration
+	CommandTimeouts   map[string]time.Duration
+	TxTrackInterval   time.Duration
+	TxConfirmTimeout  time.Duration
+	PayoutLimits      PayoutLimits
+	Campaigns         []*Campaign
+	// RuleSets are the eligibility rule sets, that the campaigns pick by name.
+	RuleSets map[string]*eligibility.Rule
+	// ExpiryCheckInterval is how often the unpaid discount codes are checked for expiry.
+	ExpiryCheckInterval time.Duration
+	// PaymentReconcileInterval is how often the pending invoices are checked, in case their IPN callback is lost.
+	PaymentReconcileInterval time.Duration
+	// LatePaymentWindow is how long the invoices of the expired parties are still checked after their expiry,
+	// in case a late payment is made and its IPN callback is lost.
+	LatePaymentWindow time.Duration
+	// ConsistencyCheckInterval is how often the state of the blockchain is compared between the nodes.
+	ConsistencyCheckInterval time.Duration
+	// MaxLocalNodeLag is the number of the blocks that the local node can be behind the majority of the nodes.
+	MaxLocalNodeLag uint32
+	// BlockFollowInterval is how often the new blocks are polled from the local node.
+	BlockFollowInterval time.Duration
+	DiscordBotCfg       DiscordBotConfig
+	TwitterAPICfg       TwitterAPIConfig
+	NowPaymentsConfig   nowpayments.Config
+	TurboswapConfig     turboswap.Config
+	WebhookConfig       webhook.Config
+	// NodeOptions are the TLS and the metadata of the nodes, by their address.
+	NodeOptions map[string]*client.NodeOptions
+	// NodeConn is the keepalive and the reconnect backoff of the node connections.
+	NodeConn client.ConnConfig
+	// ChainCacheBlockTime is the block time that the TTLs of the cached chain queries are tied to.
+	// Zero disables the cache.
+	ChainCacheBlockTime time.Duration
+}
+
+const (
+	RoleAdmin            = "admin"
+	RoleCampaignOperator = "campaign-operator"
+	RoleViewer           = "viewer"
+)
+
+// RoleMembers defines who has a role, by Discord user IDs or Discord guild role IDs.
+type RoleMembers struct {
+	DiscordIDs   []string
+	DiscordRoles []string
+}
+
+// PayoutLimits are the spending limits of the bot wallet in PAC. Zero means no limit.
+type PayoutLimits struct {
+	MinBalance int64
+	MaxPerTx   int64
+	MaxPerHour int64
+	MaxPerDay  int64
+	MaxPerUser int64
 }
 
 type TwitterAPIConfig struct {
 	BearerToken string
 	TwitterID   string
+	// MaxRateLimitWait is how long the calls wait for the rate limit to reset, before telling the users to try later.
+	MaxRateLimitWait time.Duration
+	Cache            twitter_api.CacheConfig
 }
 
 type DiscordBotConfig struct {
 	DiscordToken   string
 	DiscordGuildID string
+	// AdminChannelID is the channel that the alerts are sent to.
+	AdminChannelID string
 }
 
 func Load(filePaths ...string) (*Config, error) {
@@ -42,33 +110,146 @@ func Load(filePaths ...string) (*Config, error) {
 
 	// Fetch config values from environment variables.
 	cfg := &Config{
-		Network:        os.Getenv("NETWORK"),
-		WalletAddress:  os.Getenv("WALLET_ADDRESS"),
-		WalletPath:     os.Getenv("WALLET_PATH"),
-		WalletPassword: os.Getenv("WALLET_PASSWORD"),
-		LocalNode:      os.Getenv("LOCAL_NODE"),
-		NetworkNodes:   strings.Split(os.Getenv("NETWORK_NODES"), ","),
-		StorePath:      os.Getenv("STORE_PATH"),
-		AuthIDs:        strings.Split(os.Getenv("AUTHORIZED_DISCORD_IDS"), ","),
+		Network:           os.Getenv("NETWORK"),
+		WalletAddress:     os.Getenv("WALLET_ADDRESS"),
+		WalletPath:        os.Getenv("WALLET_PATH"),
+		WalletPassword:    os.Getenv("WALLET_PASSWORD"),
+		LocalNode:         os.Getenv("LOCAL_NODE"),
+		NetworkNodes:      strings.Split(os.Getenv("NETWORK_NODES"), ","),
+		StorePath:         os.Getenv("STORE_PATH"),
+		AuditLogPath:      os.Getenv("AUDIT_LOG_PATH"),
+		PayoutJournalPath: os.Getenv("PAYOUT_JOURNAL_PATH"),
 		DiscordBotCfg: DiscordBotConfig{
 			DiscordToken:   os.Getenv("DISCORD_TOKEN"),
 			DiscordGuildID: os.Getenv("DISCORD_GUILD_ID"),
-		},
-		TwitterAPICfg: TwitterAPIConfig{
-			BearerToken: os.Getenv("TWITTER_BEARER_TOKEN"),
-			TwitterID:   os.Getenv("TWITTER_ID"),
+			AdminChannelID: os.Getenv("DISCORD_ADMIN_CHANNEL_ID"),
 		},
 		NowPaymentsConfig: nowpayments.Config{
-			ListenPort: os.Getenv("NOWPAYMENTS_LISTEN_PORT"),
-			Webhook:    os.Getenv("NOWPAYMENTS_WEBHOOK"),
-			APIToken:   os.Getenv("NOWPAYMENTS_API_KEY"),
-			APIUrl:     os.Getenv("NOWPAYMENTS_API_URL"),
-			IPNSecret:  os.Getenv("NOWPAYMENTS_IPN_SECRET"),
-			Username:   os.Getenv("NOWPAYMENTS_USERNAME"),
-			Password:   os.Getenv("NOWPAYMENTS_PASSWORD"),
+			Webhook:   os.Getenv("NOWPAYMENTS_WEBHOOK"),
+			APIToken:  os.Getenv("NOWPAYMENTS_API_KEY"),
+			APIUrl:    os.Getenv("NOWPAYMENTS_API_URL"),
+			IPNSecret: os.Getenv("NOWPAYMENTS_IPN_SECRET"),
+			Username:  os.Getenv("NOWPAYMENTS_USERNAME"),
+			Password:  os.Getenv("NOWPAYMENTS_PASSWORD"),
+		},
+		TurboswapConfig: turboswap.Config{
+			APIToken:   os.Getenv("TURBOSWAP_API_TOKEN"),
+			APIUrl:     os.Getenv("TURBOSWAP_API_URL"),
+			PaymentURL: os.Getenv("TURBOSWAP_PAYMENT_URL"),
 		},
 	}
 
+	if cfg.AuditLogPath == "" {
+		cfg.AuditLogPath = path.Join(cfg.StorePath, "audit.jsonl")
+	}
+
+	if dryRun := os.Getenv("DRY_RUN"); dryRun != "" {
+		cfg.DryRun, err = strconv.ParseBool(dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("DRY_RUN is incorrect: %w", err)
+		}
+	}
+
+	cfg.PayoutJournalPath = payoutJournalPath(cfg.StorePath, cfg.PayoutJournalPath, cfg.DryRun)
+
+	cfg.Roles = loadRoles()
+
+	cfg.CommandTimeout, err = parseDuration(os.Getenv("COMMAND_TIMEOUT"), 30*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("COMMAND_TIMEOUT is incorrect: %w", err)
+	}
+
+	cfg.CommandTimeouts, err = parseDurationMap(os.Getenv("COMMAND_TIMEOUTS"))
+	if err != nil {
+		return nil, fmt.Errorf("COMMAND_TIMEOUTS is incorrect: %w", err)
+	}
+
+	cfg.TxTrackInterval, err = parseDuration(os.Getenv("TX_TRACK_INTERVAL"), 30*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("TX_TRACK_INTERVAL is incorrect: %w", err)
+	}
+
+	cfg.TxConfirmTimeout, err = parseDuration(os.Getenv("TX_CONFIRM_TIMEOUT"), 10*time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("TX_CONFIRM_TIMEOUT is incorrect: %w", err)
+	}
+
+	cfg.BlockFollowInterval, err = parseDuration(os.Getenv("BLOCK_FOLLOW_INTERVAL"), 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("BLOCK_FOLLOW_INTERVAL is incorrect: %w", err)
+	}
+
+	cfg.ExpiryCheckInterval, err = parseDuration(os.Getenv("EXPIRY_CHECK_INTERVAL"), time.Hour)
+	if err != nil {
+		return nil, fmt.Errorf("EXPIRY_CHECK_INTERVAL is incorrect: %w", err)
+	}
+
+	cfg.PaymentReconcileInterval, err = parseDuration(os.Getenv("PAYMENT_RECONCILE_INTERVAL"), 30*time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("PAYMENT_RECONCILE_INTERVAL is incorrect: %w", err)
+	}
+
+	cfg.LatePaymentWindow, err = parseDuration(os.Getenv("LATE_PAYMENT_WINDOW"), 7*24*time.Hour)
+	if err != nil {
+		return nil, fmt.Errorf("LATE_PAYMENT_WINDOW is incorrect: %w", err)
+	}
+
+	cfg.ConsistencyCheckInterval, err = parseDuration(os.Getenv("CONSISTENCY_CHECK_INTERVAL"), 5*time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("CONSISTENCY_CHECK_INTERVAL is incorrect: %w", err)
+	}
+
+	cfg.MaxLocalNodeLag = 10
+	if value := os.Getenv("MAX_LOCAL_NODE_LAG"); value != "" {
+		var lag uint64
+		lag, err = strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("MAX_LOCAL_NODE_LAG is incorrect: %w", err)
+		}
+		cfg.MaxLocalNodeLag = uint32(lag)
+	}
+
+	nodes := append([]string{cfg.LocalNode}, cfg.NetworkNodes...)
+	cfg.NodeOptions, err = LoadNodeOptions(os.Getenv("NODE_OPTIONS_PATH"), nodes)
+	if err != nil {
+		return nil, fmt.Errorf("NODE_OPTIONS_PATH is incorrect: %w", err)
+	}
+
+	cfg.NodeConn, err = loadNodeConnConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.ChainCacheBlockTime, err = parseDuration(os.Getenv("CHAIN_CACHE_BLOCK_TIME"), 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("CHAIN_CACHE_BLOCK_TIME is incorrect: %w", err)
+	}
+
+	cfg.TwitterAPICfg, err = loadTwitterAPIConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.WebhookConfig, err = loadWebhookConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.PayoutLimits, err = loadPayoutLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.RuleSets, err = LoadRuleSets(os.Getenv("ELIGIBILITY_RULES_PATH"))
+	if err != nil {
+		return nil, fmt.Errorf("ELIGIBILITY_RULES_PATH is incorrect: %w", err)
+	}
+
+	cfg.Campaigns, err = LoadCampaigns(os.Getenv("CAMPAIGNS_PATH"), cfg.RuleSets)
+	if err != nil {
+		return nil, fmt.Errorf("CAMPAIGNS_PATH is incorrect: %w", err)
+	}
+
 	// Check if the required configurations are set.
 	if err := cfg.BasicCheck(); err != nil {
 		return nil, err
@@ -111,3 +292,185 @@ func (cfg *Config) BasicCheck() error {
 
 	return nil
 }
+
+// payoutJournalPath returns the path of the payout journal, that is in the store path by default.
+// The simulated payouts have their own journal, even if the path is set,
+// so they are not counted in the spending limits and never sent on the next live start.
+func payoutJournalPath(storePath, journalPath string, dryRun bool) string {
+	if journalPath == "" {
+		journalPath = path.Join(storePath, "payout_journal.jsonl")
+	}
+
+	if dryRun {
+		ext := path.Ext(journalPath)
+		journalPath = strings.TrimSuffix(journalPath, ext) + ".dryrun" + ext
+	}
+
+	return journalPath
+}
+
+// loadRoles loads the members of each role. For example for the campaign-operator role,
+// CAMPAIGN_OPERATOR_DISCORD_IDS and CAMPAIGN_OPERATOR_DISCORD_ROLES are loaded.
+// AUTHORIZED_DISCORD_IDS is kept for backward compatibility and is added to the admins.
+func loadRoles() map[string]RoleMembers {
+	roles := make(map[string]RoleMembers)
+	for _, role := range []string{RoleAdmin, RoleCampaignOperator, RoleViewer} {
+		prefix := strings.ToUpper(strings.ReplaceAll(role, "-", "_"))
+		roles[role] = RoleMembers{
+			DiscordIDs:   splitList(os.Getenv(prefix + "_DISCORD_IDS")),
+			DiscordRoles: splitList(os.Getenv(prefix + "_DISCORD_ROLES")),
+		}
+	}
+
+	admins := roles[RoleAdmin]
+	admins.DiscordIDs = append(admins.DiscordIDs, splitList(os.Getenv("AUTHORIZED_DISCORD_IDS"))...)
+	roles[RoleAdmin] = admins
+
+	return roles
+}
+
+// loadTwitterAPIConfig loads the config of the Twitter API client.
+// The users are cached for an hour and the quote tweets for 5 minutes by default.
+func loadTwitterAPIConfig() (TwitterAPIConfig, error) {
+	cfg := TwitterAPIConfig{
+		BearerToken: os.Getenv("TWITTER_BEARER_TOKEN"),
+		TwitterID:   os.Getenv("TWITTER_ID"),
+	}
+
+	var err error
+	for _, d := range []struct {
+		env   string
+		value *time.Duration
+		def   time.Duration
+	}{
+		{"TWITTER_MAX_RATE_LIMIT_WAIT", &cfg.MaxRateLimitWait, 20 * time.Second},
+		{"TWITTER_USER_CACHE_TTL", &cfg.Cache.UserInfoTTL, time.Hour},
+		{"TWITTER_RETWEET_CACHE_TTL", &cfg.Cache.RetweetSearchTTL, 5 * time.Minute},
+	} {
+		*d.value, err = parseDuration(os.Getenv(d.env), d.def)
+		if err != nil {
+			return cfg, fmt.Errorf("%s is incorrect: %w", d.env, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// loadWebhookConfig loads the config of the webhook server.
+// NOWPAYMENTS_LISTEN_PORT is kept for backward compatibility, if WEBHOOK_LISTEN_ADDR is not set.
+// The request bodies are limited to 1 MB by default.
+func loadWebhookConfig() (webhook.Config, error) {
+	cfg := webhook.Config{
+		ListenAddr:  os.Getenv("WEBHOOK_LISTEN_ADDR"),
+		TLSCertFile: os.Getenv("WEBHOOK_TLS_CERT_FILE"),
+		TLSKeyFile:  os.Getenv("WEBHOOK_TLS_KEY_FILE"),
+		MaxBodySize: 1 << 20,
+	}
+
+	if port := os.Getenv("NOWPAYMENTS_LISTEN_PORT"); cfg.ListenAddr == "" && port != "" {
+		cfg.ListenAddr = ":" + port
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return cfg, errors.New("both WEBHOOK_TLS_CERT_FILE and WEBHOOK_TLS_KEY_FILE should be set")
+	}
+
+	if value := os.Getenv("WEBHOOK_MAX_BODY_SIZE"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size <= 0 {
+			return cfg, fmt.Errorf("WEBHOOK_MAX_BODY_SIZE is incorrect: %s", value)
+		}
+		cfg.MaxBodySize = size
+	}
+
+	var err error
+	for _, d := range []struct {
+		env   string
+		value *time.Duration
+		def   time.Duration
+	}{
+		{"WEBHOOK_READ_TIMEOUT", &cfg.ReadTimeout, 10 * time.Second},
+		{"WEBHOOK_WRITE_TIMEOUT", &cfg.WriteTimeout, 10 * time.Second},
+		{"WEBHOOK_SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout, 10 * time.Second},
+	} {
+		*d.value, err = parseDuration(os.Getenv(d.env), d.def)
+		if err != nil {
+			return cfg, fmt.Errorf("%s is incorrect: %w", d.env, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// loadPayoutLimits loads the spending limits of the bot wallet.
+// The minimum balance of the wallet is 500 PAC by default.
+func loadPayoutLimits() (PayoutLimits, error) {
+	limits := PayoutLimits{}
+	for _, l := range []struct {
+		env   string
+		value *int64
+		def   int64
+	}{
+		{"WALLET_MIN_BALANCE", &limits.MinBalance, 500},
+		{"LIMIT_PER_TX", &limits.MaxPerTx, 0},
+		{"LIMIT_PER_HOUR", &limits.MaxPerHour, 0},
+		{"LIMIT_PER_DAY", &limits.MaxPerDay, 0},
+		{"LIMIT_PER_USER", &limits.MaxPerUser, 0},
+	} {
+		*l.value = l.def
+		if value := os.Getenv(l.env); value != "" {
+			amount, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || amount < 0 {
+				return limits, fmt.Errorf("%s is incorrect: %s", l.env, value)
+			}
+			*l.value = amount
+		}
+	}
+
+	return limits, nil
+}
+
+// splitList splits a comma separated list and drops the empty items.
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// parseDuration parses a duration like "30s", returning the default value if it is empty.
+func parseDuration(value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// parseDurationMap parses a list of key-duration pairs like "claim=1m,node-info=10s".
+func parseDurationMap(value string) (map[string]time.Duration, error) {
+	durations := make(map[string]time.Duration)
+	if value == "" {
+		return durations, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		key, dur, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return nil, fmt.Errorf("invalid pair: %s", pair)
+		}
+
+		d, err := time.ParseDuration(dur)
+		if err != nil {
+			return nil, err
+		}
+		durations[key] = d
+	}
+
+	return durations, nil
+}
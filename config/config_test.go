@@ -2,7 +2,10 @@ package config
 
 import (
 	"testing"
+	"time"
 
+	"github.com/kehiy/RoboPac/twitter_api"
+	"github.com/kehiy/RoboPac/webhook"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -65,3 +68,102 @@ func TestBasicCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDurationMap(t *testing.T) {
+	durations, err := parseDurationMap("claim=1m, node-info=10s")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{
+		"claim":     time.Minute,
+		"node-info": 10 * time.Second,
+	}, durations)
+
+	durations, err = parseDurationMap("")
+	assert.NoError(t, err)
+	assert.Empty(t, durations)
+
+	_, err = parseDurationMap("claim")
+	assert.Error(t, err)
+
+	_, err = parseDurationMap("claim=abc")
+	assert.Error(t, err)
+}
+
+func TestPayoutJournalPath(t *testing.T) {
+	assert.Equal(t, "store/payout_journal.jsonl", payoutJournalPath("store", "", false))
+	assert.Equal(t, "store/payout_journal.dryrun.jsonl", payoutJournalPath("store", "", true))
+	assert.Equal(t, "data/journal.jsonl", payoutJournalPath("store", "data/journal.jsonl", false))
+	assert.Equal(t, "data/journal.dryrun.jsonl", payoutJournalPath("store", "data/journal.jsonl", true))
+	assert.Equal(t, "data/journal.dryrun", payoutJournalPath("store", "data/journal", true))
+}
+
+func TestLoadRoles(t *testing.T) {
+	t.Setenv("ADMIN_DISCORD_IDS", "1, 2")
+	t.Setenv("AUTHORIZED_DISCORD_IDS", "3")
+	t.Setenv("CAMPAIGN_OPERATOR_DISCORD_ROLES", "role-1")
+	t.Setenv("VIEWER_DISCORD_IDS", "")
+
+	roles := loadRoles()
+	assert.Equal(t, []string{"1", "2", "3"}, roles[RoleAdmin].DiscordIDs)
+	assert.Empty(t, roles[RoleAdmin].DiscordRoles)
+	assert.Equal(t, []string{"role-1"}, roles[RoleCampaignOperator].DiscordRoles)
+	assert.Empty(t, roles[RoleViewer].DiscordIDs)
+}
+
+func TestLoadPayoutLimits(t *testing.T) {
+	t.Setenv("WALLET_MIN_BALANCE", "")
+	t.Setenv("LIMIT_PER_TX", "200")
+	t.Setenv("LIMIT_PER_DAY", "5000")
+
+	limits, err := loadPayoutLimits()
+	assert.NoError(t, err)
+	assert.Equal(t, PayoutLimits{MinBalance: 500, MaxPerTx: 200, MaxPerDay: 5000}, limits)
+
+	t.Setenv("LIMIT_PER_USER", "-1")
+	_, err = loadPayoutLimits()
+	assert.EqualError(t, err, "LIMIT_PER_USER is incorrect: -1")
+}
+
+func TestLoadTwitterAPIConfig(t *testing.T) {
+	t.Setenv("TWITTER_BEARER_TOKEN", "token")
+	t.Setenv("TWITTER_USER_CACHE_TTL", "2h")
+
+	cfg, err := loadTwitterAPIConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "token", cfg.BearerToken)
+	assert.Equal(t, 20*time.Second, cfg.MaxRateLimitWait)
+	assert.Equal(t, twitter_api.CacheConfig{UserInfoTTL: 2 * time.Hour, RetweetSearchTTL: 5 * time.Minute}, cfg.Cache)
+
+	t.Setenv("TWITTER_RETWEET_CACHE_TTL", "5")
+	_, err = loadTwitterAPIConfig()
+	assert.ErrorContains(t, err, "TWITTER_RETWEET_CACHE_TTL is incorrect")
+}
+
+func TestLoadWebhookConfig(t *testing.T) {
+	t.Setenv("WEBHOOK_LISTEN_ADDR", "")
+	t.Setenv("NOWPAYMENTS_LISTEN_PORT", "50055")
+	t.Setenv("WEBHOOK_READ_TIMEOUT", "5s")
+
+	cfg, err := loadWebhookConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, webhook.Config{
+		ListenAddr:      ":50055",
+		MaxBodySize:     1 << 20,
+		ReadTimeout:     5 * time.Second,
+		WriteTimeout:    10 * time.Second,
+		ShutdownTimeout: 10 * time.Second,
+	}, cfg)
+
+	t.Setenv("WEBHOOK_LISTEN_ADDR", "127.0.0.1:8080")
+	cfg, err = loadWebhookConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:8080", cfg.ListenAddr)
+
+	t.Setenv("WEBHOOK_TLS_CERT_FILE", "cert.pem")
+	_, err = loadWebhookConfig()
+	assert.EqualError(t, err, "both WEBHOOK_TLS_CERT_FILE and WEBHOOK_TLS_KEY_FILE should be set")
+
+	t.Setenv("WEBHOOK_TLS_KEY_FILE", "key.pem")
+	t.Setenv("WEBHOOK_MAX_BODY_SIZE", "0")
+	_, err = loadWebhookConfig()
+	assert.EqualError(t, err, "WEBHOOK_MAX_BODY_SIZE is incorrect: 0")
+}
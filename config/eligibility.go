@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kehiy/RoboPac/eligibility"
+)
+
+// DefaultRuleSet is the rule set of the campaigns that don't pick one.
+const DefaultRuleSet = "default"
+
+// DefaultRuleSets returns the rule sets used when no rule sets file is set.
+// The default rule set is the one of the Validator Booster Program: the validator should not be staked,
+// and the Twitter account should be verified, whitelisted, or at least 3 years old with 200 followers.
+func DefaultRuleSets() map[string]*eligibility.Rule {
+	return map[string]*eligibility.Rule{
+		DefaultRuleSet: {
+			Type: eligibility.TypeAll,
+			Rules: []*eligibility.Rule{
+				{Type: eligibility.TypeUnstakedValidator},
+				{
+					Type: eligibility.TypeAny,
+					Rules: []*eligibility.Rule{
+						{Type: eligibility.TypeTwitterVerified},
+						{Type: eligibility.TypeWhitelisted},
+						{
+							Type: eligibility.TypeAll,
+							Rules: []*eligibility.Rule{
+								{Type: eligibility.TypeTwitterAge, Min: 3, Unit: eligibility.UnitYears},
+								{Type: eligibility.TypeTwitterFollowers, Min: 200},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// LoadRuleSets loads the eligibility rule sets from a JSON file, that maps the name of each rule set to its rule.
+// If the path is empty, the default rule sets are returned.
+func LoadRuleSets(path string) (map[string]*eligibility.Rule, error) {
+	if path == "" {
+		return DefaultRuleSets(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleSets := make(map[string]*eligibility.Rule)
+	if err := json.Unmarshal(data, &ruleSets); err != nil {
+		return nil, err
+	}
+
+	if len(ruleSets) == 0 {
+		return nil, errors.New("no rule set is defined")
+	}
+
+	for name, rule := range ruleSets {
+		if rule == nil {
+			return nil, fmt.Errorf("rule set `%s` is empty", name)
+		}
+
+		if err := rule.BasicCheck(); err != nil {
+			return nil, fmt.Errorf("rule set `%s`: %w", name, err)
+		}
+	}
+
+	return ruleSets, nil
+}
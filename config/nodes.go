@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/kehiy/RoboPac/client"
+)
+
+// LoadNodeOptions loads the connection options of the nodes from a JSON file, that maps the address of each node
+// to its options. The nodes that are not in the file are connected without TLS and metadata.
+// If the path is empty, no options are returned.
+func LoadNodeOptions(path string, addrs []string) (map[string]*client.NodeOptions, error) {
+	nodeOptions := make(map[string]*client.NodeOptions)
+	if path == "" {
+		return nodeOptions, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &nodeOptions); err != nil {
+		return nil, err
+	}
+
+	for addr, opts := range nodeOptions {
+		if !slices.Contains(addrs, addr) {
+			return nil, fmt.Errorf("node %s is not in LOCAL_NODE or NETWORK_NODES", addr)
+		}
+
+		if opts == nil {
+			return nil, fmt.Errorf("node %s has no options", addr)
+		}
+
+		if err := opts.BasicCheck(); err != nil {
+			return nil, fmt.Errorf("node %s: %w", addr, err)
+		}
+	}
+
+	return nodeOptions, nil
+}
+
+// loadNodeConnConfig loads the keepalive and the reconnect backoff of the node connections.
+// The keepalive is off by default, and the backoff is the gRPC default: from 1 second up to 2 minutes.
+func loadNodeConnConfig() (client.ConnConfig, error) {
+	cfg := client.ConnConfig{}
+
+	var err error
+	for _, d := range []struct {
+		env   string
+		value *time.Duration
+		def   time.Duration
+	}{
+		{"NODE_KEEPALIVE_TIME", &cfg.KeepaliveTime, 0},
+		{"NODE_KEEPALIVE_TIMEOUT", &cfg.KeepaliveTimeout, 20 * time.Second},
+		{"NODE_BACKOFF_BASE_DELAY", &cfg.BackoffBaseDelay, time.Second},
+		{"NODE_BACKOFF_MAX_DELAY", &cfg.BackoffMaxDelay, 2 * time.Minute},
+	} {
+		*d.value, err = parseDuration(os.Getenv(d.env), d.def)
+		if err != nil {
+			return cfg, fmt.Errorf("%s is incorrect: %w", d.env, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// NodeOptionsOf returns the connection options of a node.
+func (cfg *Config) NodeOptionsOf(addr string) *client.NodeOptions {
+	if opts, ok := cfg.NodeOptions[addr]; ok {
+		return opts
+	}
+
+	return &client.NodeOptions{}
+}
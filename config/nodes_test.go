@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/kehiy/RoboPac/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadNodeOptions(t *testing.T) {
+	optionsPath := path.Join(t.TempDir(), "nodes.json")
+	nodes := []string{"localhost:50052", "node.example.com:443"}
+
+	t.Run("no options", func(t *testing.T) {
+		options, err := LoadNodeOptions("", nodes)
+		assert.NoError(t, err)
+		assert.Empty(t, options)
+	})
+
+	t.Run("valid options", func(t *testing.T) {
+		data := `{"node.example.com:443": {"tls": true, "metadata": {"authorization": "Basic dXNlcjpwYXNz"}}}`
+		require.NoError(t, os.WriteFile(optionsPath, []byte(data), 0o600))
+
+		options, err := LoadNodeOptions(optionsPath, nodes)
+		assert.NoError(t, err)
+
+		cfg := &Config{NodeOptions: options}
+		assert.True(t, cfg.NodeOptionsOf("node.example.com:443").TLS)
+		assert.Equal(t, "Basic dXNlcjpwYXNz", cfg.NodeOptionsOf("node.example.com:443").Metadata["authorization"])
+		assert.False(t, cfg.NodeOptionsOf("localhost:50052").TLS)
+	})
+
+	t.Run("unknown node", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(optionsPath, []byte(`{"other.example.com:443": {"tls": true}}`), 0o600))
+
+		_, err := LoadNodeOptions(optionsPath, nodes)
+		assert.EqualError(t, err, "node other.example.com:443 is not in LOCAL_NODE or NETWORK_NODES")
+	})
+
+	t.Run("null options", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(optionsPath, []byte(`{"node.example.com:443": null}`), 0o600))
+
+		_, err := LoadNodeOptions(optionsPath, nodes)
+		assert.EqualError(t, err, "node node.example.com:443 has no options")
+	})
+
+	t.Run("client certificate without key", func(t *testing.T) {
+		data := `{"node.example.com:443": {"tls": true, "cert_file": "client.pem"}}`
+		require.NoError(t, os.WriteFile(optionsPath, []byte(data), 0o600))
+
+		_, err := LoadNodeOptions(optionsPath, nodes)
+		assert.ErrorContains(t, err, "node node.example.com:443: both the cert file and the key file")
+	})
+}
+
+func TestLoadNodeConnConfig(t *testing.T) {
+	t.Setenv("NODE_KEEPALIVE_TIME", "5m")
+
+	cfg, err := loadNodeConnConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, client.ConnConfig{
+		KeepaliveTime:    5 * time.Minute,
+		KeepaliveTimeout: 20 * time.Second,
+		BackoffBaseDelay: time.Second,
+		BackoffMaxDelay:  2 * time.Minute,
+	}, cfg)
+
+	t.Setenv("NODE_BACKOFF_MAX_DELAY", "2")
+	_, err = loadNodeConnConfig()
+	assert.ErrorContains(t, err, "NODE_BACKOFF_MAX_DELAY is incorrect")
+}
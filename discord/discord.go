@@ -1,6 +1,7 @@
 package discord
 
 import (
+	"context"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -10,21 +11,29 @@ import (
 )
 
 type DiscordBot struct {
-	Session   *discordgo.Session
-	BotEngine engine.IEngine
-	GuildID   string
+	ctx            context.Context
+	cancel         context.CancelFunc
+	Session        *discordgo.Session
+	BotEngine      engine.IEngine
+	GuildID        string
+	AdminChannelID string
 }
 
-func NewDiscordBot(botEngine engine.IEngine, token, guildID string) (*DiscordBot, error) {
+func NewDiscordBot(botEngine engine.IEngine, token, guildID, adminChannelID string) (*DiscordBot, error) {
 	s, err := discordgo.New("Bot " + token)
 	if err != nil {
 		return nil, err
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &DiscordBot{
-		Session:   s,
-		BotEngine: botEngine,
-		GuildID:   guildID,
+		ctx:            ctx,
+		cancel:         cancel,
+		Session:        s,
+		BotEngine:      botEngine,
+		GuildID:        guildID,
+		AdminChannelID: adminChannelID,
 	}, nil
 }
 
@@ -32,9 +41,7 @@ func (db *DiscordBot) Start() {
 	log.Info("starting Discord Bot...")
 
 	db.Session.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		if h, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
-			h(db, s, i) // support db *DiscordBot
-		}
+		db.commandHandler(s, i)
 	})
 
 	err := db.Session.Open()
@@ -42,10 +49,17 @@ func (db *DiscordBot) Start() {
 		log.Panic("can't open discord session", "err", err)
 	}
 
+	// Sending the engine alerts to the admin channel and the users.
+	if db.AdminChannelID == "" {
+		log.Warn("no admin channel is set, admin alerts are only logged")
+	}
+	db.BotEngine.RegisterNotifier(engine.FrontendDiscord, db)
+
 	// Updating bot status in real-time by network info.
 	log.Info("starting info status")
 	go db.UpdateStatusInfo()
 
+	commands := applicationCommands(db.BotEngine.Commands())
 	registeredCommands := make([]*discordgo.ApplicationCommand, len(commands))
 	for i, v := range commands {
 		cmd, err := db.Session.ApplicationCommandCreate(db.Session.State.User.ID, db.GuildID, v)
@@ -57,11 +71,46 @@ func (db *DiscordBot) Start() {
 	}
 }
 
+// Notify sends the user alerts to the user as a direct message and the admin alerts to the admin channel.
+func (db *DiscordBot) Notify(alert *engine.Alert) {
+	if alert.UserID != "" {
+		channel, err := db.Session.UserChannelCreate(alert.UserID)
+		if err != nil {
+			log.Error("can't open the direct message channel", "err", err, "discordID", alert.UserID)
+
+			return
+		}
+
+		_, err = db.Session.ChannelMessageSendEmbed(channel.ID, alertEmbed(alert))
+		if err != nil {
+			log.Error("can't send the alert to the user", "err", err, "discordID", alert.UserID, "title", alert.Title)
+		}
+
+		return
+	}
+
+	if db.AdminChannelID == "" {
+		return
+	}
+
+	_, err := db.Session.ChannelMessageSendEmbed(db.AdminChannelID, alertEmbed(alert))
+	if err != nil {
+		log.Error("can't send the alert to the admin channel", "err", err, "title", alert.Title)
+	}
+}
+
 func (db *DiscordBot) UpdateStatusInfo() {
 	log.Info("info status started")
 	for {
-		ns, err := db.BotEngine.NetworkStatus()
+		ctx, cancel := context.WithTimeout(db.ctx, 30*time.Second)
+		ns, err := db.BotEngine.NetworkStatus(ctx)
+		cancel()
 		if err != nil {
+			if db.ctx.Err() != nil {
+				return
+			}
+			time.Sleep(time.Second * 5)
+
 			continue
 		}
 
@@ -112,5 +161,6 @@ func (db *DiscordBot) UpdateStatusInfo() {
 func (db *DiscordBot) Stop() {
 	log.Info("shutting down Discord Bot...")
 
+	db.cancel()
 	_ = db.Session.Close()
 }
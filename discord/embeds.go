@@ -4,100 +4,70 @@ import (
 	"fmt"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/kehiy/RoboPac/engine"
 )
 
-func helpEmbed(s *discordgo.Session) *discordgo.MessageEmbed {
-	return &discordgo.MessageEmbed{
-		Title: "RoboPac Help 🆘",
-		URL:   "https://pactus.org",
-		Author: &discordgo.MessageEmbedAuthor{
-			URL:     "https://pactus.org",
-			IconURL: s.State.User.AvatarURL(""),
-			Name:    s.State.User.Username,
-		},
-		Description: "RoboPac is a robot that provides support and information about the Pactus Blockchain.\n" +
-			"Here is a list of commands supported by RoboPac:\n" +
-			"```/claim``` Will help you to claim your test-net rewards on main-net.\n" +
-			"```/claimer-info``` Shows you status of your claim reward.\n" +
-			"```/node-info``` Shows a node and validator info in network and blockchain.\n" +
-			"```/network-status``` Shows a brief info about network.\n" +
-			"```/network-health``` Check and shows network health status.\n" +
-			"```/wallet``` Shows RoboPac wallet address and balance.\n" +
-			"```/booster-payment``` Create payment link in Validator Booster Program.\n" +
-			"```/booster-claim``` Claim the stake PAC coin in Validator Booster Program.\n",
-		Color: PACTUS,
+func helpEmbed(s *discordgo.Session, res *engine.Result) *discordgo.MessageEmbed {
+	embed := resultEmbed(res)
+	embed.URL = "https://pactus.org"
+	embed.Author = &discordgo.MessageEmbedAuthor{
+		URL:     "https://pactus.org",
+		IconURL: s.State.User.AvatarURL(""),
+		Name:    s.State.User.Username,
 	}
-}
 
-func claimEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, result string) *discordgo.MessageEmbed {
-	return &discordgo.MessageEmbed{
-		Title:       "Claim Result💸",
-		Description: result,
-		Color:       GREEN,
-	}
+	return embed
 }
 
-func claimerInfoEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, result string) *discordgo.MessageEmbed {
-	return &discordgo.MessageEmbed{
-		Title:       "Claimer Infoℹ️",
-		Description: result,
-		Color:       PACTUS,
+func resultEmbed(res *engine.Result) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       res.Title,
+		Description: res.Message,
+		Color:       statusColor(res.Status),
 	}
-}
 
-func nodeInfoEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, result string) *discordgo.MessageEmbed {
-	return &discordgo.MessageEmbed{
-		Title:       "Node Info🛟",
-		Description: result,
-		Color:       PACTUS,
+	for _, f := range res.Fields {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   f.Name,
+			Value:  f.Value,
+			Inline: true,
+		})
 	}
-}
 
-func networkHealthEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, result string, color int) *discordgo.MessageEmbed {
-	return &discordgo.MessageEmbed{
-		Title:       "Network Health🧑‍⚕️",
-		Description: result,
-		Color:       color,
+	for _, l := range res.Links {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  l.Name,
+			Value: l.URL,
+		})
 	}
-}
 
-func networkStatusEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, result string) *discordgo.MessageEmbed {
-	return &discordgo.MessageEmbed{
-		Title:       "Network Status🕸️",
-		Description: result,
-		Color:       PACTUS,
+	if res.Note != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{
+			Text: "Note📝: " + res.Note,
+		}
 	}
-}
 
-func botWalletEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, result string) *discordgo.MessageEmbed {
-	return &discordgo.MessageEmbed{
-		Title:       "Bot Wallet🪙",
-		Description: result,
-		Color:       PACTUS,
+	if res.Simulated {
+		embed.Title = "🧪 [SIMULATED] " + embed.Title
+		embed.Description = "**Dry-run mode: no transaction is broadcast.**\n" + embed.Description
 	}
-}
 
-func claimStatusEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, result string) *discordgo.MessageEmbed {
-	return &discordgo.MessageEmbed{
-		Title:       "Claim's Status📃",
-		Description: result,
-		Color:       PACTUS,
-	}
+	return embed
 }
 
-func rewardCalcEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, result string) *discordgo.MessageEmbed {
+func campaignEmbed(title, result string) *discordgo.MessageEmbed {
 	return &discordgo.MessageEmbed{
-		Title:       "Validator reward calculation🧮",
+		Title:       title,
 		Description: result,
 		Color:       PACTUS,
 	}
 }
 
-func boosterEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, result string) *discordgo.MessageEmbed {
+func alertEmbed(alert *engine.Alert) *discordgo.MessageEmbed {
 	return &discordgo.MessageEmbed{
-		Title:       "Pactus Validator Booster Program ✨",
-		Description: result,
-		Color:       PACTUS,
+		Title:       alert.Title,
+		Description: alert.Message,
+		Color:       statusColor(alert.Status),
 	}
 }
 
@@ -108,3 +78,11 @@ func errorEmbedMessage(reason string) *discordgo.MessageEmbed {
 		Color:       RED,
 	}
 }
+
+func timeoutEmbedMessage(reason string) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Title:       "Timeout⏳",
+		Description: reason,
+		Color:       YELLOW,
+	}
+}
@@ -1,15 +1,21 @@
 package discord
 
 import (
+	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/kehiy/RoboPac/engine"
 	"github.com/kehiy/RoboPac/log"
 )
 
 func (db *DiscordBot) respondErrMsg(cmdErr error, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	errorEmbed := errorEmbedMessage(cmdErr.Error())
+	var errorEmbed *discordgo.MessageEmbed
+	if errors.As(cmdErr, &engine.TimeoutError{}) {
+		errorEmbed = timeoutEmbedMessage(cmdErr.Error())
+	} else {
+		errorEmbed = errorEmbedMessage(cmdErr.Error())
+	}
 	db.respondEmbed(errorEmbed, s, i)
 }
 
@@ -27,226 +33,70 @@ func (db *DiscordBot) respondEmbed(embed *discordgo.MessageEmbed, s *discordgo.S
 	}
 }
 
-func helpCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
-		return
-	}
-
-	embed := helpEmbed(s)
-	db.respondEmbed(embed, s, i)
-}
-
-func claimCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (db *DiscordBot) commandHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
 		return
 	}
 
-	// Get the application command data
 	data := i.ApplicationCommandData()
-
-	// Extract the options
-	testnetAddr := data.Options[0].StringValue()
-	mainnetAddr := data.Options[1].StringValue()
-
-	log.Info("new claim request", "discordID", i.Member.User.ID, "mainNetAddr", mainnetAddr, "testNetAddr", testnetAddr)
-
-	command := fmt.Sprintf("claim %s %s %s", i.Member.User.ID, testnetAddr, mainnetAddr)
-
-	result, err := db.BotEngine.Run(command)
-	if err != nil {
-		db.respondErrMsg(err, s, i)
-
+	cmd := db.findCommand(data.Name)
+	if cmd == nil {
 		return
 	}
 
-	embed := claimEmbed(s, i, result)
-	db.respondEmbed(embed, s, i)
-}
-
-func claimerInfoCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
-		return
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(data.Options))
+	for _, opt := range data.Options {
+		options[opt.Name] = opt
 	}
 
-	testnetAddr := i.ApplicationCommandData().Options[0].StringValue()
-	command := fmt.Sprintf("claimer-info %s", testnetAddr)
-
-	result, err := db.BotEngine.Run(command)
-	if err != nil {
-		db.respondErrMsg(err, s, i)
-
-		return
+	in := &engine.Input{
+		Command: cmd.Name,
+		Flags:   make(map[string]string),
 	}
-
-	embed := claimerInfoEmbed(s, i, result)
-	db.respondEmbed(embed, s, i)
-}
-
-func nodeInfoCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
-		return
+	for _, arg := range cmd.Args {
+		if opt, ok := options[arg.Name]; ok {
+			in.Flags[arg.Name] = optionValue(opt)
+		}
 	}
 
-	valAddress := i.ApplicationCommandData().Options[0].StringValue()
-	command := fmt.Sprintf("node-info %s", valAddress)
+	log.Info("new command request", "discordID", i.Member.User.ID, "command", cmd.Name)
 
-	result, err := db.BotEngine.Run(command)
-	if err != nil {
-		db.respondErrMsg(err, s, i)
-
-		return
-	}
+	caller := engine.NewDiscordCaller(i.Member.User.ID, i.Member.Roles)
+	caller.JoinedAt = i.Member.JoinedAt
 
-	embed := nodeInfoEmbed(s, i, result)
-	db.respondEmbed(embed, s, i)
-}
-
-func networkHealthCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
-		return
-	}
-
-	command := "network-health"
-
-	result, err := db.BotEngine.Run(command)
+	res, err := db.BotEngine.Execute(db.ctx, caller, in)
 	if err != nil {
 		db.respondErrMsg(err, s, i)
 
 		return
 	}
 
-	var color int
-	if strings.Contains(result, "Healthy") {
-		color = GREEN
-	} else {
-		color = RED
-	}
-
-	embed := networkHealthEmbed(s, i, result, color)
-	db.respondEmbed(embed, s, i)
-}
+	if cmd.Name == engine.CmdHelp {
+		db.respondEmbed(helpEmbed(s, res), s, i)
 
-func networkStatusCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
 		return
 	}
 
-	result, err := db.BotEngine.Run("network")
-	if err != nil {
-		db.respondErrMsg(err, s, i)
-		return
-	}
-
-	embed := networkStatusEmbed(s, i, result)
-	db.respondEmbed(embed, s, i)
-}
+	// The public announcement is skipped in dry-run mode.
+	if cmd.Name == engine.CmdBoosterWhitelist && !res.Simulated {
+		pubMsg := fmt.Sprintf("The Twitter account @%s has been successfully whitelisted!", options["twitter-username"].StringValue())
+		_, err = s.ChannelMessageSendEmbed("1208143718482182184", campaignEmbed(res.Title, pubMsg))
+		if err != nil {
+			db.respondErrMsg(err, s, i)
 
-func walletCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
-		return
+			return
+		}
 	}
 
-	result, _ := db.BotEngine.Run("wallet")
-
-	embed := botWalletEmbed(s, i, result)
-	db.respondEmbed(embed, s, i)
+	db.respondEmbed(resultEmbed(res), s, i)
 }
 
-func claimStatusCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
-		return
+func (db *DiscordBot) findCommand(name string) *engine.Command {
+	for _, cmd := range db.BotEngine.Commands() {
+		if cmd.SlashName() == name {
+			return cmd
+		}
 	}
 
-	result, _ := db.BotEngine.Run("claim-status")
-
-	embed := claimStatusEmbed(s, i, result)
-	db.respondEmbed(embed, s, i)
-}
-
-func rewardCalcCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
-		return
-	}
-
-	stake := i.ApplicationCommandData().Options[0].StringValue()
-	time := i.ApplicationCommandData().Options[1].StringValue()
-
-	result, err := db.BotEngine.Run(fmt.Sprintf("calc-reward %v %v", stake, time))
-	if err != nil {
-		db.respondErrMsg(err, s, i)
-
-		return
-	}
-
-	embed := rewardCalcEmbed(s, i, result)
-	db.respondEmbed(embed, s, i)
-}
-
-func boosterPaymentCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
-		return
-	}
-
-	twitterName := i.ApplicationCommandData().Options[0].StringValue()
-	valAddr := i.ApplicationCommandData().Options[1].StringValue()
-
-	result, err := db.BotEngine.Run(fmt.Sprintf("booster-payment %v %v %v", i.Member.User.ID, twitterName, valAddr))
-	if err != nil {
-		db.respondErrMsg(err, s, i)
-		return
-	}
-
-	embed := boosterEmbed(s, i, result)
-	db.respondEmbed(embed, s, i)
-}
-
-func boosterClaimCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
-		return
-	}
-
-	twitterID := i.ApplicationCommandData().Options[0].StringValue()
-
-	result, err := db.BotEngine.Run(fmt.Sprintf("booster-claim %v", twitterID))
-	if err != nil {
-		db.respondErrMsg(err, s, i)
-		return
-	}
-
-	embed := boosterEmbed(s, i, result)
-	db.respondEmbed(embed, s, i)
-}
-
-func boosterWhitelistCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
-		return
-	}
-
-	twitterName := i.ApplicationCommandData().Options[0].StringValue()
-
-	result, err := db.BotEngine.Run(fmt.Sprintf("booster-whitelist %v %v", twitterName, i.Member.User.ID))
-	if err != nil {
-		db.respondErrMsg(err, s, i)
-		return
-	}
-
-	pubMsg := fmt.Sprintf("The Twitter account @%s has been successfully whitelisted!", twitterName)
-	pubEmbed := boosterEmbed(s, i, pubMsg)
-	_, err = s.ChannelMessageSendEmbed("1208143718482182184", pubEmbed)
-	if err != nil {
-		db.respondErrMsg(err, s, i)
-		return
-	}
-
-	embed := boosterEmbed(s, i, result)
-	db.respondEmbed(embed, s, i)
-}
-
-func boosterStatusCommandHandler(db *DiscordBot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if !checkMessage(i, s, db.GuildID, i.Member.User.ID) {
-		return
-	}
-	result, _ := db.BotEngine.Run("booster-status")
-	embed := boosterEmbed(s, i, result)
-	db.respondEmbed(embed, s, i)
+	return nil
 }
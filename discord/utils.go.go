@@ -2,12 +2,15 @@ package discord
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/kehiy/RoboPac/engine"
 )
 
 const (
 	GREEN  = 0x008000
+	YELLOW = 0xFFD700
 	RED    = 0xFF0000
 	PACTUS = 0x052D5A
 )
@@ -34,3 +37,26 @@ func newStatus(name string, value interface{}) discordgo.UpdateStatusData {
 		},
 	}
 }
+
+func optionValue(opt *discordgo.ApplicationCommandInteractionDataOption) string {
+	if opt.Type == discordgo.ApplicationCommandOptionInteger {
+		return strconv.FormatInt(opt.IntValue(), 10)
+	}
+
+	return opt.StringValue()
+}
+
+func statusColor(status engine.Status) int {
+	switch status {
+	case engine.StatusSuccess:
+		return GREEN
+	case engine.StatusWarning:
+		return YELLOW
+	case engine.StatusDanger:
+		return RED
+	case engine.StatusInfo:
+		return PACTUS
+	default:
+		return PACTUS
+	}
+}
@@ -0,0 +1,261 @@
+package eligibility
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kehiy/RoboPac/twitter_api"
+)
+
+const (
+	// TypeAll passes if all of its rules pass.
+	TypeAll = "all"
+	// TypeAny passes if any of its rules passes.
+	TypeAny = "any"
+
+	TypeTwitterVerified   = "twitter_verified"
+	TypeTwitterAge        = "twitter_age"
+	TypeTwitterFollowers  = "twitter_followers"
+	TypeWhitelisted       = "whitelisted"
+	TypeDiscordMemberAge  = "discord_member_age"
+	TypeUnstakedValidator = "unstaked_validator"
+)
+
+// The units of the age rules. The ages are counted by the calendar, so a year is not always 365 days.
+const (
+	UnitDays   = "days"
+	UnitMonths = "months"
+	UnitYears  = "years"
+)
+
+// Applicant is who applies for a package, the rules are checked against it.
+type Applicant struct {
+	Twitter *twitter_api.UserInfo
+	// Whitelisted is true if the Twitter account is whitelisted by the admins.
+	Whitelisted bool
+	// DiscordJoinedAt is when the user joined the Discord server. It is zero if it is unknown.
+	DiscordJoinedAt time.Time
+	// StakedValidator is true if the validator address is already staked.
+	StakedValidator bool
+	Now             time.Time
+}
+
+// RuleFunc checks a rule against the applicant.
+// It returns the reasons that the applicant fails the rule, or nothing if it passes.
+type RuleFunc func(rule *Rule, applicant *Applicant) []string
+
+// ruleFuncs are the types of the rules that check the applicant itself.
+// The `all` and `any` rules are not here, they only combine other rules.
+var ruleFuncs = map[string]RuleFunc{
+	TypeTwitterVerified:   checkTwitterVerified,
+	TypeTwitterAge:        checkTwitterAge,
+	TypeTwitterFollowers:  checkTwitterFollowers,
+	TypeWhitelisted:       checkWhitelisted,
+	TypeDiscordMemberAge:  checkDiscordMemberAge,
+	TypeUnstakedValidator: checkUnstakedValidator,
+}
+
+// Register adds a new type of rule, or replaces an existing one. The `all` and `any` types can't be replaced.
+// It should be called before the rules are loaded, e.g. in an init function.
+func Register(ruleType string, fn RuleFunc) {
+	ruleFuncs[ruleType] = fn
+}
+
+// Rule is an eligibility rule, loaded from the config.
+// The `all` and `any` rules combine other rules, so a rule set is a tree of rules.
+type Rule struct {
+	Type string `json:"type"`
+	// Min is the minimum that the rule requires, e.g. the account age or the number of followers.
+	Min int `json:"min,omitempty"`
+	// Unit is the unit of the minimum age in the age rules, the default is days.
+	Unit string `json:"unit,omitempty"`
+	// Rules are the rules that the `all` and `any` rules combine.
+	Rules []*Rule `json:"rules,omitempty"`
+}
+
+// BasicCheck checks the rule and its sub-rules.
+func (r *Rule) BasicCheck() error {
+	if r.Min < 0 {
+		return fmt.Errorf("the minimum of the rule `%s` is negative", r.Type)
+	}
+
+	switch r.Unit {
+	case "", UnitDays, UnitMonths, UnitYears:
+	default:
+		return fmt.Errorf("unknown unit of the rule `%s`: %s", r.Type, r.Unit)
+	}
+
+	if r.isGroup() {
+		if len(r.Rules) == 0 {
+			return fmt.Errorf("the rule `%s` has no rules", r.Type)
+		}
+	} else {
+		if _, ok := ruleFuncs[r.Type]; !ok {
+			return fmt.Errorf("unknown rule type: %s", r.Type)
+		}
+
+		if len(r.Rules) != 0 {
+			return fmt.Errorf("the rule `%s` can't have rules", r.Type)
+		}
+	}
+
+	for _, sub := range r.Rules {
+		if err := sub.BasicCheck(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check checks the applicant against the rule.
+// If the applicant is not eligible, a *RejectionError is returned with all the rules that it fails.
+func (r *Rule) Check(applicant *Applicant) error {
+	reasons := r.failures(applicant)
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	return &RejectionError{Reasons: reasons}
+}
+
+// isGroup returns true if the rule combines other rules.
+func (r *Rule) isGroup() bool {
+	return r.Type == TypeAll || r.Type == TypeAny
+}
+
+func (r *Rule) failures(applicant *Applicant) []string {
+	switch r.Type {
+	case TypeAll:
+		return r.allFailures(applicant)
+	case TypeAny:
+		return r.anyFailures(applicant)
+	}
+
+	fn, ok := ruleFuncs[r.Type]
+	if !ok {
+		return []string{fmt.Sprintf("unknown rule type: %s", r.Type)}
+	}
+
+	return fn(r, applicant)
+}
+
+// minTime returns the time that is the minimum age before now.
+func (r *Rule) minTime(now time.Time) time.Time {
+	switch r.Unit {
+	case UnitYears:
+		return now.AddDate(-r.Min, 0, 0)
+	case UnitMonths:
+		return now.AddDate(0, -r.Min, 0)
+	default:
+		return now.AddDate(0, 0, -r.Min)
+	}
+}
+
+// minAge returns the minimum age with its unit, e.g. `3 years`.
+func (r *Rule) minAge() string {
+	unit := r.Unit
+	if unit == "" {
+		unit = UnitDays
+	}
+
+	if r.Min == 1 {
+		unit = strings.TrimSuffix(unit, "s")
+	}
+
+	return fmt.Sprintf("%d %s", r.Min, unit)
+}
+
+func (r *Rule) allFailures(applicant *Applicant) []string {
+	reasons := []string{}
+	for _, sub := range r.Rules {
+		reasons = append(reasons, sub.failures(applicant)...)
+	}
+
+	return reasons
+}
+
+// anyFailures returns the failures of all the rules, since passing any of them is enough.
+func (r *Rule) anyFailures(applicant *Applicant) []string {
+	reasons := []string{}
+	for _, sub := range r.Rules {
+		failures := sub.failures(applicant)
+		if len(failures) == 0 {
+			return nil
+		}
+		reasons = append(reasons, failures...)
+	}
+
+	return reasons
+}
+
+// RejectionError is returned when the applicant fails the eligibility rules.
+type RejectionError struct {
+	Reasons []string
+}
+
+func (e *RejectionError) Error() string {
+	var builder strings.Builder
+	builder.WriteString("not eligible:")
+	for _, reason := range e.Reasons {
+		builder.WriteString("\n- ")
+		builder.WriteString(reason)
+	}
+
+	return builder.String()
+}
+
+func checkTwitterVerified(_ *Rule, applicant *Applicant) []string {
+	if applicant.Twitter == nil || !applicant.Twitter.IsVerified {
+		return []string{"the Twitter account is not verified"}
+	}
+
+	return nil
+}
+
+func checkTwitterAge(rule *Rule, applicant *Applicant) []string {
+	minCreatedAt := rule.minTime(applicant.Now)
+	if applicant.Twitter == nil || applicant.Twitter.CreatedAt.After(minCreatedAt) {
+		return []string{fmt.Sprintf("the Twitter account is less than %s old", rule.minAge())}
+	}
+
+	return nil
+}
+
+func checkTwitterFollowers(rule *Rule, applicant *Applicant) []string {
+	if applicant.Twitter == nil || applicant.Twitter.Followers < rule.Min {
+		return []string{fmt.Sprintf("the Twitter account has less than %d followers", rule.Min)}
+	}
+
+	return nil
+}
+
+func checkWhitelisted(_ *Rule, applicant *Applicant) []string {
+	if !applicant.Whitelisted {
+		return []string{"the Twitter account is not whitelisted"}
+	}
+
+	return nil
+}
+
+func checkDiscordMemberAge(rule *Rule, applicant *Applicant) []string {
+	if applicant.DiscordJoinedAt.IsZero() {
+		return []string{"the Discord membership can't be checked, run the command in the Discord server"}
+	}
+
+	minJoinedAt := rule.minTime(applicant.Now)
+	if applicant.DiscordJoinedAt.After(minJoinedAt) {
+		return []string{fmt.Sprintf("you have joined the Discord server less than %s ago", rule.minAge())}
+	}
+
+	return nil
+}
+
+func checkUnstakedValidator(_ *Rule, applicant *Applicant) []string {
+	if applicant.StakedValidator {
+		return []string{"this address is already a staked validator"}
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package eligibility
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kehiy/RoboPac/twitter_api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	now := time.Now()
+	booster := &Rule{
+		Type: TypeAll,
+		Rules: []*Rule{
+			{Type: TypeUnstakedValidator},
+			{
+				Type: TypeAny,
+				Rules: []*Rule{
+					{Type: TypeTwitterVerified},
+					{Type: TypeWhitelisted},
+					{
+						Type: TypeAll,
+						Rules: []*Rule{
+							{Type: TypeTwitterAge, Min: 3, Unit: UnitYears},
+							{Type: TypeTwitterFollowers, Min: 200},
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.NoError(t, booster.BasicCheck())
+
+	newApplicant := func(ageYears, followers int) *Applicant {
+		return &Applicant{
+			Twitter: &twitter_api.UserInfo{
+				CreatedAt: now.AddDate(-ageYears, 0, 0),
+				Followers: followers,
+			},
+			Now: now,
+		}
+	}
+
+	t.Run("active account", func(t *testing.T) {
+		assert.NoError(t, booster.Check(newApplicant(4, 300)))
+	})
+
+	t.Run("verified or whitelisted accounts are not checked", func(t *testing.T) {
+		verified := newApplicant(1, 10)
+		verified.Twitter.IsVerified = true
+		assert.NoError(t, booster.Check(verified))
+
+		whitelisted := newApplicant(1, 10)
+		whitelisted.Whitelisted = true
+		assert.NoError(t, booster.Check(whitelisted))
+	})
+
+	t.Run("all the failed rules are listed", func(t *testing.T) {
+		applicant := newApplicant(1, 10)
+		applicant.StakedValidator = true
+
+		err := booster.Check(applicant)
+		rejection := &RejectionError{}
+		assert.ErrorAs(t, err, &rejection)
+		assert.Equal(t, []string{
+			"this address is already a staked validator",
+			"the Twitter account is not verified",
+			"the Twitter account is not whitelisted",
+			"the Twitter account is less than 3 years old",
+			"the Twitter account has less than 200 followers",
+		}, rejection.Reasons)
+		assert.Equal(t, "not eligible:\n- this address is already a staked validator\n"+
+			"- the Twitter account is not verified\n- the Twitter account is not whitelisted\n"+
+			"- the Twitter account is less than 3 years old\n- the Twitter account has less than 200 followers",
+			err.Error())
+	})
+
+	t.Run("ages are counted by the calendar", func(t *testing.T) {
+		// Three years ago is 1096 days ago when a leap day is in between.
+		leapNow := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		applicant := newApplicant(0, 300)
+		applicant.Now = leapNow
+		applicant.Twitter.CreatedAt = leapNow.AddDate(0, 0, -1095)
+
+		ageRule := &Rule{Type: TypeTwitterAge, Min: 3, Unit: UnitYears}
+		assert.ErrorContains(t, ageRule.Check(applicant), "the Twitter account is less than 3 years old")
+
+		applicant.Twitter.CreatedAt = leapNow.AddDate(-3, 0, 0)
+		assert.NoError(t, ageRule.Check(applicant))
+
+		monthRule := &Rule{Type: TypeDiscordMemberAge, Min: 1, Unit: UnitMonths}
+		applicant.DiscordJoinedAt = leapNow.AddDate(0, 0, -27)
+		assert.ErrorContains(t, monthRule.Check(applicant), "you have joined the Discord server less than 1 month ago")
+
+		applicant.DiscordJoinedAt = leapNow.AddDate(0, -1, 0)
+		assert.NoError(t, monthRule.Check(applicant))
+
+		assert.ErrorContains(t, (&Rule{Type: TypeTwitterAge, Min: 3, Unit: "weeks"}).BasicCheck(),
+			"unknown unit of the rule `twitter_age`: weeks")
+	})
+
+	t.Run("Discord membership", func(t *testing.T) {
+		rule := &Rule{Type: TypeDiscordMemberAge, Min: 30}
+		applicant := newApplicant(1, 10)
+
+		assert.ErrorContains(t, rule.Check(applicant), "the Discord membership can't be checked")
+
+		applicant.DiscordJoinedAt = now.AddDate(0, 0, -10)
+		assert.ErrorContains(t, rule.Check(applicant), "you have joined the Discord server less than 30 days ago")
+
+		applicant.DiscordJoinedAt = now.AddDate(0, -2, 0)
+		assert.NoError(t, rule.Check(applicant))
+	})
+}
+
+func TestRegister(t *testing.T) {
+	rule := &Rule{Type: "has_name"}
+	assert.ErrorContains(t, rule.BasicCheck(), "unknown rule type: has_name")
+
+	Register("has_name", func(_ *Rule, applicant *Applicant) []string {
+		if applicant.Twitter.TwitterName == "" {
+			return []string{"the Twitter account has no name"}
+		}
+
+		return nil
+	})
+	t.Cleanup(func() { delete(ruleFuncs, "has_name") })
+
+	assert.NoError(t, rule.BasicCheck())
+	assert.NoError(t, rule.Check(&Applicant{Twitter: &twitter_api.UserInfo{TwitterName: "pactus"}}))
+	assert.EqualError(t, rule.Check(&Applicant{Twitter: &twitter_api.UserInfo{}}),
+		"not eligible:\n- the Twitter account has no name")
+}
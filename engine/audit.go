@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kehiy/RoboPac/audit"
+	"github.com/kehiy/RoboPac/journal"
+	"github.com/kehiy/RoboPac/store"
+	"github.com/kehiy/RoboPac/utils"
+)
+
+// The audit log names of the changes that are made without a command.
+const (
+	auditPayout      = "payout"
+	auditPartyStatus = "party-status"
+)
+
+// The background jobs that change the state, as the actors of the audit log.
+const (
+	jobPaymentEvent      = "payment-event"
+	jobInvoiceReconciler = "invoice-reconciler"
+	jobPayoutReconciler  = "payout-reconciler"
+	jobExpiry            = "expiry"
+	// jobClaim and jobCampaignClaim are the actors of the claims that are not run as a command.
+	jobClaim         = "claim"
+	jobCampaignClaim = "campaign-claim"
+)
+
+// audit writes the outcome of a state-changing command to the audit log.
+// The command has already been run at this point, so a failure to write the log
+// can't be returned to the caller and is only logged.
+func (be *BotEngine) audit(caller *Caller, command string, args map[string]string, res *Result, err error) {
+	rec := &audit.Record{
+		Actor:     caller.String(),
+		Command:   command,
+		Args:      args,
+		Simulated: be.dryRun,
+	}
+
+	if err != nil {
+		rec.Result = fmt.Sprintf("error: %s", err)
+	} else {
+		rec.Result = fmt.Sprintf("%s: %s", res.Status, res.Message)
+		rec.TxID = res.TxID
+	}
+
+	be.appendAudit(rec)
+}
+
+// auditPayout writes the outcome of a payout to the audit log, whether a command or a background job has sent it.
+func (be *BotEngine) auditPayout(caller *Caller, entry *journal.Entry, err error) {
+	rec := &audit.Record{
+		Actor:   caller.String(),
+		Command: auditPayout,
+		Args: map[string]string{
+			"kind":     entry.Kind,
+			"ref":      entry.Ref,
+			"receiver": entry.Receiver,
+			"amount":   strconv.FormatFloat(utils.ChangeToCoin(entry.Amount), 'f', -1, 64),
+		},
+		Result:    string(entry.State),
+		TxID:      entry.TxID,
+		Simulated: be.dryRun,
+	}
+	if err != nil {
+		rec.Result = fmt.Sprintf("error: %s", err)
+	}
+
+	be.appendAudit(rec)
+}
+
+// auditPartyStatus writes the change of the status or the payment status of the party to the audit log.
+// Nothing is written if none of them is changed.
+func (be *BotEngine) auditPartyStatus(caller *Caller, campaignID string, previous, party *store.TwitterParty) {
+	if previous.Status == party.Status && previous.PaymentStatus == party.PaymentStatus {
+		return
+	}
+
+	be.appendAudit(&audit.Record{
+		Actor:   caller.String(),
+		Command: auditPartyStatus,
+		Args: map[string]string{
+			"campaign":       campaignID,
+			"twitter-name":   party.TwitterName,
+			"discount-code":  party.DiscountCode,
+			"payment-status": party.PaymentStatus,
+			"paid-amount":    strconv.FormatFloat(party.PaidAmount, 'f', 2, 64),
+		},
+		Result:    fmt.Sprintf("%s -> %s", previous.Status, party.Status),
+		TxID:      party.TransactionID,
+		Simulated: be.dryRun,
+	})
+}
+
+func (be *BotEngine) appendAudit(rec *audit.Record) {
+	if err := be.auditLog.Append(rec); err != nil {
+		be.logger.Error("unable to write the audit log",
+			"error", err,
+			"actor", rec.Actor,
+			"command", rec.Command,
+			"result", rec.Result,
+			"txID", rec.TxID,
+		)
+	}
+}
@@ -0,0 +1,339 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kehiy/RoboPac/config"
+	"github.com/kehiy/RoboPac/eligibility"
+	"github.com/kehiy/RoboPac/payment"
+	"github.com/kehiy/RoboPac/store"
+	"github.com/kehiy/RoboPac/utils"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/pactus-project/pactus/util/logger"
+)
+
+// findCampaign returns the campaign definition by its ID.
+func (be *BotEngine) findCampaign(campaignID string) (*config.Campaign, error) {
+	for _, c := range be.campaigns {
+		if c.ID == campaignID {
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown campaign: %s", campaignID)
+}
+
+// campaignIDs returns the IDs of the campaigns, the first one is the default campaign of the commands.
+func campaignIDs(campaigns []*config.Campaign) []string {
+	ids := make([]string, 0, len(campaigns))
+	for _, c := range campaigns {
+		ids = append(ids, c.ID)
+	}
+
+	return ids
+}
+
+// campaignTitle returns the title of the campaign, that the results of its commands are shown with.
+func (be *BotEngine) campaignTitle(campaignID string) string {
+	campaign, err := be.findCampaign(campaignID)
+	if err != nil {
+		return ""
+	}
+
+	return campaign.Title
+}
+
+// provider returns the payment provider of the campaign.
+func (be *BotEngine) provider(campaign *config.Campaign) (payment.IPaymentProvider, error) {
+	provider, ok := be.providers[campaign.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown payment provider: %s", campaign.Provider)
+	}
+
+	return provider, nil
+}
+
+// paymentLink returns the link that the party pays its invoice with.
+func (be *BotEngine) paymentLink(campaignID string, party *store.TwitterParty) string {
+	campaign, err := be.findCampaign(campaignID)
+	if err != nil {
+		return ""
+	}
+
+	provider, err := be.provider(campaign)
+	if err != nil {
+		return ""
+	}
+
+	return provider.PaymentLink(party)
+}
+
+// paymentExpiry returns the time that the discount code of the party expires.
+func (be *BotEngine) paymentExpiry(campaignID string, party *store.TwitterParty) time.Time {
+	campaign, err := be.findCampaign(campaignID)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return campaign.PaymentExpiry(party.CreatedAt)
+}
+
+// CampaignPayment reserves a package of the campaign for the Twitter account and creates its payment.
+// The package is reserved atomically in the store, so the cap of the campaign is exact.
+// The slow Twitter and payment calls are done without holding the engine lock.
+func (be *BotEngine) CampaignPayment(ctx context.Context, campaignID, discordID, twitterName, valAddr string,
+) (*store.TwitterParty, error) {
+	campaign, err := be.findCampaign(campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := be.provider(campaign)
+	if err != nil {
+		return nil, err
+	}
+
+	// The expired parties can register again.
+	existingParty := be.store.FindTwitterParty(campaign.ID, twitterName)
+	if existingParty != nil && !existingParty.IsExpired() {
+		if existingParty.TransactionID != "" {
+			return nil, fmt.Errorf("transaction is processed before: https://pacscan.org/transactions/%v", existingParty.TransactionID)
+		}
+
+		return existingParty, nil
+	}
+
+	if err := campaign.IsOpen(time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Failing fast, the cap is checked again on the reservation.
+	status := be.store.CampaignStatus(campaign.ID)
+	if campaign.MaxPackages > 0 && status.AllPkgs >= campaign.MaxPackages {
+		return nil, store.ErrCampaignFull
+	}
+
+	valInfo, _ := be.clientMgr.GetValidatorInfo(ctx, valAddr)
+
+	pubKey, err := be.clientMgr.FindPublicKey(ctx, valAddr, false)
+	if err != nil {
+		return nil, err
+	}
+
+	userInfo, err := be.twitterClient.UserInfo(ctx, twitterName)
+	if err != nil {
+		return nil, err
+	}
+
+	applicant := &eligibility.Applicant{
+		Twitter:         userInfo,
+		Whitelisted:     be.store.IsWhitelisted(campaign.ID, userInfo.TwitterID),
+		StakedValidator: valInfo != nil,
+		Now:             time.Now(),
+	}
+	if caller := callerFrom(ctx); caller != nil && caller.ID == discordID {
+		applicant.DiscordJoinedAt = caller.JoinedAt
+	}
+	if err := be.checkEligibility(campaign, applicant); err != nil {
+		return nil, err
+	}
+
+	tweetInfo, err := be.twitterClient.RetweetSearch(ctx, discordID, twitterName)
+	if err != nil {
+		return nil, err
+	}
+
+	discountCode, err := gonanoid.Generate("0123456789", 8)
+	if err != nil {
+		return nil, err
+	}
+
+	party := &store.TwitterParty{
+		TwitterID:    userInfo.TwitterID,
+		TwitterName:  userInfo.TwitterName,
+		RetweetID:    tweetInfo.ID,
+		ValAddr:      valAddr,
+		ValPubKey:    pubKey,
+		AmountInPAC:  campaign.Reward(userInfo.Followers),
+		DiscountCode: discountCode,
+		DiscordID:    discordID,
+		CreatedAt:    time.Now().Unix(),
+	}
+	if caller := callerFrom(ctx); caller != nil {
+		party.Frontend = caller.Frontend
+	}
+
+	reserved, err := be.store.ReservePackage(campaign.ID, campaign.MaxPackages, party)
+	if err != nil {
+		return nil, err
+	}
+
+	party.TotalPrice = campaign.Price(reserved)
+	if party.TotalPrice == 0 {
+		be.releasePackage(campaign.ID, party)
+
+		return nil, store.ErrCampaignFull
+	}
+
+	err = provider.CreateInvoice(ctx, party)
+	if err != nil {
+		be.releasePackage(campaign.ID, party)
+
+		return nil, err
+	}
+
+	err = be.store.SaveTwitterParty(campaign.ID, party)
+	if err != nil {
+		be.releasePackage(campaign.ID, party)
+
+		return nil, err
+	}
+
+	return party, nil
+}
+
+// checkEligibility checks the applicant against the eligibility rules of the campaign.
+// The rejection lists all the rules that the applicant fails, with the whitelist URL of the campaign.
+func (be *BotEngine) checkEligibility(campaign *config.Campaign, applicant *eligibility.Applicant) error {
+	if campaign.Eligibility == nil {
+		return nil
+	}
+
+	err := campaign.Eligibility.Check(applicant)
+	if err == nil {
+		return nil
+	}
+
+	be.logger.Debug("applicant is not eligible", "campaign", campaign.ID,
+		"twitterName", applicant.Twitter.TwitterName, "error", err)
+
+	if campaign.WhitelistURL == "" {
+		return fmt.Errorf("you are %w", err)
+	}
+
+	return fmt.Errorf("you are %w\nTo whitelist your Twitter click here: %s", err, campaign.WhitelistURL)
+}
+
+// releasePackage releases the reserved package, when the payment can't be created.
+func (be *BotEngine) releasePackage(campaignID string, party *store.TwitterParty) {
+	if err := be.store.ReleasePackage(campaignID, party.TwitterID); err != nil {
+		be.logger.Error("unable to release the package", "error", err,
+			"campaign", campaignID, "twitterName", party.TwitterName)
+	}
+}
+
+// CampaignClaim checks the payment of the party and sends its stake PAC coins once it is paid.
+// The payment is checked without holding the engine lock.
+func (be *BotEngine) CampaignClaim(ctx context.Context, campaignID, twitterName string) (*store.TwitterParty, error) {
+	campaign, err := be.findCampaign(campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	party := be.store.FindTwitterParty(campaign.ID, twitterName)
+	if party == nil {
+		return nil, fmt.Errorf("no discount code generated for this Twitter account: `%v`", twitterName)
+	}
+
+	if party.IsExpired() {
+		return nil, fmt.Errorf("the discount code `%s` is expired at %s and the payments after that are not accepted."+
+			" If you have paid it, please contact the support team", party.DiscountCode,
+			time.Unix(party.ExpiredAt, 0).Format(time.DateOnly))
+	}
+
+	caller := callerOrSystem(ctx, jobCampaignClaim)
+	if party.Status == store.PartyReserved {
+		previous := *party
+		changed, err := be.checkInvoice(ctx, campaign, party)
+		if err != nil {
+			return nil, err
+		}
+
+		// The party may be expired while checking the payment.
+		if changed {
+			if err := be.savePaymentStatus(caller, campaign, party, &previous); err != nil {
+				return nil, err
+			}
+		}
+
+		if !party.PaymentFinished {
+			return party, nil
+		}
+	}
+
+	return be.fulfilParty(ctx, caller, campaign, twitterName)
+}
+
+// fulfilParty sends the stake PAC coins of the paid party.
+// Both the users and the payment events can fulfil the party, so it is done holding the engine lock.
+// The change is written to the audit log on behalf of the caller.
+func (be *BotEngine) fulfilParty(ctx context.Context, caller *Caller, campaign *config.Campaign,
+	twitterName string,
+) (*store.TwitterParty, error) {
+	be.Lock()
+	defer be.Unlock()
+
+	// Looking the party up again, it may be claimed by another request meanwhile.
+	party := be.store.FindTwitterParty(campaign.ID, twitterName)
+	if party == nil {
+		return nil, fmt.Errorf("no discount code generated for this Twitter account: `%v`", twitterName)
+	}
+
+	switch {
+	case party.Status != store.PartyPaid:
+
+	case party.TransactionID != "":
+		// The provider has sent the bond transaction itself.
+		previous := *party
+		party.Status = store.PartyFulfilled
+		if err := be.store.SaveTwitterParty(campaign.ID, party); err != nil {
+			return nil, err
+		}
+		be.auditPartyStatus(caller, campaign.ID, &previous, party)
+		be.trackTransaction(campaign.ID, party.TwitterName, party.TransactionID)
+
+	default:
+		logger.Info("sending bond transaction", "campaign", campaign.ID,
+			"receiver", party.ValAddr, "amount", party.AmountInPAC)
+		txID, err := be.payout(ctx, caller, campaign.ID, party.TwitterName, party.DiscordID, party.ValPubKey, party.ValAddr,
+			campaign.Memo, utils.CoinToChange(float64(party.AmountInPAC)))
+		if err != nil {
+			return nil, err
+		}
+		party.TransactionID = txID
+		party.Status = store.PartyFulfilled
+	}
+
+	return party, nil
+}
+
+func (be *BotEngine) CampaignWhitelist(ctx context.Context, campaignID, twitterName, authorizedDiscordID string) error {
+	campaign, err := be.findCampaign(campaignID)
+	if err != nil {
+		return err
+	}
+
+	foundParty := be.store.FindTwitterParty(campaign.ID, twitterName)
+	if foundParty != nil {
+		return fmt.Errorf("the Twitter `%v` already registered for the campaign. Discount code is %v",
+			foundParty.TwitterName, foundParty.DiscountCode)
+	}
+
+	userInfo, err := be.twitterClient.UserInfo(ctx, twitterName)
+	if err != nil {
+		return err
+	}
+
+	return be.store.WhitelistTwitterAccount(campaign.ID, userInfo.TwitterID, userInfo.TwitterName, authorizedDiscordID)
+}
+
+func (be *BotEngine) CampaignStatus(_ context.Context, campaignID string) (*store.CampaignStatus, error) {
+	campaign, err := be.findCampaign(campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	return be.store.CampaignStatus(campaign.ID), nil
+}
@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ArgType int
+
+const (
+	ArgTypeString ArgType = iota
+	ArgTypeInteger
+)
+
+// Arg describes one argument of a command.
+type Arg struct {
+	Name    string
+	Desc    string
+	Type    ArgType
+	Choices []string
+
+	// Optional arguments can be left empty. If Default is set, it is used when
+	// the argument is not provided.
+	Optional bool
+	Default  string
+
+	// FromCaller means the argument is filled with the caller's ID instead of asking the user for it.
+	// It can't be set by position, and only admins can set it by flag.
+	FromCaller bool
+}
+
+// Command declares a bot command. The engine dispatcher, the help output and
+// the frontends' command lists are all generated from the declared commands.
+//
+// Timeout is the default deadline of the command and can be overridden in the config.
+// Audited commands move funds or change the store, every run of them is written to the audit log.
+// DiscordName is the name of the slash command when it differs from Name.
+type Command struct {
+	Name        string
+	DiscordName string
+	Title       string
+	Desc        string
+	Args        []Arg
+	Role        Role
+	Timeout     time.Duration
+	Audited     bool
+	Handler     func(ctx context.Context, args map[string]string) (*Result, error)
+}
+
+// UsageError is returned when the command arguments are not valid.
+type UsageError struct {
+	Command *Command
+	Reason  string
+}
+
+func (e UsageError) Error() string {
+	return fmt.Sprintf("%s\nusage: %s", e.Reason, e.Command.Usage())
+}
+
+// TimeoutError is returned when a command does not finish before its deadline.
+type TimeoutError struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("command `%s` timed out after %v, please try again later", e.Command, e.Timeout)
+}
+
+func (a *Arg) IsRequired() bool {
+	return !a.Optional && a.Default == ""
+}
+
+// SlashName returns the name of the command in the Discord slash commands.
+func (c *Command) SlashName() string {
+	if c.DiscordName != "" {
+		return c.DiscordName
+	}
+
+	return c.Name
+}
+
+// Usage returns the text form of the command, e.g. `calc-reward <stake> [time=day]`.
+func (c *Command) Usage() string {
+	var sb strings.Builder
+	sb.WriteString(c.Name)
+	for _, arg := range c.Args {
+		switch {
+		case arg.FromCaller:
+			sb.WriteString(fmt.Sprintf(" [--%s=<caller>]", arg.Name))
+		case arg.IsRequired():
+			sb.WriteString(fmt.Sprintf(" <%s>", arg.Name))
+		case arg.Default != "":
+			sb.WriteString(fmt.Sprintf(" [%s=%s]", arg.Name, arg.Default))
+		default:
+			sb.WriteString(fmt.Sprintf(" [%s]", arg.Name))
+		}
+	}
+
+	return sb.String()
+}
+
+func (c *Command) findArg(name string) *Arg {
+	for i, arg := range c.Args {
+		if arg.Name == name {
+			return &c.Args[i]
+		}
+	}
+
+	return nil
+}
+
+// bindArgs maps the flags and the positional arguments of the input to the declared arguments.
+// Flags are bound by name, positional arguments fill the remaining arguments in order.
+// The arguments filled by the caller's ID get the caller ID, unless they are set by flag.
+func (c *Command) bindArgs(caller *Caller, in *Input) (map[string]string, error) {
+	args := make(map[string]string, len(c.Args))
+	for name, value := range in.Flags {
+		if c.findArg(name) == nil {
+			return nil, UsageError{Command: c, Reason: fmt.Sprintf("unknown flag: --%s", name)}
+		}
+		args[name] = value
+	}
+
+	params := in.Args
+	for _, arg := range c.Args {
+		if _, ok := args[arg.Name]; ok {
+			continue
+		}
+
+		if arg.FromCaller {
+			if caller.ID == "" {
+				return nil, UsageError{Command: c, Reason: fmt.Sprintf("missing flag: --%s", arg.Name)}
+			}
+			args[arg.Name] = caller.ID
+
+			continue
+		}
+
+		if len(params) > 0 {
+			args[arg.Name] = params[0]
+			params = params[1:]
+
+			continue
+		}
+
+		if arg.IsRequired() {
+			return nil, UsageError{Command: c, Reason: fmt.Sprintf("missing argument: %s", arg.Name)}
+		}
+
+		if arg.Default != "" {
+			args[arg.Name] = arg.Default
+		}
+	}
+
+	if len(params) > 0 {
+		return nil, UsageError{
+			Command: c,
+			Reason:  fmt.Sprintf("too many arguments, unexpected %q", strings.Join(params, " ")),
+		}
+	}
+
+	for _, arg := range c.Args {
+		value, ok := args[arg.Name]
+		if !ok {
+			continue
+		}
+
+		if arg.Type == ArgTypeInteger {
+			if _, err := strconv.Atoi(value); err != nil {
+				return nil, UsageError{
+					Command: c,
+					Reason:  fmt.Sprintf("invalid value for %s: %s is not a number", arg.Name, value),
+				}
+			}
+		}
+
+		if len(arg.Choices) > 0 && !slices.Contains(arg.Choices, value) {
+			return nil, UsageError{
+				Command: c,
+				Reason:  fmt.Sprintf("invalid value for %s: expected one of %s", arg.Name, strings.Join(arg.Choices, "/")),
+			}
+		}
+	}
+
+	return args, nil
+}
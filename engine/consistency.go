@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kehiy/RoboPac/client"
+)
+
+// NetworkConsistency compares the state of the blockchain between all the RPC nodes.
+// The local node is lagging if it is more than the max local lag behind the majority of the nodes.
+func (be *BotEngine) NetworkConsistency(ctx context.Context) (*client.ConsistencyReport, error) {
+	report := be.clientMgr.CheckConsistency(ctx, be.maxLocalNodeLag)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (be *BotEngine) consistencyCheckLoop() {
+	ticker := time.NewTicker(be.consistencyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-be.ctx.Done():
+			return
+
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(be.ctx, be.consistencyCheckInterval)
+			report := be.clientMgr.CheckConsistency(ctx, be.maxLocalNodeLag)
+			cancel()
+
+			be.alertInconsistencies(report)
+		}
+	}
+}
+
+// alertInconsistencies alerts the admins when the local node falls behind the majority of the nodes,
+// or a node is on a possible fork. Each problem is alerted once, until it is solved.
+// It is only used by the consistency check loop.
+func (be *BotEngine) alertInconsistencies(report *client.ConsistencyReport) {
+	reported := make(map[string]bool)
+
+	local := report.Snapshot(be.localNode)
+	if local != nil && local.Err == nil && local.Lag > be.maxLocalNodeLag {
+		reported["lag"] = true
+		if !be.reportedInconsistencies["lag"] {
+			be.alert(StatusDanger, "Local node is lagging🐢", fmt.Sprintf(
+				"The local node `%s` is at height %d, %d blocks behind the majority of the nodes at height %d.",
+				local.Name, local.Height, local.Lag, report.MajorityHeight))
+		}
+	}
+
+	for _, name := range report.Forked {
+		key := "fork/" + name
+		reported[key] = true
+		if be.reportedInconsistencies[key] {
+			continue
+		}
+
+		be.alert(StatusDanger, "Possible fork🍴", fmt.Sprintf(
+			"The node `%s` has a different block at height %d than the majority of the nodes.",
+			name, report.CommonHeight))
+	}
+
+	if len(reported) == 0 && len(be.reportedInconsistencies) > 0 {
+		be.alert(StatusInfo, "Nodes are consistent✅",
+			"The local node is not lagging and all the nodes agree on the blockchain again.")
+	}
+
+	be.reportedInconsistencies = reported
+}
+
+// describeSnapshot describes the state of an RPC node in one line.
+func describeSnapshot(report *client.ConsistencyReport, snapshot *client.NodeSnapshot) string {
+	switch {
+	case snapshot.Err != nil:
+		return fmt.Sprintf("❌ unreachable: %s", snapshot.Err)
+
+	case slices.Contains(report.Forked, snapshot.Name):
+		return fmt.Sprintf("🍴 different block at height %d: %s", report.CommonHeight, snapshot.CommonHash)
+
+	case slices.Contains(report.Divergent, snapshot.Name):
+		return fmt.Sprintf("⚠️ different stake at height %d: %d validators, total power %d",
+			snapshot.Height, snapshot.TotalValidators, snapshot.TotalPower)
+
+	case slices.Contains(report.Lagging, snapshot.Name):
+		return fmt.Sprintf("⚠️ %d blocks behind, height %d", snapshot.Lag, snapshot.Height)
+
+	default:
+		return fmt.Sprintf("✅ height %d, last block %s", snapshot.Height, snapshot.LastBlockHash)
+	}
+}
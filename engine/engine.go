@@ -4,36 +4,83 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"slices"
 	"sync"
 	"time"
 
+	"github.com/kehiy/RoboPac/audit"
 	"github.com/kehiy/RoboPac/client"
 	"github.com/kehiy/RoboPac/config"
+	"github.com/kehiy/RoboPac/journal"
 	"github.com/kehiy/RoboPac/log"
 	"github.com/kehiy/RoboPac/nowpayments"
+	"github.com/kehiy/RoboPac/payment"
 	"github.com/kehiy/RoboPac/store"
+	"github.com/kehiy/RoboPac/turboswap"
 	"github.com/kehiy/RoboPac/twitter_api"
 	"github.com/kehiy/RoboPac/utils"
 	"github.com/kehiy/RoboPac/wallet"
+	"github.com/kehiy/RoboPac/webhook"
 	"github.com/libp2p/go-libp2p/core/peer"
-	gonanoid "github.com/matoous/go-nanoid/v2"
 	putils "github.com/pactus-project/pactus/util"
-	"github.com/pactus-project/pactus/util/logger"
 )
 
 type BotEngine struct {
 	ctx    context.Context //nolint
 	cancel func()
 
-	wallet      wallet.IWallet
-	store       store.IStore
-	nowpayments nowpayments.INowpayment
-	clientMgr   *client.Mgr
-	logger      *log.SubLogger
+	wallet    wallet.IWallet
+	store     store.IStore
+	providers map[string]payment.IPaymentProvider
+	auditLog  *audit.Log
+	journal   *journal.Journal
+	clientMgr *client.Mgr
+	logger    *log.SubLogger
+
+	webhooks *webhook.Server
+	// webhookListenAddr is the address of the webhook server, it is not started if it is empty.
+	webhookListenAddr string
 
 	twitterClient twitter_api.IClient
-	AuthIDs       []string
+	roles         map[string]config.RoleMembers
+	commands      []*Command
+
+	commandTimeout  time.Duration
+	commandTimeouts map[string]time.Duration
+	dryRun          bool
+
+	txTrackInterval  time.Duration
+	txConfirmTimeout time.Duration
+	limits           config.PayoutLimits
+	campaigns        []*config.Campaign
+
+	expiryCheckInterval time.Duration
+	paymentEvents       chan *payment.Event
+
+	paymentReconcileInterval time.Duration
+	latePaymentWindow        time.Duration
+	// reportedMismatches are the payment mismatches that the admins are alerted about.
+	// It is only used by the reconciler loop.
+	reportedMismatches map[string]bool
+
+	// payoutFailures are the paid parties that their bond transaction has failed, by their campaign and Twitter name.
+	// The admins are alerted once, and the reconciler retries them with a backoff.
+	payoutFailures     map[string]*payoutFailure
+	payoutFailuresLock sync.Mutex
+
+	consistencyCheckInterval time.Duration
+	// localNode is the name of the local node, it is alerted when it falls behind the other nodes.
+	localNode       string
+	maxLocalNodeLag uint32
+	// reportedInconsistencies are the problems of the nodes that the admins are alerted about.
+	// It is only used by the consistency check loop.
+	reportedInconsistencies map[string]bool
+
+	// blockFollowInterval is how often the new blocks are polled, the block follower is not started if it is zero.
+	blockFollowInterval time.Duration
+
+	notifiers     map[string]Notifier
+	notifiersLock sync.RWMutex
+	alerts        chan *queuedAlert
 
 	sync.RWMutex
 }
@@ -42,21 +89,24 @@ func NewBotEngine(cfg *config.Config) (IEngine, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	cm := client.NewClientMgr(ctx)
+	cm.EnableCache(cfg.ChainCacheBlockTime)
 
-	localClient, err := client.NewClient(cfg.LocalNode)
+	// The local node is the first one, it is preferred while it is as healthy as the others.
+	localClient, err := client.NewClient(cfg.LocalNode, cfg.NodeOptionsOf(cfg.LocalNode), &cfg.NodeConn)
 	if err != nil {
-		cancel()
 		log.Error("can't make a new local client", "err", err, "addr", cfg.LocalNode)
+	} else {
+		cm.AddClient(cfg.LocalNode, localClient)
 	}
 
-	cm.AddClient(localClient)
-
 	for _, nn := range cfg.NetworkNodes {
-		c, err := client.NewClient(nn)
+		c, err := client.NewClient(nn, cfg.NodeOptionsOf(nn), &cfg.NodeConn)
 		if err != nil {
 			log.Error("can't add new network node client", "err", err, "addr", nn)
+
+			continue
 		}
-		cm.AddClient(c)
+		cm.AddClient(nn, c)
 	}
 	cm.Start()
 
@@ -81,46 +131,106 @@ func NewBotEngine(cfg *config.Config) (IEngine, error) {
 	log.Info("wallet opened successfully", "address", wallet.Address())
 
 	// load store.
-	store, err := store.NewStore(cfg.StorePath, sSl)
+	store, err := store.NewStore(cfg.StorePath, cfg.DryRun, campaignIDs(cfg.Campaigns), sSl)
 	if err != nil {
 		log.Panic("could not load store", "err", err)
 	}
 	log.Info("store loaded successfully", "path", cfg.StorePath)
 
-	twitterClient, err := twitter_api.NewClient(cfg.TwitterAPICfg.BearerToken, cfg.TwitterAPICfg.TwitterID)
+	twitterAPIClient, err := twitter_api.NewClient(cfg.TwitterAPICfg.BearerToken, cfg.TwitterAPICfg.TwitterID,
+		cfg.TwitterAPICfg.MaxRateLimitWait)
 	if err != nil {
 		log.Panic("could not start twitter client", "err", err)
 	}
+	twitterClient := twitter_api.NewCachedClient(twitterAPIClient, cfg.TwitterAPICfg.Cache)
 	log.Info("twitterClient loaded successfully")
 
-	nowpayments, err := nowpayments.NewNowPayments(&cfg.NowPaymentsConfig)
+	nowPayments, err := nowpayments.NewNowPayments(&cfg.NowPaymentsConfig)
 	if err != nil {
-		log.Error("could not start twitter client", "err", err)
+		log.Panic("could not start nowpayments", "err", err)
 	}
 	log.Info("nowpayments loaded successfully")
 
-	return newBotEngine(eSl, cm, wallet, store, twitterClient, nowpayments, cfg.AuthIDs, ctx, cancel), nil
+	turboSwap, err := turboswap.NewTurboswap(&cfg.TurboswapConfig)
+	if err != nil {
+		log.Panic("could not start turboswap", "err", err)
+	}
+	log.Info("turboswap loaded successfully")
+
+	providers := map[string]payment.IPaymentProvider{
+		nowPayments.Name(): nowPayments,
+		turboSwap.Name():   turboSwap,
+	}
+
+	auditLog, err := audit.Open(cfg.AuditLogPath)
+	if err != nil {
+		log.Panic("could not open audit log", "err", err, "path", cfg.AuditLogPath)
+	}
+	log.Info("audit log opened successfully", "path", cfg.AuditLogPath)
+
+	payoutJournal, err := journal.Open(cfg.PayoutJournalPath)
+	if err != nil {
+		log.Panic("could not open payout journal", "err", err, "path", cfg.PayoutJournalPath)
+	}
+	log.Info("payout journal opened successfully", "path", cfg.PayoutJournalPath)
+
+	return newBotEngine(eSl, cm, wallet, store, twitterClient, providers, auditLog, payoutJournal,
+		cfg, ctx, cancel), nil
 }
 
 func newBotEngine(logger *log.SubLogger, cm *client.Mgr, w wallet.IWallet, s store.IStore,
-	twitterClient twitter_api.IClient, nowpayments nowpayments.INowpayment, authIDs []string,
-	ctx context.Context, cnl context.CancelFunc,
+	twitterClient twitter_api.IClient, providers map[string]payment.IPaymentProvider, auditLog *audit.Log,
+	payoutJournal *journal.Journal, cfg *config.Config, ctx context.Context, cnl context.CancelFunc,
 ) *BotEngine {
-	return &BotEngine{
-		ctx:           ctx,
-		cancel:        cnl,
-		logger:        logger,
-		wallet:        w,
-		clientMgr:     cm,
-		store:         s,
-		twitterClient: twitterClient,
-		nowpayments:   nowpayments,
-		AuthIDs:       authIDs,
-	}
+	be := &BotEngine{
+		ctx:             ctx,
+		cancel:          cnl,
+		logger:          logger,
+		wallet:          w,
+		clientMgr:       cm,
+		store:           s,
+		twitterClient:   twitterClient,
+		providers:       providers,
+		auditLog:        auditLog,
+		journal:         payoutJournal,
+		roles:           cfg.Roles,
+		commandTimeout:  cfg.CommandTimeout,
+		commandTimeouts: cfg.CommandTimeouts,
+		dryRun:          cfg.DryRun,
+
+		webhooks:          webhook.NewServer(&cfg.WebhookConfig, log.NewSubLogger("webhook")),
+		webhookListenAddr: cfg.WebhookConfig.ListenAddr,
+
+		txTrackInterval:  cfg.TxTrackInterval,
+		txConfirmTimeout: cfg.TxConfirmTimeout,
+		limits:           cfg.PayoutLimits,
+		campaigns:        cfg.Campaigns,
+
+		expiryCheckInterval: cfg.ExpiryCheckInterval,
+		paymentEvents:       make(chan *payment.Event, paymentEventsQueueSize),
+
+		paymentReconcileInterval: cfg.PaymentReconcileInterval,
+		latePaymentWindow:        cfg.LatePaymentWindow,
+		reportedMismatches:       make(map[string]bool),
+		payoutFailures:           make(map[string]*payoutFailure),
+
+		consistencyCheckInterval: cfg.ConsistencyCheckInterval,
+		localNode:                cfg.LocalNode,
+		maxLocalNodeLag:          cfg.MaxLocalNodeLag,
+		reportedInconsistencies:  make(map[string]bool),
+
+		blockFollowInterval: cfg.BlockFollowInterval,
+
+		notifiers: make(map[string]Notifier),
+		alerts:    make(chan *queuedAlert, alertsQueueSize),
+	}
+	be.commands = be.newCommands()
+
+	return be
 }
 
-func (be *BotEngine) NetworkHealth() (*NetHealthResponse, error) {
-	lastBlockTime, lastBlockHeight := be.clientMgr.GetLastBlockTime()
+func (be *BotEngine) NetworkHealth(ctx context.Context) (*NetHealthResponse, error) {
+	lastBlockTime, lastBlockHeight := be.clientMgr.GetLastBlockTime(ctx)
 	lastBlockTimeFormatted := time.Unix(int64(lastBlockTime), 0)
 	currentTime := time.Now()
 
@@ -137,21 +247,23 @@ func (be *BotEngine) NetworkHealth() (*NetHealthResponse, error) {
 		LastBlockTime:   lastBlockTimeFormatted,
 		LastBlockHeight: lastBlockHeight,
 		TimeDifference:  timeDiff,
+		Nodes:           be.clientMgr.Health(),
+		Cache:           be.clientMgr.CacheStats(),
 	}, nil
 }
 
-func (be *BotEngine) NetworkStatus() (*NetStatus, error) {
-	netInfo, err := be.clientMgr.GetNetworkInfo()
+func (be *BotEngine) NetworkStatus(ctx context.Context) (*NetStatus, error) {
+	netInfo, err := be.clientMgr.GetNetworkInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	chainInfo, err := be.clientMgr.GetBlockchainInfo()
+	chainInfo, err := be.clientMgr.GetBlockchainInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	cs, err := be.clientMgr.GetCirculatingSupply()
+	cs, err := be.clientMgr.GetCirculatingSupply(ctx)
 	if err != nil {
 		cs = 0
 	}
@@ -170,8 +282,8 @@ func (be *BotEngine) NetworkStatus() (*NetStatus, error) {
 	}, nil
 }
 
-func (be *BotEngine) NodeInfo(valAddress string) (*NodeInfo, error) {
-	peerInfo, err := be.clientMgr.GetPeerInfo(valAddress)
+func (be *BotEngine) NodeInfo(ctx context.Context, valAddress string) (*NodeInfo, error) {
+	peerInfo, err := be.clientMgr.GetPeerInfo(ctx, valAddress)
 	if err != nil {
 		return nil, err
 	}
@@ -184,7 +296,7 @@ func (be *BotEngine) NodeInfo(valAddress string) (*NodeInfo, error) {
 	ip := utils.ExtractIPFromMultiAddr(peerInfo.Address)
 	geoData := utils.GetGeoIP(ip)
 
-	val, err := be.clientMgr.GetValidatorInfo(valAddress)
+	val, err := be.clientMgr.GetValidatorInfo(ctx, valAddress)
 	if err != nil {
 		return nil, err
 	}
@@ -207,7 +319,7 @@ func (be *BotEngine) NodeInfo(valAddress string) (*NodeInfo, error) {
 	}, nil
 }
 
-func (be *BotEngine) ClaimerInfo(testNetValAddr string) (*store.Claimer, error) {
+func (be *BotEngine) ClaimerInfo(_ context.Context, testNetValAddr string) (*store.Claimer, error) {
 	be.RLock()
 	defer be.RUnlock()
 
@@ -219,18 +331,18 @@ func (be *BotEngine) ClaimerInfo(testNetValAddr string) (*store.Claimer, error)
 	return claimer, nil
 }
 
-func (be *BotEngine) Claim(discordID string, testnetAddr string, mainnetAddr string) (string, error) {
+func (be *BotEngine) Claim(ctx context.Context, discordID string, testnetAddr string, mainnetAddr string) (string, error) {
 	be.Lock()
 	defer be.Unlock()
 
 	be.logger.Info("new claim request", "mainnetAddr", mainnetAddr, "testnetAddr", testnetAddr, "discordID", discordID)
 
-	valInfo, _ := be.clientMgr.GetValidatorInfo(mainnetAddr)
+	valInfo, _ := be.clientMgr.GetValidatorInfo(ctx, mainnetAddr)
 	if valInfo != nil {
 		return "", errors.New("this address is already a staked validator")
 	}
 
-	if utils.ChangeToCoin(be.wallet.Balance()) <= 500 {
+	if utils.ChangeToCoin(be.wallet.Balance(ctx)) <= float64(be.limits.MinBalance) {
 		be.logger.Warn("bot wallet hasn't enough balance")
 		return "", errors.New("insufficient wallet balance")
 	}
@@ -249,47 +361,32 @@ func (be *BotEngine) Claim(discordID string, testnetAddr string, mainnetAddr str
 		return "", errors.New("this claimer have already claimed rewards")
 	}
 
-	pubKey, err := be.clientMgr.FindPublicKey(mainnetAddr, true)
+	pubKey, err := be.clientMgr.FindPublicKey(ctx, mainnetAddr, true)
 	if err != nil {
 		return "", err
 	}
 
 	memo := "TestNet reward claim from RoboPac"
-	txID, err := be.wallet.BondTransaction(pubKey, mainnetAddr, memo, claimer.TotalReward)
+	txID, err := be.payout(ctx, callerOrSystem(ctx, jobClaim), store.TxKindClaim, testnetAddr, discordID,
+		pubKey, mainnetAddr, memo, claimer.TotalReward)
 	if err != nil {
 		return "", err
 	}
 
-	if txID == "" {
-		return "", errors.New("can't send bond transaction")
-	}
-
 	be.logger.Info("new bond transaction sent", "txID", txID)
 
-	err = be.store.AddClaimTransaction(testnetAddr, txID)
-	if err != nil {
-		be.logger.Panic("unable to add the claim transaction",
-			"error", err,
-			"discordID", discordID,
-			"testnetAddr", testnetAddr,
-			"txID", txID,
-		)
-
-		return "", err
-	}
-
 	return txID, nil
 }
 
-func (be *BotEngine) BotWallet() (string, int64) {
-	return be.wallet.Address(), be.wallet.Balance()
+func (be *BotEngine) BotWallet(ctx context.Context) (string, int64) {
+	return be.wallet.Address(), be.wallet.Balance(ctx)
 }
 
-func (be *BotEngine) ClaimStatus() *store.ClaimStatus {
+func (be *BotEngine) ClaimStatus(_ context.Context) *store.ClaimStatus {
 	return be.store.ClaimStatus()
 }
 
-func (be *BotEngine) RewardCalculate(stake int64, t string) (int64, string, int64, error) {
+func (be *BotEngine) RewardCalculate(ctx context.Context, stake int64, t string) (int64, string, int64, error) {
 	if stake < 1 || stake > 1_000 {
 		return 0, "", 0, errors.New("minimum of stake is 1 PAC and maximum is 1,000 PAC")
 	}
@@ -308,181 +405,92 @@ func (be *BotEngine) RewardCalculate(stake int64, t string) (int64, string, int6
 		time = "day"
 	}
 
-	bi, err := be.clientMgr.GetBlockchainInfo()
+	bi, err := be.clientMgr.GetBlockchainInfo(ctx)
 	if err != nil {
-		return 0, "", 0, nil
+		return 0, "", 0, err
 	}
 
 	reward := (stake * int64(blocks)) / int64(putils.ChangeToCoin(bi.TotalPower))
 	return reward, time, int64(utils.ChangeToCoin(bi.TotalPower)), nil
 }
 
-func (be *BotEngine) BoosterPayment(discordID, twitterName, valAddr string) (*store.TwitterParty, error) {
-	be.Lock()
-	defer be.Unlock()
-
-	boosterStatus := be.BoosterStatus()
-	if boosterStatus.AllPkgs > 500 {
-		return nil, errors.New("program is finished")
-	}
-
-	existingParty := be.store.FindTwitterParty(twitterName)
-	if existingParty != nil {
-		if existingParty.TransactionID != "" {
-			return nil, fmt.Errorf("transaction is processed before: https://pacscan.org/transactions/%v", existingParty.TransactionID)
-		} else {
-			return existingParty, nil
-		}
-	}
+func (be *BotEngine) Stop() {
+	be.logger.Info("shutting bot engine down...")
 
-	valInfo, _ := be.clientMgr.GetValidatorInfo(valAddr)
-	if valInfo != nil {
-		return nil, errors.New("this address is already a staked validator")
-	}
+	be.cancel()
+	be.clientMgr.Stop()
 
-	pubKey, err := be.clientMgr.FindPublicKey(valAddr, false)
-	if err != nil {
-		return nil, err
+	if err := be.webhooks.Stop(); err != nil {
+		be.logger.Error("unable to shut the webhook server down", "error", err)
 	}
+}
 
-	userInfo, err := be.twitterClient.UserInfo(be.ctx, twitterName)
-	if err != nil {
-		return nil, err
-	}
-	if !userInfo.IsVerified {
-		if !be.store.IsWhitelisted(userInfo.TwitterID) {
-			threeYearsAgo := time.Now().AddDate(-3, 0, 0)
-			if userInfo.CreatedAt.After(threeYearsAgo) {
-				return nil, errors.New("the Twitter account is less than 3 years old." +
-					" To whitelist your Twitter click here: https://forms.gle/fMaN1xtE322RBEYX8")
-			}
-
-			if userInfo.Followers < 200 {
-				return nil, errors.New("the Twitter account has less than 200 followers." +
-					" To whitelist your Twitter click here: https://forms.gle/fMaN1xtE322RBEYX8")
-			}
+// startWebhooks registers the webhooks of the payment providers and starts the webhook server.
+// The server is not started if no listen address is set, then the invoices are only reconciled periodically.
+func (be *BotEngine) startWebhooks() {
+	for name, provider := range be.providers {
+		handler := provider.WebhookHandler()
+		if handler == nil {
+			continue
 		}
-	}
-
-	tweetInfo, err := be.twitterClient.RetweetSearch(be.ctx, discordID, twitterName)
-	if err != nil {
-		return nil, err
-	}
 
-	discountCode, err := gonanoid.Generate("0123456789", 8)
-	if err != nil {
-		return nil, err
+		if err := be.webhooks.Register(name, handler); err != nil {
+			be.logger.Error("unable to register the webhook", "error", err, "provider", name)
+		}
 	}
 
-	totalPrice := boosterPrice(boosterStatus.AllPkgs)
-	amountInPAC := int64(150)
-	if userInfo.Followers > 1000 {
-		amountInPAC = 200
-	}
+	if be.webhookListenAddr == "" {
+		be.logger.Warn("no webhook listen address is set, the webhook server is not started")
 
-	party := &store.TwitterParty{
-		TwitterID:    userInfo.TwitterID,
-		TwitterName:  userInfo.TwitterName,
-		RetweetID:    tweetInfo.ID,
-		ValAddr:      valAddr,
-		ValPubKey:    pubKey,
-		TotalPrice:   totalPrice,
-		AmountInPAC:  amountInPAC,
-		DiscountCode: discountCode,
-		DiscordID:    discordID,
-		CreatedAt:    time.Now().Unix(),
+		return
 	}
 
-	err = be.nowpayments.CreatePayment(party)
-	if err != nil {
-		return nil, err
+	if err := be.webhooks.Start(); err != nil {
+		be.alert(StatusDanger, "Webhook server failed🚨", fmt.Sprintf(
+			"Unable to start the webhook server on `%s`, the payment callbacks are not received: %s.",
+			be.webhookListenAddr, err))
 	}
-
-	err = be.store.SaveTwitterParty(party)
-	if err != nil {
-		return nil, err
-	}
-
-	return party, nil
 }
 
-func (be *BotEngine) BoosterClaim(twitterName string) (*store.TwitterParty, error) {
-	be.Lock() // KAY, move this to store
-	defer be.Unlock()
+func (be *BotEngine) Start() {
+	be.logger.Info("starting the bot engine...")
 
-	party := be.store.FindTwitterParty(twitterName)
-	if party == nil {
-		return nil, fmt.Errorf("no discount code generated for this Twitter account: `%v`", twitterName)
+	if be.dryRun {
+		be.logger.Warn("the bot engine is running in dry-run mode, transactions are not broadcast")
 	}
-	err := be.nowpayments.UpdatePayment(party)
-	if err != nil {
-		return nil, err
-	}
-
-	if party.NowPaymentsFinished {
-		if party.TransactionID == "" {
-			logger.Info("sending bond transaction", "receiver", party.ValAddr, "amount", party.AmountInPAC)
-			memo := "Booster Program"
-			txID, err := be.wallet.BondTransaction(party.ValPubKey, party.ValAddr, memo, utils.CoinToChange(float64(party.AmountInPAC)))
-			if err != nil {
-				return nil, err
-			}
 
-			if txID == "" {
-				return nil, errors.New("can't send bond transaction")
-			}
+	go be.sendAlerts()
 
-			party.TransactionID = txID
+	ctx, cancel := context.WithTimeout(be.ctx, time.Minute)
+	be.reconcilePayouts(ctx)
+	cancel()
 
-			err = be.store.SaveTwitterParty(party)
-			if err != nil {
-				return nil, err
-			}
-		}
+	if be.txTrackInterval > 0 {
+		go be.trackTransactions()
 	}
 
-	return party, nil
-}
-
-func (be *BotEngine) BoosterWhitelist(twitterName, authorizedDiscordID string) error {
-	if !slices.Contains(be.AuthIDs, authorizedDiscordID) {
-		return fmt.Errorf("unauthorized person")
+	if be.blockFollowInterval > 0 {
+		sub := be.clientMgr.Events().Subscribe(blockEventsQueueSize, client.EventTransfer, client.EventBond)
+		go be.confirmTransactions(sub)
+		be.clientMgr.FollowBlocks(be.blockFollowInterval)
 	}
 
-	foundParty := be.store.FindTwitterParty(twitterName)
-	if foundParty != nil {
-		return fmt.Errorf("the Twitter `%v` already registered for the campaign. Discount code is %v",
-			foundParty.TwitterName, foundParty.DiscountCode)
+	if be.expiryCheckInterval > 0 {
+		go be.expireParties()
 	}
 
-	userInfo, err := be.twitterClient.UserInfo(be.ctx, twitterName)
-	if err != nil {
-		return err
+	for _, provider := range be.providers {
+		provider.SetEventHandler(be.queuePaymentEvent)
 	}
+	go be.processPaymentEvents()
 
-	return be.store.WhitelistTwitterAccount(userInfo.TwitterID, userInfo.TwitterName, authorizedDiscordID)
-}
+	be.startWebhooks()
 
-func (be *BotEngine) BoosterStatus() *store.BoosterStatus {
-	return be.store.BoosterStatus()
-}
-
-func boosterPrice(allPackages int) int {
-	if allPackages < 100 {
-		return 30
-	} else if allPackages < 200 {
-		return 40
+	if be.paymentReconcileInterval > 0 {
+		go be.reconcileInvoicesLoop()
 	}
-	return 50
-}
 
-func (be *BotEngine) Stop() {
-	be.logger.Info("shutting bot engine down...")
-
-	be.cancel()
-	be.clientMgr.Stop()
-}
-
-func (be *BotEngine) Start() {
-	be.logger.Info("starting the bot engine...")
+	if be.consistencyCheckInterval > 0 {
+		go be.consistencyCheckLoop()
+	}
 }
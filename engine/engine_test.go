@@ -2,24 +2,36 @@ package engine
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"path"
 	"testing"
 	"time"
 
+	"github.com/kehiy/RoboPac/audit"
 	"github.com/kehiy/RoboPac/client"
+	"github.com/kehiy/RoboPac/config"
+	"github.com/kehiy/RoboPac/eligibility"
+	"github.com/kehiy/RoboPac/journal"
 	"github.com/kehiy/RoboPac/log"
-	"github.com/kehiy/RoboPac/nowpayments"
+	"github.com/kehiy/RoboPac/payment"
 	rpstore "github.com/kehiy/RoboPac/store"
 	"github.com/kehiy/RoboPac/twitter_api"
 	"github.com/kehiy/RoboPac/utils"
 	"github.com/kehiy/RoboPac/wallet"
+	"github.com/kehiy/RoboPac/webhook"
 	"github.com/libp2p/go-libp2p/core/peer"
 	pactus "github.com/pactus-project/pactus/www/grpc/gen/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
+var anyone = NewDiscordCaller("123456789", nil)
+
 var peerID, _ = peer.Decode("12D3KooWNwudyHVEwtyRTkTx9JoWgHo65hkPUxU12pKviAreVJYg")
 
 var networkInfo = &pactus.GetNetworkInfoResponse{
@@ -64,7 +76,7 @@ var networkInfo = &pactus.GetNetworkInfoResponse{
 }
 
 func setup(t *testing.T) (*BotEngine, *client.MockIClient, *rpstore.MockIStore,
-	*wallet.MockIWallet, *twitter_api.MockIClient, *nowpayments.MockINowpayment, context.Context,
+	*wallet.MockIWallet, *twitter_api.MockIClient, *payment.MockIPaymentProvider, context.Context,
 ) {
 	t.Helper()
 	ctrl := gomock.NewController(t)
@@ -75,7 +87,7 @@ func setup(t *testing.T) (*BotEngine, *client.MockIClient, *rpstore.MockIStore,
 
 	ctx, cancel := context.WithCancel(context.Background())
 	cm := client.NewClientMgr(ctx)
-	cm.AddClient(mockClient)
+	cm.AddClient("local", mockClient)
 
 	mockClient.EXPECT().GetNetworkInfo(ctx).Return(
 		networkInfo, nil,
@@ -86,9 +98,29 @@ func setup(t *testing.T) (*BotEngine, *client.MockIClient, *rpstore.MockIStore,
 	mockWallet := wallet.NewMockIWallet(ctrl)
 	mockStore := rpstore.NewMockIStore(ctrl)
 	mockTwitter := twitter_api.NewMockIClient(ctrl)
-	mockNowPayments := nowpayments.NewMockINowpayment(ctrl)
+	mockNowPayments := payment.NewMockIPaymentProvider(ctrl)
+
+	cfg := &config.Config{
+		Roles: map[string]config.RoleMembers{
+			config.RoleAdmin:            {DiscordIDs: []string{"admin-id"}},
+			config.RoleCampaignOperator: {DiscordRoles: []string{"operator-role"}},
+			config.RoleViewer:           {DiscordIDs: []string{"viewer-id"}},
+		},
+		CommandTimeout:  time.Second,
+		CommandTimeouts: make(map[string]time.Duration),
+		PayoutLimits:    config.PayoutLimits{MinBalance: 500},
+		Campaigns:       config.DefaultCampaigns(),
+	}
+
+	auditLog, err := audit.Open(path.Join(t.TempDir(), "audit.jsonl"))
+	assert.NoError(t, err)
+
+	payoutJournal, err := journal.Open(path.Join(t.TempDir(), "payout_journal.jsonl"))
+	assert.NoError(t, err)
 
-	eng := newBotEngine(sl, cm, mockWallet, mockStore, mockTwitter, mockNowPayments, []string{""}, ctx, cancel)
+	providers := map[string]payment.IPaymentProvider{config.ProviderNowPayments: mockNowPayments}
+	eng := newBotEngine(sl, cm, mockWallet, mockStore, mockTwitter, providers, auditLog, payoutJournal,
+		cfg, ctx, cancel)
 	return eng, mockClient, mockStore, mockWallet, mockTwitter, mockNowPayments, ctx
 }
 
@@ -134,7 +166,7 @@ func TestNetworkStatus(t *testing.T) {
 		int64(100), nil,
 	)
 
-	status, err := eng.NetworkStatus()
+	status, err := eng.NetworkStatus(ctx)
 	assert.NoError(t, err)
 
 	assert.Equal(t, uint32(5), status.ConnectedPeersCount)
@@ -151,7 +183,7 @@ func TestNetworkHealth(t *testing.T) {
 
 		time.Sleep(2 * time.Second)
 
-		healthy, err := eng.NetworkHealth()
+		healthy, err := eng.NetworkHealth(ctx)
 		assert.NoError(t, err)
 
 		assert.Equal(t, true, healthy.HealthStatus)
@@ -165,11 +197,110 @@ func TestNetworkHealth(t *testing.T) {
 		currentTime := time.Now().Unix() - 16 // time difference is more than 15 seconds.
 		client.EXPECT().LastBlockTime(ctx).Return(uint32(currentTime), uint32(100), nil)
 
-		healthy, err := eng.NetworkHealth()
+		healthy, err := eng.NetworkHealth(ctx)
 		assert.NoError(t, err)
 
 		assert.Equal(t, false, healthy.HealthStatus)
 	})
+
+	t.Run("unhealthy result status", func(t *testing.T) {
+		currentTime := time.Now().Unix() - 16
+		client.EXPECT().LastBlockTime(gomock.Any()).Return(uint32(currentTime), uint32(100), nil)
+
+		res, err := eng.Run(ctx, anyone, "network-health")
+		assert.NoError(t, err)
+
+		assert.Equal(t, StatusDanger, res.Status)
+		assert.Equal(t, "Network is UnHealthy❌", res.Message)
+		assert.Equal(t, "local", res.Fields[len(res.Fields)-1].Name)
+		assert.Contains(t, res.Fields[len(res.Fields)-1].Value, "✅ height 0, latency")
+	})
+
+	t.Run("cache hit rate", func(t *testing.T) {
+		eng.clientMgr.EnableCache(time.Hour)
+		client.EXPECT().LastBlockTime(gomock.Any()).Return(uint32(time.Now().Unix()), uint32(100), nil)
+
+		_, err := eng.Run(ctx, anyone, "network-health")
+		assert.NoError(t, err)
+		res, err := eng.Run(ctx, anyone, "network-health")
+		assert.NoError(t, err)
+
+		assert.Equal(t, "Cache Hit Rate", res.Fields[len(res.Fields)-1].Name)
+		assert.Equal(t, "50% of 2 calls (last_block_time 50%)", res.Fields[len(res.Fields)-1].Value)
+	})
+}
+
+func TestNetworkConsistency(t *testing.T) {
+	eng, _, _, _, _, _, ctx := setup(t)
+	ctrl := gomock.NewController(t)
+
+	localClient := client.NewMockIClient(ctrl)
+	remoteClient := client.NewMockIClient(ctrl)
+	eng.clientMgr = client.NewClientMgr(ctx)
+	eng.clientMgr.AddClient("local", localClient)
+	eng.clientMgr.AddClient("remote", remoteClient)
+	eng.localNode = "local"
+	eng.maxLocalNodeLag = 10
+
+	expectHeights := func(localHeight, remoteHeight uint32) {
+		commonHeight := min(localHeight, remoteHeight)
+		localClient.EXPECT().GetBlockchainInfo(gomock.Any()).Return(
+			&pactus.GetBlockchainInfoResponse{LastBlockHeight: localHeight}, nil)
+		remoteClient.EXPECT().GetBlockchainInfo(gomock.Any()).Return(
+			&pactus.GetBlockchainInfoResponse{LastBlockHeight: remoteHeight}, nil)
+		localClient.EXPECT().GetBlockHash(gomock.Any(), commonHeight).Return("hash", nil)
+		remoteClient.EXPECT().GetBlockHash(gomock.Any(), commonHeight).Return("hash", nil)
+	}
+
+	t.Run("nodes agree", func(t *testing.T) {
+		expectHeights(100, 100)
+
+		res, err := eng.Run(ctx, anyone, "network-consistency")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusSuccess, res.Status)
+		assert.Equal(t, "local", res.Fields[2].Name)
+		assert.Equal(t, "✅ height 100, last block ", res.Fields[2].Value)
+	})
+
+	t.Run("local node lagging is alerted once", func(t *testing.T) {
+		notifier := newTestNotifier(eng)
+
+		expectHeights(80, 100)
+		res, err := eng.Run(ctx, anyone, "network-consistency")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusWarning, res.Status)
+		assert.Equal(t, "⚠️ 20 blocks behind, height 80", res.Fields[2].Value)
+
+		expectHeights(80, 100)
+		eng.alertInconsistencies(eng.clientMgr.CheckConsistency(ctx, eng.maxLocalNodeLag))
+		expectHeights(85, 100)
+		eng.alertInconsistencies(eng.clientMgr.CheckConsistency(ctx, eng.maxLocalNodeLag))
+		require.Len(t, notifier.sent(), 1)
+		assert.Equal(t, StatusDanger, notifier.sent()[0].Status)
+		assert.Equal(t, "The local node `local` is at height 80, 20 blocks behind "+
+			"the majority of the nodes at height 100.", notifier.sent()[0].Message)
+
+		expectHeights(95, 100)
+		eng.alertInconsistencies(eng.clientMgr.CheckConsistency(ctx, eng.maxLocalNodeLag))
+		require.Len(t, notifier.sent(), 2)
+		assert.Equal(t, StatusInfo, notifier.sent()[1].Status)
+	})
+
+	t.Run("possible fork", func(t *testing.T) {
+		notifier := newTestNotifier(eng)
+
+		localClient.EXPECT().GetBlockchainInfo(gomock.Any()).Return(
+			&pactus.GetBlockchainInfoResponse{LastBlockHeight: 100}, nil)
+		remoteClient.EXPECT().GetBlockchainInfo(gomock.Any()).Return(
+			&pactus.GetBlockchainInfoResponse{LastBlockHeight: 100}, nil)
+		localClient.EXPECT().GetBlockHash(gomock.Any(), uint32(100)).Return("hash", nil)
+		remoteClient.EXPECT().GetBlockHash(gomock.Any(), uint32(100)).Return("fork", nil)
+
+		eng.alertInconsistencies(eng.clientMgr.CheckConsistency(ctx, eng.maxLocalNodeLag))
+		require.Len(t, notifier.sent(), 1)
+		assert.Equal(t, "The node `remote` has a different block at height 100 "+
+			"than the majority of the nodes.", notifier.sent()[0].Message)
+	})
 }
 
 func TestNodeInfo(t *testing.T) {
@@ -212,7 +343,7 @@ func TestNodeInfo(t *testing.T) {
 			}, nil,
 		).AnyTimes()
 
-		info, err := eng.NodeInfo(valAddress)
+		info, err := eng.NodeInfo(ctx, valAddress)
 		assert.NoError(t, err)
 
 		assert.Equal(t, int64(1_000), info.StakeAmount)
@@ -232,7 +363,7 @@ func TestClaim(t *testing.T) {
 		memo := "TestNet reward claim from RoboPac"
 		txID := "tx-id"
 
-		wallet.EXPECT().Balance().Return(
+		wallet.EXPECT().Balance(gomock.Any()).Return(
 			utils.CoinToChange(501),
 		).MaxTimes(2)
 
@@ -248,7 +379,11 @@ func TestClaim(t *testing.T) {
 			},
 		)
 
-		wallet.EXPECT().BondTransaction(pubKey, mainnetAddr, memo, amount).Return(
+		wallet.EXPECT().SignBondTransaction(gomock.Any(), pubKey, mainnetAddr, memo, amount).Return(
+			txID, []byte("raw-tx"), nil,
+		).MaxTimes(1)
+
+		wallet.EXPECT().BroadcastTransaction(gomock.Any(), []byte("raw-tx")).Return(
 			txID, nil,
 		).MaxTimes(1)
 
@@ -256,7 +391,18 @@ func TestClaim(t *testing.T) {
 			nil,
 		)
 
-		expectedTx, err := eng.Claim(discordID, testnetAddr, mainnetAddr)
+		store.EXPECT().SaveTransaction(gomock.Any()).DoAndReturn(
+			func(tx *rpstore.Transaction) error {
+				assert.Equal(t, txID, tx.TxID)
+				assert.Equal(t, rpstore.TxKindClaim, tx.Kind)
+				assert.Equal(t, testnetAddr, tx.Ref)
+				assert.Equal(t, rpstore.TxStatusPending, tx.Status)
+
+				return nil
+			},
+		)
+
+		expectedTx, err := eng.Claim(ctx, discordID, testnetAddr, mainnetAddr)
 		assert.NoError(t, err)
 		assert.NotNil(t, expectedTx, txID)
 
@@ -273,7 +419,7 @@ func TestClaim(t *testing.T) {
 			},
 		).Times(1)
 
-		expectedTx, err = eng.Claim(discordID, testnetAddr, mainnetAddr)
+		expectedTx, err = eng.Claim(ctx, discordID, testnetAddr, mainnetAddr)
 		assert.Error(t, err)
 		assert.Empty(t, expectedTx)
 	})
@@ -293,7 +439,7 @@ func TestClaim(t *testing.T) {
 			}, nil,
 		).Times(1)
 
-		expectedTx, err := eng.Claim(discordID, testnetAddr, mainnetAddr)
+		expectedTx, err := eng.Claim(ctx, discordID, testnetAddr, mainnetAddr)
 		assert.EqualError(t, err, "this address is already a staked validator")
 		assert.Empty(t, expectedTx)
 	})
@@ -305,14 +451,14 @@ func TestClaim(t *testing.T) {
 		testnetAddr := "testnet-addr-fail-balance"
 		discordID := "123456789-fail-balance"
 
-		wallet.EXPECT().Balance().Return(
+		wallet.EXPECT().Balance(gomock.Any()).Return(
 			utils.CoinToChange(499),
 		)
 
 		client.EXPECT().GetValidatorInfo(ctx, mainnetAddr).Return(
 			nil, fmt.Errorf("not found"),
 		)
-		expectedTx, err := eng.Claim(discordID, testnetAddr, mainnetAddr)
+		expectedTx, err := eng.Claim(ctx, discordID, testnetAddr, mainnetAddr)
 		assert.EqualError(t, err, "insufficient wallet balance")
 		assert.Empty(t, expectedTx)
 	})
@@ -324,7 +470,7 @@ func TestClaim(t *testing.T) {
 		testnetAddr := "testnet-addr-fail-notfound"
 		discordID := "123456789-fail-notfound"
 
-		wallet.EXPECT().Balance().Return(
+		wallet.EXPECT().Balance(gomock.Any()).Return(
 			utils.CoinToChange(501),
 		)
 
@@ -336,7 +482,7 @@ func TestClaim(t *testing.T) {
 			nil,
 		)
 
-		expectedTx, err := eng.Claim(discordID, testnetAddr, mainnetAddr)
+		expectedTx, err := eng.Claim(ctx, discordID, testnetAddr, mainnetAddr)
 		assert.EqualError(t, err, "claimer not found")
 		assert.Empty(t, expectedTx)
 	})
@@ -348,7 +494,7 @@ func TestClaim(t *testing.T) {
 		testnetAddr := "testnet-addr-fail-different-id"
 		discordID := "123456789-fail-different-id"
 
-		wallet.EXPECT().Balance().Return(
+		wallet.EXPECT().Balance(gomock.Any()).Return(
 			utils.CoinToChange(501),
 		)
 
@@ -362,7 +508,7 @@ func TestClaim(t *testing.T) {
 			},
 		)
 
-		expectedTx, err := eng.Claim(discordID, testnetAddr, mainnetAddr)
+		expectedTx, err := eng.Claim(ctx, discordID, testnetAddr, mainnetAddr)
 		assert.EqualError(t, err, "invalid claimer")
 		assert.Empty(t, expectedTx)
 	})
@@ -374,7 +520,7 @@ func TestClaim(t *testing.T) {
 		testnetAddr := "testnet-addr-fail-not-first-validator"
 		discordID := "123456789-fail-not-first-validator"
 
-		wallet.EXPECT().Balance().Return(
+		wallet.EXPECT().Balance(gomock.Any()).Return(
 			utils.CoinToChange(501),
 		)
 
@@ -389,7 +535,7 @@ func TestClaim(t *testing.T) {
 			},
 		)
 
-		expectedTx, err := eng.Claim(discordID, testnetAddr, mainnetAddr)
+		expectedTx, err := eng.Claim(ctx, discordID, testnetAddr, mainnetAddr)
 		assert.EqualError(t, err, "please enter the first validator address")
 		assert.Empty(t, expectedTx)
 	})
@@ -401,7 +547,7 @@ func TestClaim(t *testing.T) {
 		testnetAddr := "testnet-addr-fail-validator-not-found"
 		discordID := "123456789-fail-validator-not-found"
 
-		wallet.EXPECT().Balance().Return(
+		wallet.EXPECT().Balance(gomock.Any()).Return(
 			utils.CoinToChange(501),
 		)
 
@@ -409,6 +555,9 @@ func TestClaim(t *testing.T) {
 			nil, fmt.Errorf("not found"),
 		)
 
+		// The validator is not in the validator map, so it is looked up in the network info.
+		client.EXPECT().GetNetworkInfo(ctx).Return(&pactus.GetNetworkInfoResponse{}, nil)
+
 		store.EXPECT().ClaimerInfo(testnetAddr).Return(
 			&rpstore.Claimer{
 				DiscordID:   discordID,
@@ -416,7 +565,7 @@ func TestClaim(t *testing.T) {
 			},
 		)
 
-		expectedTx, err := eng.Claim(discordID, testnetAddr, mainnetAddr)
+		expectedTx, err := eng.Claim(ctx, discordID, testnetAddr, mainnetAddr)
 		assert.EqualError(t, err, "peer does not exist with this address: mainnet-addr-fail-validator-not-found")
 		assert.Empty(t, expectedTx)
 	})
@@ -431,7 +580,7 @@ func TestClaim(t *testing.T) {
 		amount := int64(30)
 		memo := "TestNet reward claim from RoboPac"
 
-		wallet.EXPECT().Balance().Return(
+		wallet.EXPECT().Balance(gomock.Any()).Return(
 			utils.CoinToChange(501),
 		)
 
@@ -447,16 +596,21 @@ func TestClaim(t *testing.T) {
 			},
 		)
 
-		wallet.EXPECT().BondTransaction(pubKey, mainnetAddr, memo, amount).Return(
+		wallet.EXPECT().SignBondTransaction(gomock.Any(), pubKey, mainnetAddr, memo, amount).Return(
+			"tx-id", []byte("raw-tx"), nil,
+		)
+
+		wallet.EXPECT().BroadcastTransaction(gomock.Any(), []byte("raw-tx")).Return(
 			"", nil,
 		)
 
-		expectedTx, err := eng.Claim(discordID, testnetAddr, mainnetAddr)
+		expectedTx, err := eng.Claim(ctx, discordID, testnetAddr, mainnetAddr)
 		assert.EqualError(t, err, "can't send bond transaction")
 		assert.Empty(t, expectedTx)
+		assert.Equal(t, journal.StateSigned, eng.journal.Find("claim:"+testnetAddr).State)
 	})
 
-	t.Run("should panic, add claimer failed", func(t *testing.T) {
+	t.Run("should fail, add claimer failed", func(t *testing.T) {
 		eng, client, store, wallet, _, _, ctx := setup(t)
 
 		mainnetAddr := "mainnet-addr-panic-add-claimer-failed"
@@ -467,13 +621,13 @@ func TestClaim(t *testing.T) {
 		memo := "TestNet reward claim from RoboPac"
 		txID := "tx-id-panic-add-claimer-failed"
 
-		wallet.EXPECT().Balance().Return(
+		wallet.EXPECT().Balance(gomock.Any()).Return(
 			utils.CoinToChange(501),
-		)
+		).Times(2)
 
 		client.EXPECT().GetValidatorInfo(ctx, mainnetAddr).Return(
 			nil, fmt.Errorf("not found"),
-		)
+		).Times(2)
 
 		store.EXPECT().ClaimerInfo(testnetAddr).Return(
 			&rpstore.Claimer{
@@ -481,19 +635,29 @@ func TestClaim(t *testing.T) {
 				TotalReward: amount,
 				ClaimedTxID: "",
 			},
+		).Times(2)
+
+		wallet.EXPECT().SignBondTransaction(gomock.Any(), pubKey, mainnetAddr, memo, amount).Return(
+			txID, []byte("raw-tx"), nil,
 		)
 
-		wallet.EXPECT().BondTransaction(pubKey, mainnetAddr, memo, amount).Return(
+		wallet.EXPECT().BroadcastTransaction(gomock.Any(), []byte("raw-tx")).Return(
 			txID, nil,
 		)
 
 		store.EXPECT().AddClaimTransaction(testnetAddr, txID).Return(
-			errors.New(""),
+			errors.New("disk is full"),
 		)
 
-		assert.Panics(t, func() {
-			_, _ = eng.Claim(discordID, testnetAddr, mainnetAddr)
-		})
+		expectedTx, err := eng.Claim(ctx, discordID, testnetAddr, mainnetAddr)
+		assert.EqualError(t, err, "disk is full")
+		assert.Empty(t, expectedTx)
+		assert.Equal(t, journal.StateBroadcast, eng.journal.Find("claim:"+testnetAddr).State)
+
+		// the reward is not paid again before the payout is reconciled.
+		expectedTx, err = eng.Claim(ctx, discordID, testnetAddr, mainnetAddr)
+		assert.ErrorContains(t, err, "the previous payout of `testnet-addr-panic-add-claimer-failed` is not finished yet")
+		assert.Empty(t, expectedTx)
 	})
 }
 
@@ -505,13 +669,13 @@ func TestBoosterProgram(t *testing.T) {
 		discordID := "123456789"
 		valAddr := "staked-validator"
 
-		store.EXPECT().BoosterStatus().Return(
-			&rpstore.BoosterStatus{
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(
+			&rpstore.CampaignStatus{
 				AllPkgs: 100,
 			},
 		)
 
-		store.EXPECT().FindTwitterParty(twitterName).Return(
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, twitterName).Return(
 			nil,
 		)
 
@@ -519,7 +683,10 @@ func TestBoosterProgram(t *testing.T) {
 			&pactus.GetValidatorResponse{}, nil,
 		)
 
-		_, err := eng.BoosterPayment(discordID, twitterName, valAddr)
+		// The validator is not in the validator map, so it is looked up in the network info.
+		client.EXPECT().GetNetworkInfo(ctx).Return(&pactus.GetNetworkInfoResponse{}, nil)
+
+		_, err := eng.CampaignPayment(ctx, config.BoosterCampaignID, discordID, twitterName, valAddr)
 		assert.Error(t, err)
 	})
 
@@ -530,13 +697,13 @@ func TestBoosterProgram(t *testing.T) {
 		discordID := "123456789"
 		valAddr := "non-existing-validator"
 
-		store.EXPECT().BoosterStatus().Return(
-			&rpstore.BoosterStatus{
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(
+			&rpstore.CampaignStatus{
 				AllPkgs: 100,
 			},
 		)
 
-		store.EXPECT().FindTwitterParty(twitterName).Return(
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, twitterName).Return(
 			nil,
 		)
 
@@ -544,7 +711,10 @@ func TestBoosterProgram(t *testing.T) {
 			nil, fmt.Errorf("not found"),
 		)
 
-		_, err := eng.BoosterPayment(discordID, twitterName, valAddr)
+		// The validator is not in the validator map, so it is looked up in the network info.
+		client.EXPECT().GetNetworkInfo(ctx).Return(&pactus.GetNetworkInfoResponse{}, nil)
+
+		_, err := eng.CampaignPayment(ctx, config.BoosterCampaignID, discordID, twitterName, valAddr)
 		assert.Error(t, err)
 	})
 
@@ -555,13 +725,13 @@ func TestBoosterProgram(t *testing.T) {
 		discordID := "123456789"
 		valAddr := "addr"
 
-		store.EXPECT().BoosterStatus().Return(
-			&rpstore.BoosterStatus{
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(
+			&rpstore.CampaignStatus{
 				AllPkgs: 100,
 			},
 		)
 
-		store.EXPECT().FindTwitterParty(twitterName).Return(
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, twitterName).Return(
 			nil,
 		)
 
@@ -570,11 +740,11 @@ func TestBoosterProgram(t *testing.T) {
 		)
 
 		expectedErr := errors.New("not exists")
-		twitter.EXPECT().UserInfo(eng.ctx, twitterName).Return(
+		twitter.EXPECT().UserInfo(ctx, twitterName).Return(
 			nil, expectedErr,
 		)
 
-		_, err := eng.BoosterPayment(discordID, twitterName, valAddr)
+		_, err := eng.CampaignPayment(ctx, config.BoosterCampaignID, discordID, twitterName, valAddr)
 		assert.ErrorIs(t, err, expectedErr)
 	})
 
@@ -586,17 +756,17 @@ func TestBoosterProgram(t *testing.T) {
 		twitterID := "1234"
 		valAddr := "addr"
 
-		store.EXPECT().BoosterStatus().Return(
-			&rpstore.BoosterStatus{
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(
+			&rpstore.CampaignStatus{
 				AllPkgs: 100,
 			},
 		)
 
-		store.EXPECT().IsWhitelisted(twitterID).Return(
+		store.EXPECT().IsWhitelisted(config.BoosterCampaignID, twitterID).Return(
 			false,
 		)
 
-		store.EXPECT().FindTwitterParty(twitterName).Return(
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, twitterName).Return(
 			nil,
 		)
 
@@ -604,15 +774,20 @@ func TestBoosterProgram(t *testing.T) {
 			nil, fmt.Errorf("not found"),
 		)
 
-		twitter.EXPECT().UserInfo(eng.ctx, twitterName).Return(
+		twitter.EXPECT().UserInfo(ctx, twitterName).Return(
 			&twitter_api.UserInfo{
 				TwitterID: twitterID,
 				CreatedAt: time.Now().AddDate(-1, 0, 0),
 			}, nil,
 		)
 
-		_, err := eng.BoosterPayment(discordID, twitterName, valAddr)
-		assert.Error(t, err)
+		_, err := eng.CampaignPayment(ctx, config.BoosterCampaignID, discordID, twitterName, valAddr)
+		assert.EqualError(t, err, "you are not eligible:\n"+
+			"- the Twitter account is not verified\n"+
+			"- the Twitter account is not whitelisted\n"+
+			"- the Twitter account is less than 3 years old\n"+
+			"- the Twitter account has less than 200 followers\n"+
+			"To whitelist your Twitter click here: https://forms.gle/fMaN1xtE322RBEYX8")
 	})
 
 	t.Run("less than 200 followers", func(t *testing.T) {
@@ -623,17 +798,17 @@ func TestBoosterProgram(t *testing.T) {
 		twitterID := "1234"
 		valAddr := "addr"
 
-		store.EXPECT().BoosterStatus().Return(
-			&rpstore.BoosterStatus{
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(
+			&rpstore.CampaignStatus{
 				AllPkgs: 100,
 			},
 		)
 
-		store.EXPECT().IsWhitelisted(twitterID).Return(
+		store.EXPECT().IsWhitelisted(config.BoosterCampaignID, twitterID).Return(
 			false,
 		)
 
-		store.EXPECT().FindTwitterParty(twitterName).Return(
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, twitterName).Return(
 			nil,
 		)
 
@@ -641,7 +816,7 @@ func TestBoosterProgram(t *testing.T) {
 			nil, fmt.Errorf("not found"),
 		)
 
-		twitter.EXPECT().UserInfo(eng.ctx, twitterName).Return(
+		twitter.EXPECT().UserInfo(ctx, twitterName).Return(
 			&twitter_api.UserInfo{
 				TwitterID: twitterID,
 				CreatedAt: time.Now().AddDate(-4, 0, 0),
@@ -649,7 +824,7 @@ func TestBoosterProgram(t *testing.T) {
 			}, nil,
 		)
 
-		_, err := eng.BoosterPayment(discordID, twitterName, valAddr)
+		_, err := eng.CampaignPayment(ctx, config.BoosterCampaignID, discordID, twitterName, valAddr)
 		assert.Error(t, err)
 	})
 
@@ -661,17 +836,17 @@ func TestBoosterProgram(t *testing.T) {
 		twitterID := "1234"
 		valAddr := "addr"
 
-		store.EXPECT().BoosterStatus().Return(
-			&rpstore.BoosterStatus{
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(
+			&rpstore.CampaignStatus{
 				AllPkgs: 100,
 			},
 		)
 
-		store.EXPECT().IsWhitelisted(twitterID).Return(
+		store.EXPECT().IsWhitelisted(config.BoosterCampaignID, twitterID).Return(
 			false,
 		)
 
-		store.EXPECT().FindTwitterParty(twitterName).Return(
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, twitterName).Return(
 			nil,
 		)
 
@@ -679,7 +854,7 @@ func TestBoosterProgram(t *testing.T) {
 			nil, fmt.Errorf("not found"),
 		)
 
-		twitter.EXPECT().UserInfo(eng.ctx, twitterName).Return(
+		twitter.EXPECT().UserInfo(ctx, twitterName).Return(
 			&twitter_api.UserInfo{
 				TwitterID:  twitterID,
 				CreatedAt:  time.Now().AddDate(-4, 0, 0),
@@ -688,11 +863,11 @@ func TestBoosterProgram(t *testing.T) {
 			}, nil,
 		)
 
-		twitter.EXPECT().RetweetSearch(eng.ctx, discordID, twitterName).Return(
+		twitter.EXPECT().RetweetSearch(ctx, discordID, twitterName).Return(
 			nil, fmt.Errorf("not found"),
 		)
 
-		_, err := eng.BoosterPayment(discordID, twitterName, valAddr)
+		_, err := eng.CampaignPayment(ctx, config.BoosterCampaignID, discordID, twitterName, valAddr)
 		assert.Error(t, err)
 	})
 
@@ -704,17 +879,17 @@ func TestBoosterProgram(t *testing.T) {
 		twitterID := "1234"
 		valAddr := "addr"
 
-		store.EXPECT().BoosterStatus().Return(
-			&rpstore.BoosterStatus{
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(
+			&rpstore.CampaignStatus{
 				AllPkgs: 100,
 			},
 		)
 
-		store.EXPECT().IsWhitelisted(twitterID).Return(
+		store.EXPECT().IsWhitelisted(config.BoosterCampaignID, twitterID).Return(
 			false,
 		)
 
-		store.EXPECT().FindTwitterParty(twitterName).Return(
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, twitterName).Return(
 			nil,
 		)
 
@@ -722,7 +897,7 @@ func TestBoosterProgram(t *testing.T) {
 			nil, fmt.Errorf("not found"),
 		)
 
-		twitter.EXPECT().UserInfo(eng.ctx, twitterName).Return(
+		twitter.EXPECT().UserInfo(ctx, twitterName).Return(
 			&twitter_api.UserInfo{
 				TwitterName: twitterName,
 				TwitterID:   twitterID,
@@ -732,21 +907,23 @@ func TestBoosterProgram(t *testing.T) {
 			}, nil,
 		)
 
-		twitter.EXPECT().RetweetSearch(eng.ctx, discordID, twitterName).Return(
+		twitter.EXPECT().RetweetSearch(ctx, discordID, twitterName).Return(
 			&twitter_api.TweetInfo{
 				CreatedAt: time.Now().AddDate(0, 0, -2),
 			}, nil,
 		)
 
-		nowPayments.EXPECT().CreatePayment(gomock.Any()).Return(
+		store.EXPECT().ReservePackage(config.BoosterCampaignID, 500, gomock.Any()).Return(100, nil)
+
+		nowPayments.EXPECT().CreateInvoice(ctx, gomock.Any()).Return(
 			nil,
 		)
 
-		store.EXPECT().SaveTwitterParty(gomock.Any()).Return(
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, gomock.Any()).Return(
 			nil,
 		)
 
-		party, err := eng.BoosterPayment(discordID, twitterName, valAddr)
+		party, err := eng.CampaignPayment(ctx, config.BoosterCampaignID, discordID, twitterName, valAddr)
 		assert.NoError(t, err)
 
 		assert.Equal(t, int64(150), party.AmountInPAC)
@@ -764,17 +941,17 @@ func TestBoosterProgram(t *testing.T) {
 		twitterID := "1234"
 		valAddr := "addr"
 
-		store.EXPECT().BoosterStatus().Return(
-			&rpstore.BoosterStatus{
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(
+			&rpstore.CampaignStatus{
 				AllPkgs: 99,
 			},
 		)
 
-		store.EXPECT().IsWhitelisted(twitterID).Return(
+		store.EXPECT().IsWhitelisted(config.BoosterCampaignID, twitterID).Return(
 			false,
 		)
 
-		store.EXPECT().FindTwitterParty(twitterName).Return(
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, twitterName).Return(
 			nil,
 		)
 
@@ -782,7 +959,7 @@ func TestBoosterProgram(t *testing.T) {
 			nil, fmt.Errorf("not found"),
 		)
 
-		twitter.EXPECT().UserInfo(eng.ctx, twitterName).Return(
+		twitter.EXPECT().UserInfo(ctx, twitterName).Return(
 			&twitter_api.UserInfo{
 				TwitterName: twitterName,
 				TwitterID:   twitterID,
@@ -792,21 +969,23 @@ func TestBoosterProgram(t *testing.T) {
 			}, nil,
 		)
 
-		twitter.EXPECT().RetweetSearch(eng.ctx, discordID, twitterName).Return(
+		twitter.EXPECT().RetweetSearch(ctx, discordID, twitterName).Return(
 			&twitter_api.TweetInfo{
 				CreatedAt: time.Now().AddDate(0, 0, -2),
 			}, nil,
 		)
 
-		nowPayments.EXPECT().CreatePayment(gomock.Any()).Return(
+		store.EXPECT().ReservePackage(config.BoosterCampaignID, 500, gomock.Any()).Return(99, nil)
+
+		nowPayments.EXPECT().CreateInvoice(ctx, gomock.Any()).Return(
 			nil,
 		)
 
-		store.EXPECT().SaveTwitterParty(gomock.Any()).Return(
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, gomock.Any()).Return(
 			nil,
 		)
 
-		party, err := eng.BoosterPayment(discordID, twitterName, valAddr)
+		party, err := eng.CampaignPayment(ctx, config.BoosterCampaignID, discordID, twitterName, valAddr)
 		assert.NoError(t, err)
 
 		assert.Equal(t, int64(200), party.AmountInPAC)
@@ -824,13 +1003,17 @@ func TestBoosterProgram(t *testing.T) {
 		twitterID := "1234"
 		valAddr := "addr"
 
-		store.EXPECT().BoosterStatus().Return(
-			&rpstore.BoosterStatus{
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(
+			&rpstore.CampaignStatus{
 				AllPkgs: 400,
 			},
 		)
 
-		store.EXPECT().FindTwitterParty(twitterName).Return(
+		store.EXPECT().IsWhitelisted(config.BoosterCampaignID, twitterID).Return(
+			false,
+		)
+
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, twitterName).Return(
 			nil,
 		)
 
@@ -838,7 +1021,7 @@ func TestBoosterProgram(t *testing.T) {
 			nil, fmt.Errorf("not found"),
 		)
 
-		twitter.EXPECT().UserInfo(eng.ctx, twitterName).Return(
+		twitter.EXPECT().UserInfo(ctx, twitterName).Return(
 			&twitter_api.UserInfo{
 				TwitterID:   twitterID,
 				TwitterName: twitterName,
@@ -848,21 +1031,23 @@ func TestBoosterProgram(t *testing.T) {
 			}, nil,
 		)
 
-		twitter.EXPECT().RetweetSearch(eng.ctx, discordID, twitterName).Return(
+		twitter.EXPECT().RetweetSearch(ctx, discordID, twitterName).Return(
 			&twitter_api.TweetInfo{
 				CreatedAt: time.Now().AddDate(0, 0, -2),
 			}, nil,
 		)
 
-		nowPayments.EXPECT().CreatePayment(gomock.Any()).Return(
+		store.EXPECT().ReservePackage(config.BoosterCampaignID, 500, gomock.Any()).Return(400, nil)
+
+		nowPayments.EXPECT().CreateInvoice(ctx, gomock.Any()).Return(
 			nil,
 		)
 
-		store.EXPECT().SaveTwitterParty(gomock.Any()).Return(
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, gomock.Any()).Return(
 			nil,
 		)
 
-		p, err := eng.BoosterPayment(discordID, twitterName, valAddr)
+		p, err := eng.CampaignPayment(ctx, config.BoosterCampaignID, discordID, twitterName, valAddr)
 		assert.NoError(t, err)
 
 		assert.Equal(t, 50, p.TotalPrice)
@@ -876,17 +1061,17 @@ func TestBoosterProgram(t *testing.T) {
 		twitterID := "1234"
 		valAddr := "addr"
 
-		store.EXPECT().BoosterStatus().Return(
-			&rpstore.BoosterStatus{
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(
+			&rpstore.CampaignStatus{
 				AllPkgs: 100,
 			},
 		)
 
-		store.EXPECT().IsWhitelisted(twitterID).Return(
+		store.EXPECT().IsWhitelisted(config.BoosterCampaignID, twitterID).Return(
 			true,
 		)
 
-		store.EXPECT().FindTwitterParty(twitterName).Return(
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, twitterName).Return(
 			nil,
 		)
 
@@ -894,7 +1079,7 @@ func TestBoosterProgram(t *testing.T) {
 			nil, fmt.Errorf("not found"),
 		)
 
-		twitter.EXPECT().UserInfo(eng.ctx, twitterName).Return(
+		twitter.EXPECT().UserInfo(ctx, twitterName).Return(
 			&twitter_api.UserInfo{
 				TwitterID:   twitterID,
 				TwitterName: twitterName,
@@ -904,57 +1089,1543 @@ func TestBoosterProgram(t *testing.T) {
 			}, nil,
 		)
 
-		twitter.EXPECT().RetweetSearch(eng.ctx, discordID, twitterName).Return(
+		twitter.EXPECT().RetweetSearch(ctx, discordID, twitterName).Return(
 			&twitter_api.TweetInfo{
 				CreatedAt: time.Now().AddDate(0, 0, -2),
 			}, nil,
 		)
 
-		nowPayments.EXPECT().CreatePayment(gomock.Any()).Return(
+		store.EXPECT().ReservePackage(config.BoosterCampaignID, 500, gomock.Any()).Return(100, nil)
+
+		nowPayments.EXPECT().CreateInvoice(ctx, gomock.Any()).Return(
 			nil,
 		)
 
-		store.EXPECT().SaveTwitterParty(gomock.Any()).Return(
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, gomock.Any()).Return(
 			nil,
 		)
 
-		_, err := eng.BoosterPayment(discordID, twitterName, valAddr)
+		_, err := eng.CampaignPayment(ctx, config.BoosterCampaignID, discordID, twitterName, valAddr)
 		assert.NoError(t, err)
 	})
 
 	t.Run("program end", func(t *testing.T) {
-		eng, _, store, _, _, _, _ := setup(t)
+		eng, _, store, _, _, _, ctx := setup(t)
 
 		twitterName := "abcd"
 		discordID := "123456789"
 		valAddr := "addr"
 
-		store.EXPECT().BoosterStatus().Return(
-			&rpstore.BoosterStatus{
-				AllPkgs: 501,
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, twitterName).Return(
+			nil,
+		)
+
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(
+			&rpstore.CampaignStatus{
+				AllPkgs: 500,
 			},
 		)
 
-		_, err := eng.BoosterPayment(discordID, twitterName, valAddr)
+		_, err := eng.CampaignPayment(ctx, config.BoosterCampaignID, discordID, twitterName, valAddr)
 		assert.EqualError(t, err, "program is finished")
 	})
 }
 
-func TestBoosterPrice(t *testing.T) {
-	for i := 0; i < 501; i++ {
-		if i < 100 {
-			price := boosterPrice(i)
-			assert.Equal(t, 30, price)
+func TestCampaigns(t *testing.T) {
+	setupCampaigns := func(t *testing.T) (*BotEngine, *client.MockIClient, *rpstore.MockIStore,
+		*twitter_api.MockIClient, *payment.MockIPaymentProvider, context.Context,
+	) {
+		t.Helper()
+
+		eng, client, store, _, twitter, nowPayments, ctx := setup(t)
+		eng.campaigns = append(eng.campaigns,
+			&config.Campaign{
+				ID:          "summer",
+				Title:       "Summer Campaign ☀️",
+				Provider:    config.ProviderNowPayments,
+				MaxPackages: 10,
+				Eligibility: &eligibility.Rule{
+					Type: eligibility.TypeAny,
+					Rules: []*eligibility.Rule{
+						{Type: eligibility.TypeWhitelisted},
+						{Type: eligibility.TypeTwitterFollowers, Min: 50},
+					},
+				},
+				PriceTiers: []config.PriceTier{{MaxPackages: 5, Price: 10}, {Price: 20}},
+				Rewards:    []config.RewardTier{{MinFollowers: 0, AmountInPAC: 80}},
+			},
+			&config.Campaign{
+				ID:         "finished",
+				Title:      "Finished Campaign",
+				Provider:   config.ProviderNowPayments,
+				EndAt:      time.Now().Add(-time.Hour),
+				PriceTiers: []config.PriceTier{{Price: 10}},
+				Rewards:    []config.RewardTier{{AmountInPAC: 80}},
+			},
+		)
+		eng.commands = eng.newCommands()
+
+		return eng, client, store, twitter, nowPayments, ctx
+	}
+
+	t.Run("campaign rules, prices and rewards", func(t *testing.T) {
+		eng, client, store, twitter, nowPayments, ctx := setupCampaigns(t)
+
+		store.EXPECT().FindTwitterParty("summer", "abcd").Return(nil)
+		store.EXPECT().CampaignStatus("summer").Return(&rpstore.CampaignStatus{AllPkgs: 6})
+		store.EXPECT().IsWhitelisted("summer", "1234").Return(false)
+		client.EXPECT().GetValidatorInfo(ctx, "addr").Return(nil, fmt.Errorf("not found"))
+		twitter.EXPECT().UserInfo(ctx, "abcd").Return(
+			&twitter_api.UserInfo{
+				TwitterID:   "1234",
+				TwitterName: "abcd",
+				CreatedAt:   time.Now().AddDate(0, -1, 0),
+				Followers:   60,
+			}, nil,
+		)
+		twitter.EXPECT().RetweetSearch(ctx, "123456789", "abcd").Return(&twitter_api.TweetInfo{}, nil)
+		store.EXPECT().ReservePackage("summer", 10, gomock.Any()).Return(6, nil)
+
+		nowPayments.EXPECT().CreateInvoice(ctx, gomock.Any()).Return(nil)
+		store.EXPECT().SaveTwitterParty("summer", gomock.Any()).Return(nil)
+
+		party, err := eng.CampaignPayment(ctx, "summer", "123456789", "abcd", "addr")
+		assert.NoError(t, err)
+		assert.Equal(t, 20, party.TotalPrice)
+		assert.Equal(t, int64(80), party.AmountInPAC)
+	})
+
+	t.Run("Discord membership of the caller", func(t *testing.T) {
+		eng, client, store, twitter, _, ctx := setupCampaigns(t)
+		summer, _ := eng.findCampaign("summer")
+		summer.Eligibility = &eligibility.Rule{Type: eligibility.TypeDiscordMemberAge, Min: 30}
+
+		caller := NewDiscordCaller("123456789", nil)
+		caller.JoinedAt = time.Now().AddDate(0, 0, -7)
+		callerCtx := withCaller(ctx, caller)
+
+		store.EXPECT().FindTwitterParty("summer", "abcd").Return(nil)
+		store.EXPECT().CampaignStatus("summer").Return(&rpstore.CampaignStatus{AllPkgs: 6})
+		store.EXPECT().IsWhitelisted("summer", "1234").Return(false)
+		client.EXPECT().GetValidatorInfo(callerCtx, "addr").Return(nil, fmt.Errorf("not found"))
+		twitter.EXPECT().UserInfo(callerCtx, "abcd").Return(&twitter_api.UserInfo{TwitterID: "1234"}, nil)
+
+		_, err := eng.CampaignPayment(callerCtx, "summer", "123456789", "abcd", "addr")
+		assert.EqualError(t, err, "you are not eligible:\n- you have joined the Discord server less than 30 days ago")
+	})
+
+	t.Run("campaign cap", func(t *testing.T) {
+		eng, _, store, _, _, ctx := setupCampaigns(t)
+
+		store.EXPECT().FindTwitterParty("summer", "abcd").Return(nil)
+		store.EXPECT().CampaignStatus("summer").Return(&rpstore.CampaignStatus{AllPkgs: 10})
+
+		_, err := eng.CampaignPayment(ctx, "summer", "123456789", "abcd", "addr")
+		assert.EqualError(t, err, "program is finished")
+	})
+
+	t.Run("the last package is reserved by another request", func(t *testing.T) {
+		eng, client, store, twitter, _, ctx := setupCampaigns(t)
+
+		store.EXPECT().FindTwitterParty("summer", "abcd").Return(nil)
+		store.EXPECT().CampaignStatus("summer").Return(&rpstore.CampaignStatus{AllPkgs: 9})
+		store.EXPECT().IsWhitelisted("summer", "1234").Return(true)
+		client.EXPECT().GetValidatorInfo(ctx, "addr").Return(nil, fmt.Errorf("not found"))
+		twitter.EXPECT().UserInfo(ctx, "abcd").Return(&twitter_api.UserInfo{TwitterID: "1234", TwitterName: "abcd"}, nil)
+		twitter.EXPECT().RetweetSearch(ctx, "123456789", "abcd").Return(&twitter_api.TweetInfo{}, nil)
+		store.EXPECT().ReservePackage("summer", 10, gomock.Any()).Return(0, rpstore.ErrCampaignFull)
+
+		_, err := eng.CampaignPayment(ctx, "summer", "123456789", "abcd", "addr")
+		assert.ErrorIs(t, err, rpstore.ErrCampaignFull)
+	})
+
+	t.Run("the package is released if the payment is not created", func(t *testing.T) {
+		eng, client, store, twitter, nowPayments, ctx := setupCampaigns(t)
+
+		store.EXPECT().FindTwitterParty("summer", "abcd").Return(nil)
+		store.EXPECT().CampaignStatus("summer").Return(&rpstore.CampaignStatus{AllPkgs: 1})
+		store.EXPECT().IsWhitelisted("summer", "1234").Return(true)
+		client.EXPECT().GetValidatorInfo(ctx, "addr").Return(nil, fmt.Errorf("not found"))
+		twitter.EXPECT().UserInfo(ctx, "abcd").Return(&twitter_api.UserInfo{TwitterID: "1234", TwitterName: "abcd"}, nil)
+		twitter.EXPECT().RetweetSearch(ctx, "123456789", "abcd").Return(&twitter_api.TweetInfo{}, nil)
+		store.EXPECT().ReservePackage("summer", 10, gomock.Any()).Return(1, nil)
+		nowPayments.EXPECT().CreateInvoice(ctx, gomock.Any()).Return(errors.New("service unavailable"))
+		store.EXPECT().ReleasePackage("summer", "1234").Return(nil)
+
+		_, err := eng.CampaignPayment(ctx, "summer", "123456789", "abcd", "addr")
+		assert.EqualError(t, err, "service unavailable")
+	})
+
+	t.Run("provider sends the bond transaction itself", func(t *testing.T) {
+		eng, _, store, _, _, ctx := setupCampaigns(t)
+		turboSwap := payment.NewMockIPaymentProvider(gomock.NewController(t))
+		eng.providers[config.ProviderTurboswap] = turboSwap
+		eng.campaigns[1].Provider = config.ProviderTurboswap
+
+		saved := rpstore.TwitterParty{TwitterName: "abcd", InvoiceID: "1111", Status: rpstore.PartyReserved}
+		store.EXPECT().FindTwitterParty("summer", "abcd").DoAndReturn(func(_, _ string) *rpstore.TwitterParty {
+			cpy := saved
+
+			return &cpy
+		}).Times(2)
+		store.EXPECT().SaveTwitterParty("summer", gomock.Any()).DoAndReturn(
+			func(_ string, party *rpstore.TwitterParty) error {
+				saved = *party
+
+				return nil
+			}).Times(2)
+		turboSwap.EXPECT().InvoiceStatus(ctx, gomock.Any()).Return(
+			&payment.InvoiceStatus{Status: payment.StatusFinished, TxID: "tx-1"}, nil,
+		)
+		store.EXPECT().SaveTransaction(gomock.Any()).Return(nil)
+
+		party, err := eng.CampaignClaim(ctx, "summer", "abcd")
+		assert.NoError(t, err)
+		assert.Equal(t, "tx-1", party.TransactionID)
+		assert.Equal(t, rpstore.PartyFulfilled, saved.Status)
+		assert.True(t, saved.PaymentFinished)
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		eng, _, _, _, _, ctx := setupCampaigns(t)
+		eng.campaigns[1].Provider = config.ProviderTurboswap
+
+		_, err := eng.CampaignPayment(ctx, "summer", "123456789", "abcd", "addr")
+		assert.EqualError(t, err, "unknown payment provider: turboswap")
+	})
+
+	t.Run("finished campaign", func(t *testing.T) {
+		eng, _, store, _, _, ctx := setupCampaigns(t)
+
+		store.EXPECT().FindTwitterParty("finished", "abcd").Return(nil)
+
+		_, err := eng.CampaignPayment(ctx, "finished", "123456789", "abcd", "addr")
+		assert.EqualError(t, err, "program is finished")
+	})
+
+	t.Run("unknown campaign", func(t *testing.T) {
+		eng, _, _, _, _, ctx := setupCampaigns(t)
+
+		_, err := eng.CampaignPayment(ctx, "unknown", "123456789", "abcd", "addr")
+		assert.EqualError(t, err, "unknown campaign: unknown")
+
+		_, err = eng.Run(ctx, NewCLICaller(), "booster-status --campaign=unknown")
+		assert.ErrorAs(t, err, &UsageError{})
+	})
+
+	t.Run("commands run for the chosen campaign", func(t *testing.T) {
+		eng, _, store, _, _, ctx := setupCampaigns(t)
+
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(&rpstore.CampaignStatus{AllPkgs: 1})
+		res, err := eng.Run(ctx, NewCLICaller(), "booster-status")
+		assert.NoError(t, err)
+		assert.Equal(t, "Pactus Validator Booster Program ✨", res.Title)
+
+		store.EXPECT().CampaignStatus("summer").Return(&rpstore.CampaignStatus{AllPkgs: 2})
+		res, err = eng.Run(ctx, NewCLICaller(), "booster-status --campaign=summer")
+		assert.NoError(t, err)
+		assert.Equal(t, "Summer Campaign ☀️", res.Title)
+		assert.Equal(t, 2, res.Data.(*rpstore.CampaignStatus).AllPkgs)
+	})
+}
+
+func TestRun(t *testing.T) {
+	eng, _, _, _, _, _, ctx := setup(t)
+
+	t.Run("unknown command", func(t *testing.T) {
+		_, err := eng.Run(ctx, anyone, "unknown-cmd")
+		assert.EqualError(t, err, "unknown command: unknown-cmd")
+	})
+
+	t.Run("missing argument", func(t *testing.T) {
+		_, err := eng.Run(ctx, anyone, "claimer-info")
+		assert.ErrorAs(t, err, &UsageError{})
+		assert.ErrorContains(t, err, "missing argument: testnet-addr")
+		assert.ErrorContains(t, err, "usage: claimer-info <testnet-addr>")
+	})
+
+	t.Run("too many arguments", func(t *testing.T) {
+		_, err := eng.Run(ctx, anyone, "claimer-info addr-1 addr-2")
+		assert.ErrorContains(t, err, "too many arguments")
+	})
+
+	t.Run("unknown flag", func(t *testing.T) {
+		_, err := eng.Run(ctx, anyone, "claimer-info --addr=addr-1")
+		assert.ErrorContains(t, err, "unknown flag: --addr")
+	})
+
+	t.Run("invalid integer argument", func(t *testing.T) {
+		_, err := eng.Run(ctx, anyone, "calc-reward abc day")
+		assert.ErrorContains(t, err, "invalid value for stake")
+	})
+
+	t.Run("invalid choice", func(t *testing.T) {
+		_, err := eng.Run(ctx, anyone, "calc-reward 100 week")
+		assert.ErrorContains(t, err, "invalid value for time")
+	})
+
+	t.Run("Discord names are not engine commands", func(t *testing.T) {
+		_, err := eng.Run(ctx, anyone, "reward-calc 100 day")
+		assert.EqualError(t, err, "unknown command: reward-calc")
+	})
+
+	t.Run("help lists public commands", func(t *testing.T) {
+		res, err := eng.Run(ctx, anyone, "help")
+		assert.NoError(t, err)
+		assert.Equal(t, CmdHelp, res.Command)
+		assert.Contains(t, res.String(), "/claim")
+		assert.Contains(t, res.String(), "/network-status")
+		assert.NotContains(t, res.String(), "/booster-whitelist")
+	})
+}
+
+func TestResult(t *testing.T) {
+	res := newResult(StatusSuccess, "done").
+		addField("Amount", "10 PAC").
+		addLink("Transaction", txLink("tx-id"))
+	res.Note = "be careful"
+
+	assert.Equal(t, "done\nAmount: 10 PAC\nTransaction: https://pacscan.org/transactions/tx-id\n\n> Note📝: be careful",
+		res.String())
+
+	data, err := json.Marshal(res)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"status":"success"`)
+	assert.Contains(t, string(data), `"fields":[{"name":"Amount","value":"10 PAC"}]`)
+}
+
+func TestCommandTimeout(t *testing.T) {
+	eng, client, _, _, _, _, ctx := setup(t)
+	eng.commandTimeouts[CmdNetworkStatus] = 50 * time.Millisecond
+
+	client.EXPECT().GetNetworkInfo(gomock.Any()).DoAndReturn(
+		func(ctx context.Context) (*pactus.GetNetworkInfoResponse, error) {
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		},
+	)
+
+	_, err := eng.Run(ctx, anyone, "network")
+	assert.ErrorIs(t, err, TimeoutError{Command: CmdNetworkStatus, Timeout: 50 * time.Millisecond})
+	assert.EqualError(t, err, "command `network` timed out after 50ms, please try again later")
+}
+
+func TestPermission(t *testing.T) {
+	eng, _, store, _, _, _, ctx := setup(t)
+
+	admin := NewDiscordCaller("admin-id", nil)
+	operator := NewDiscordCaller("operator-id", []string{"some-role", "operator-role"})
+	viewer := NewDiscordCaller("viewer-id", nil)
+
+	t.Run("roles", func(t *testing.T) {
+		assert.Equal(t, RoleAdmin, eng.roleOf(admin))
+		assert.Equal(t, RoleCampaignOperator, eng.roleOf(operator))
+		assert.Equal(t, RoleViewer, eng.roleOf(viewer))
+		assert.Equal(t, RoleAnyone, eng.roleOf(anyone))
+		assert.Equal(t, RoleAdmin, eng.roleOf(NewCLICaller()))
+	})
+
+	t.Run("unauthorized person", func(t *testing.T) {
+		_, err := eng.Run(ctx, anyone, "booster-whitelist abcd")
+		assert.EqualError(t, err, "unauthorized person: the `booster-whitelist` command requires the campaign-operator role")
+
+		_, err = eng.Run(ctx, viewer, "booster-whitelist abcd")
+		assert.ErrorContains(t, err, "unauthorized person")
+	})
+
+	t.Run("public commands are open to anyone", func(t *testing.T) {
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(&rpstore.CampaignStatus{AllPkgs: 10})
+		res, err := eng.Run(ctx, anyone, "booster-status")
+		assert.NoError(t, err)
+		assert.Equal(t, CmdBoosterStatus, res.Command)
+
+		store.EXPECT().ClaimStatus().Return(&rpstore.ClaimStatus{})
+		res, err = eng.Run(ctx, anyone, "claim-status")
+		assert.NoError(t, err)
+		assert.Equal(t, CmdClaimStatus, res.Command)
+
+		res, err = eng.Run(ctx, anyone, "help")
+		assert.NoError(t, err)
+		assert.Contains(t, res.String(), "/wallet")
+		assert.Contains(t, res.String(), "/booster-status")
+	})
+
+	t.Run("can't act on behalf of others", func(t *testing.T) {
+		_, err := eng.Run(ctx, operator, "booster-whitelist abcd --authorized-discord-id=admin-id")
+		assert.EqualError(t, err, "unauthorized person: only admins can set the authorized-discord-id")
+	})
+
+	t.Run("caller ID is required", func(t *testing.T) {
+		_, err := eng.Run(ctx, NewCLICaller(), "claim testnet-addr mainnet-addr")
+		assert.ErrorContains(t, err, "missing flag: --discord-id")
+	})
+}
+
+func TestAudit(t *testing.T) {
+	eng, _, store, _, twitter, _, ctx := setup(t)
+
+	operator := NewDiscordCaller("operator-id", []string{"operator-role"})
+
+	t.Run("state-changing commands are recorded", func(t *testing.T) {
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, "abcd").Return(nil)
+		twitter.EXPECT().UserInfo(gomock.Any(), "abcd").Return(
+			&twitter_api.UserInfo{TwitterID: "1234", TwitterName: "abcd"}, nil,
+		)
+		store.EXPECT().WhitelistTwitterAccount(config.BoosterCampaignID, "1234", "abcd", "operator-id").Return(nil)
+
+		_, err := eng.Run(ctx, operator, "booster-whitelist abcd")
+		assert.NoError(t, err)
+	})
+
+	t.Run("failures are recorded", func(t *testing.T) {
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, "abcd").Return(&rpstore.TwitterParty{TwitterName: "abcd"})
+
+		_, err := eng.Run(ctx, operator, "booster-whitelist abcd")
+		assert.Error(t, err)
+	})
+
+	t.Run("read-only commands are not recorded", func(t *testing.T) {
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(&rpstore.CampaignStatus{})
+
+		_, err := eng.Run(ctx, operator, "booster-status")
+		assert.NoError(t, err)
+	})
+
+	count, err := audit.Verify(eng.auditLog.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	records, err := audit.Search(eng.auditLog.Path(), audit.Filter{Actor: "discord:operator-id"})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, CmdBoosterWhitelist, records[0].Command)
+	assert.Equal(t, "abcd", records[0].Args["twitter-username"])
+	assert.Equal(t, "success: Twitter `abcd` whitelisted", records[0].Result)
+	assert.Contains(t, records[1].Result, "error: the Twitter `abcd` already registered")
+}
+
+func TestDryRun(t *testing.T) {
+	eng, _, store, _, twitter, _, ctx := setup(t)
+	eng.dryRun = true
+
+	store.EXPECT().FindTwitterParty(config.BoosterCampaignID, "abcd").Return(nil)
+	twitter.EXPECT().UserInfo(gomock.Any(), "abcd").Return(
+		&twitter_api.UserInfo{TwitterID: "1234", TwitterName: "abcd"}, nil,
+	)
+	store.EXPECT().WhitelistTwitterAccount(config.BoosterCampaignID, "1234", "abcd", "admin-id").Return(nil)
+
+	res, err := eng.Run(ctx, NewDiscordCaller("admin-id", nil), "booster-whitelist abcd")
+	assert.NoError(t, err)
+	assert.True(t, res.Simulated)
+	assert.Equal(t, "🧪 SIMULATED: dry-run mode, no transaction is broadcast\nTwitter `abcd` whitelisted", res.String())
+
+	records, err := audit.Search(eng.auditLog.Path(), audit.Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.True(t, records[0].Simulated)
+}
+
+func TestTransactionTracker(t *testing.T) {
+	eng, client, store, _, _, _, ctx := setup(t)
+	eng.txConfirmTimeout = 10 * time.Minute
+
+	confirmed := &rpstore.Transaction{
+		TxID: "confirmed-tx", Kind: rpstore.TxKindClaim, Ref: "testnet-addr",
+		Status: rpstore.TxStatusPending, SentAt: time.Now().Unix(),
+	}
+	waiting := &rpstore.Transaction{
+		TxID: "waiting-tx", Kind: config.BoosterCampaignID, Ref: "twitter-id-1",
+		Status: rpstore.TxStatusPending, SentAt: time.Now().Unix(),
+	}
+	expired := &rpstore.Transaction{
+		TxID: "expired-tx", Kind: config.BoosterCampaignID, Ref: "twitter-id-2",
+		Status: rpstore.TxStatusPending, SentAt: time.Now().Add(-time.Hour).Unix(),
+	}
+
+	store.EXPECT().PendingTransactions().Return([]*rpstore.Transaction{confirmed, waiting, expired})
+	client.EXPECT().GetTransactionData(ctx, "confirmed-tx").Return(
+		&pactus.GetTransactionResponse{BlockHeight: 1234}, nil,
+	)
+	client.EXPECT().GetTransactionData(ctx, "waiting-tx").Return(nil, errors.New("not found"))
+	client.EXPECT().GetTransactionData(ctx, "expired-tx").Return(nil, errors.New("not found"))
+
+	saved := map[string]*rpstore.Transaction{}
+	store.EXPECT().SaveTransaction(gomock.Any()).DoAndReturn(
+		func(tx *rpstore.Transaction) error {
+			saved[tx.TxID] = tx
+
+			return nil
+		},
+	).Times(2)
+
+	eng.checkTransactions(ctx)
+
+	assert.Equal(t, rpstore.TxStatusConfirmed, saved["confirmed-tx"].Status)
+	assert.Equal(t, uint32(1234), saved["confirmed-tx"].Height)
+	assert.False(t, saved["confirmed-tx"].NeedsReview)
+
+	assert.Equal(t, rpstore.TxStatusFailed, saved["expired-tx"].Status)
+	assert.True(t, saved["expired-tx"].NeedsReview)
+
+	assert.NotContains(t, saved, "waiting-tx")
+
+	t.Run("status is shown in claimer-info", func(t *testing.T) {
+		store.EXPECT().ClaimerInfo("testnet-addr").Return(
+			&rpstore.Claimer{DiscordID: "123456789", TotalReward: 1e9, ClaimedTxID: "confirmed-tx"},
+		)
+		store.EXPECT().TransactionInfo("confirmed-tx").Return(saved["confirmed-tx"])
+
+		res, err := eng.Run(ctx, anyone, "claimer-info testnet-addr")
+		assert.NoError(t, err)
+		assert.Contains(t, res.String(), "Transaction Status: confirmed✅ at height 1,234")
+	})
+
+	t.Run("transactions to review", func(t *testing.T) {
+		store.EXPECT().ReviewTransactions().Return([]*rpstore.Transaction{saved["expired-tx"]})
+
+		res, err := eng.Run(ctx, NewCLICaller(), "tx-review")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusWarning, res.Status)
+		assert.Contains(t, res.String(), "booster: twitter-id-2: https://pacscan.org/transactions/expired-tx")
+	})
+}
+
+func TestBlockEventsConfirmation(t *testing.T) {
+	eng, _, store, _, _, _, _ := setup(t)
+
+	pending := &rpstore.Transaction{
+		TxID: "pending-tx", Kind: config.BoosterCampaignID, Ref: "twitter-id-1",
+		Status: rpstore.TxStatusPending, SentAt: time.Now().Unix(),
+	}
+	confirmed := &rpstore.Transaction{
+		TxID: "confirmed-tx", Kind: rpstore.TxKindClaim, Ref: "testnet-addr",
+		Status: rpstore.TxStatusConfirmed, Height: 1200,
+	}
+
+	store.EXPECT().TransactionInfo("pending-tx").Return(pending)
+	store.EXPECT().TransactionInfo("confirmed-tx").Return(confirmed)
+	store.EXPECT().TransactionInfo("unknown-tx").Return(nil)
+
+	saved := make(chan *rpstore.Transaction, 1)
+	store.EXPECT().SaveTransaction(gomock.Any()).DoAndReturn(
+		func(tx *rpstore.Transaction) error {
+			saved <- tx
+
+			return nil
+		},
+	)
+
+	events := eng.clientMgr.Events()
+	go eng.confirmTransactions(events.Subscribe(10, client.EventBond))
+	events.Publish(&client.Event{Type: client.EventBond, TxID: "unknown-tx", Height: 1300})
+	events.Publish(&client.Event{Type: client.EventBond, TxID: "confirmed-tx", Height: 1300})
+	events.Publish(&client.Event{Type: client.EventBond, TxID: "pending-tx", Height: 1300})
+
+	select {
+	case tx := <-saved:
+		assert.Equal(t, "pending-tx", tx.TxID)
+		assert.Equal(t, rpstore.TxStatusConfirmed, tx.Status)
+		assert.Equal(t, uint32(1300), tx.Height)
+	case <-time.After(time.Second):
+		t.Fatal("transaction is not confirmed")
+	}
+}
+
+func TestReconcilePayouts(t *testing.T) {
+	eng, client, store, wallet, _, _, ctx := setup(t)
+
+	record := func(entry *journal.Entry) {
+		assert.NoError(t, eng.journal.Record(entry))
+	}
+
+	record(&journal.Entry{Key: "claim:addr-1", Kind: rpstore.TxKindClaim, Ref: "addr-1", State: journal.StateIntent})
+	record(&journal.Entry{
+		Key: "claim:addr-2", Kind: rpstore.TxKindClaim, Ref: "addr-2",
+		State: journal.StateSigned, TxID: "tx-2", RawTx: []byte("raw-tx-2"),
+	})
+	record(&journal.Entry{
+		Key: "claim:addr-3", Kind: rpstore.TxKindClaim, Ref: "addr-3",
+		State: journal.StateSigned, TxID: "tx-3", RawTx: []byte("raw-tx-3"),
+	})
+	record(&journal.Entry{
+		Key: "booster:abcd", Kind: config.BoosterCampaignID, Ref: "abcd",
+		State: journal.StateBroadcast, TxID: "tx-4", RawTx: []byte("raw-tx-4"),
+	})
+	record(&journal.Entry{
+		Key: "claim:addr-5", Kind: rpstore.TxKindClaim, Ref: "addr-5",
+		State: journal.StateSigned, TxID: "tx-5", RawTx: []byte("raw-tx-5"),
+	})
+	// addr-6 is simulated by a dry run, it is never sent.
+	record(&journal.Entry{
+		Key: "claim:addr-6", Kind: rpstore.TxKindClaim, Ref: "addr-6",
+		State: journal.StateSigned, TxID: "tx-6", RawTx: []byte("raw-tx-6"), DryRun: true,
+	})
+
+	// tx-2 is on the chain, it is only saved.
+	client.EXPECT().GetTransactionData(ctx, "tx-2").Return(&pactus.GetTransactionResponse{BlockHeight: 10}, nil)
+	store.EXPECT().AddClaimTransaction("addr-2", "tx-2").Return(nil)
+
+	// tx-3 is not on the chain, the same transaction is sent again.
+	client.EXPECT().GetTransactionData(ctx, "tx-3").Return(nil, errors.New("not found"))
+	wallet.EXPECT().BroadcastTransaction(gomock.Any(), []byte("raw-tx-3")).Return("tx-3", nil)
+	store.EXPECT().AddClaimTransaction("addr-3", "tx-3").Return(nil)
+
+	// tx-4 is broadcast, but not saved.
+	party := &rpstore.TwitterParty{TwitterName: "abcd"}
+	store.EXPECT().FindTwitterParty(config.BoosterCampaignID, "abcd").Return(party)
+	store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, party).Return(nil)
+
+	// tx-5 can't be sent, it is flagged for review.
+	client.EXPECT().GetTransactionData(ctx, "tx-5").Return(nil, errors.New("not found"))
+	wallet.EXPECT().BroadcastTransaction(gomock.Any(), []byte("raw-tx-5")).Return("", errors.New("invalid transaction"))
+
+	txs := map[string]*rpstore.Transaction{}
+	store.EXPECT().SaveTransaction(gomock.Any()).DoAndReturn(
+		func(tx *rpstore.Transaction) error {
+			txs[tx.TxID] = tx
+
+			return nil
+		},
+	).Times(4)
+
+	eng.reconcilePayouts(ctx)
+
+	assert.Equal(t, journal.StateAborted, eng.journal.Find("claim:addr-1").State)
+	assert.Equal(t, journal.StateDone, eng.journal.Find("claim:addr-2").State)
+	assert.Equal(t, journal.StateDone, eng.journal.Find("claim:addr-3").State)
+	assert.Equal(t, journal.StateDone, eng.journal.Find("booster:abcd").State)
+	assert.Equal(t, journal.StateSigned, eng.journal.Find("claim:addr-5").State)
+	assert.Equal(t, journal.StateAborted, eng.journal.Find("claim:addr-6").State)
+	assert.Equal(t, "tx-4", party.TransactionID)
+
+	assert.Equal(t, rpstore.TxStatusPending, txs["tx-2"].Status)
+	assert.True(t, txs["tx-5"].NeedsReview)
+	assert.Len(t, eng.journal.Unfinished(), 1)
+
+	records, err := audit.Search(eng.auditLog.Path(), audit.Filter{Actor: "system:payout-reconciler"})
+	assert.NoError(t, err)
+	assert.Len(t, records, 4)
+	for _, rec := range records {
+		if rec.TxID == "tx-5" {
+			assert.Equal(t, "error: invalid transaction", rec.Result)
+		} else {
+			assert.Equal(t, "done", rec.Result)
+		}
+	}
+}
+
+// testNotifier keeps the alerts of an engine, that is not started.
+type testNotifier struct {
+	eng    *BotEngine
+	alerts []*Alert
+}
+
+func newTestNotifier(eng *BotEngine) *testNotifier {
+	n := &testNotifier{eng: eng}
+	eng.RegisterNotifier(FrontendDiscord, n)
+
+	return n
+}
+
+func (n *testNotifier) Notify(alert *Alert) {
+	n.alerts = append(n.alerts, alert)
+}
+
+// sent sends the queued alerts of the engine and returns all the alerts that are sent to the notifier.
+func (n *testNotifier) sent() []*Alert {
+	for {
+		select {
+		case queued := <-n.eng.alerts:
+			n.eng.sendAlert(queued)
+
+		default:
+			return n.alerts
 		}
+	}
+}
+
+func TestAlertQueue(t *testing.T) {
+	eng, _, _, _, _, _, _ := setup(t)
+	notifier := newTestNotifier(eng)
+
+	// The alerts of the locked engine are queued, the notifiers are not called while it is locked.
+	eng.Lock()
+	eng.alert(StatusDanger, "Payout limit reached🚨", "blocked")
+	eng.notifyUser(FrontendDiscord, "user-1", StatusInfo, "Booster", "paid")
+	eng.notifyUser(FrontendCLI, "user-2", StatusInfo, "Booster", "paid")
+	assert.Empty(t, notifier.alerts)
+	eng.Unlock()
+
+	alerts := notifier.sent()
+	require.Len(t, alerts, 2)
+	assert.Empty(t, alerts[0].UserID)
+	assert.Equal(t, "user-1", alerts[1].UserID)
+}
+
+func TestPayoutLimits(t *testing.T) {
+	pay := func(eng *BotEngine, ref, discordID string, amount int64) (string, error) {
+		return eng.payout(context.Background(), NewCLICaller(), rpstore.TxKindClaim, ref, discordID,
+			"public-key", "mainnet-addr", "memo", amount)
+	}
+
+	expectPayout := func(store *rpstore.MockIStore, wallet *wallet.MockIWallet, ref, txID string, amount int64) {
+		wallet.EXPECT().SignBondTransaction(gomock.Any(), "public-key", "mainnet-addr", "memo", amount).Return(
+			txID, []byte(txID), nil,
+		)
+		wallet.EXPECT().BroadcastTransaction(gomock.Any(), []byte(txID)).Return(txID, nil)
+		store.EXPECT().AddClaimTransaction(ref, txID).Return(nil)
+		store.EXPECT().SaveTransaction(gomock.Any()).Return(nil)
+	}
+
+	t.Run("per transaction limit", func(t *testing.T) {
+		eng, _, _, _, _, _, _ := setup(t)
+		notifier := newTestNotifier(eng)
+		eng.limits.MaxPerTx = 100
+
+		_, err := pay(eng, "addr-1", "user-1", utils.CoinToChange(101))
+		assert.ErrorIs(t, err, ErrLimitReached)
+		assert.ErrorContains(t, err, "per transaction")
+		assert.Nil(t, eng.journal.Find("claim:addr-1"))
+
+		assert.Len(t, notifier.sent(), 1)
+		assert.Equal(t, StatusDanger, notifier.sent()[0].Status)
+		assert.Contains(t, notifier.sent()[0].Message, "user-1")
+	})
+
+	t.Run("hourly and daily limits", func(t *testing.T) {
+		eng, _, store, wallet, _, _, _ := setup(t)
+		notifier := newTestNotifier(eng)
+		eng.limits.MaxPerHour = 100
+		eng.limits.MaxPerDay = 150
+
+		expectPayout(store, wallet, "addr-1", "tx-1", utils.CoinToChange(60))
+		_, err := pay(eng, "addr-1", "user-1", utils.CoinToChange(60))
+		assert.NoError(t, err)
+
+		_, err = pay(eng, "addr-2", "user-2", utils.CoinToChange(50))
+		assert.ErrorIs(t, err, ErrLimitReached)
+		assert.ErrorContains(t, err, "hourly")
+
+		// The payouts of the previous hours are only counted in the daily limit.
+		assert.NoError(t, eng.journal.Record(&journal.Entry{
+			Key: "claim:old", Amount: utils.CoinToChange(60), State: journal.StateDone,
+			CreatedAt: time.Now().Add(-2 * time.Hour).Unix(),
+		}))
+
+		_, err = pay(eng, "addr-2", "user-2", utils.CoinToChange(40))
+		assert.ErrorIs(t, err, ErrLimitReached)
+		assert.ErrorContains(t, err, "daily")
+
+		assert.Len(t, notifier.sent(), 2)
+	})
+
+	t.Run("per user limit", func(t *testing.T) {
+		eng, _, store, wallet, _, _, _ := setup(t)
+		eng.limits.MaxPerUser = 100
+
+		expectPayout(store, wallet, "addr-1", "tx-1", utils.CoinToChange(80))
+		_, err := pay(eng, "addr-1", "user-1", utils.CoinToChange(80))
+		assert.NoError(t, err)
+
+		_, err = pay(eng, "addr-2", "user-1", utils.CoinToChange(30))
+		assert.ErrorIs(t, err, ErrLimitReached)
+		assert.ErrorContains(t, err, "per user")
+
+		expectPayout(store, wallet, "addr-3", "tx-3", utils.CoinToChange(30))
+		_, err = pay(eng, "addr-3", "user-2", utils.CoinToChange(30))
+		assert.NoError(t, err)
+	})
+
+	t.Run("done payouts are not paid again", func(t *testing.T) {
+		eng, _, store, wallet, _, _, _ := setup(t)
+
+		expectPayout(store, wallet, "addr-1", "tx-1", utils.CoinToChange(80))
+		_, err := pay(eng, "addr-1", "user-1", utils.CoinToChange(80))
+		assert.NoError(t, err)
+
+		_, err = pay(eng, "addr-1", "user-1", utils.CoinToChange(20))
+		assert.EqualError(t, err, "`addr-1` is already paid in transaction tx-1")
+		assert.Equal(t, utils.CoinToChange(80), eng.journal.Spent(time.Time{}, "user-1"))
+	})
+
+	t.Run("aborted payouts are not counted", func(t *testing.T) {
+		eng, _, _, wallet, _, _, _ := setup(t)
+		eng.limits.MaxPerUser = 100
+
+		wallet.EXPECT().SignBondTransaction(gomock.Any(), "public-key", "mainnet-addr", "memo", utils.CoinToChange(80)).Return(
+			"", nil, errors.New("invalid public key"),
+		)
+		_, err := pay(eng, "addr-1", "user-1", utils.CoinToChange(80))
+		assert.ErrorContains(t, err, "invalid public key")
+
+		assert.Zero(t, eng.journal.Spent(time.Time{}, "user-1"))
+	})
+}
+
+func TestPartyExpiry(t *testing.T) {
+	daysAgo := func(days int) int64 {
+		return time.Now().AddDate(0, 0, -days).Unix()
+	}
+
+	t.Run("expire unpaid parties", func(t *testing.T) {
+		eng, _, store, _, _, nowPayments, ctx := setup(t)
+		notifier := newTestNotifier(eng)
+
+		unpaid := &rpstore.TwitterParty{TwitterName: "unpaid", DiscordID: "user-1", DiscountCode: "1111", CreatedAt: daysAgo(8)}
+		paid := &rpstore.TwitterParty{TwitterName: "paid", DiscordID: "user-2", DiscountCode: "2222", CreatedAt: daysAgo(8)}
+		fresh := &rpstore.TwitterParty{TwitterName: "fresh", DiscordID: "user-3", DiscountCode: "3333", CreatedAt: daysAgo(6)}
+
+		store.EXPECT().UnpaidTwitterParties(config.BoosterCampaignID).Return(
+			[]*rpstore.TwitterParty{unpaid, paid, fresh},
+		)
+		nowPayments.EXPECT().InvoiceStatus(ctx, gomock.Any()).DoAndReturn(
+			func(_ context.Context, party *rpstore.TwitterParty) (*payment.InvoiceStatus, error) {
+				if party.TwitterName == "paid" {
+					return &payment.InvoiceStatus{Status: payment.StatusFinished}, nil
+				}
+
+				return &payment.InvoiceStatus{Status: payment.StatusWaiting}, nil
+			},
+		).Times(2)
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, unpaid).Return(nil)
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, paid).Return(nil)
+
+		eng.checkExpiredParties(ctx)
+
+		assert.True(t, unpaid.IsExpired())
+		assert.NotZero(t, unpaid.ExpiredAt)
+		assert.Equal(t, rpstore.PartyPaid, paid.Status)
+		assert.True(t, paid.PaymentFinished)
+		assert.False(t, fresh.IsExpired())
+
+		records, err := audit.Search(eng.auditLog.Path(), audit.Filter{Actor: "system:expiry"})
+		assert.NoError(t, err)
+		assert.Len(t, records, 2)
+		assert.Equal(t, "unpaid", records[0].Args["twitter-name"])
+		assert.Equal(t, " -> expired", records[0].Result)
+
+		assert.Len(t, notifier.sent(), 1)
+		assert.Equal(t, "user-1", notifier.sent()[0].UserID)
+		assert.Contains(t, notifier.sent()[0].Message, "1111")
+	})
+
+	t.Run("party is paid while checking the payment", func(t *testing.T) {
+		eng, _, store, _, _, nowPayments, ctx := setup(t)
+		notifier := newTestNotifier(eng)
+
+		party := &rpstore.TwitterParty{TwitterName: "abcd", Status: rpstore.PartyReserved, CreatedAt: daysAgo(8)}
+		store.EXPECT().UnpaidTwitterParties(config.BoosterCampaignID).Return([]*rpstore.TwitterParty{party})
+		nowPayments.EXPECT().InvoiceStatus(ctx, gomock.Any()).Return(&payment.InvoiceStatus{Status: payment.StatusWaiting}, nil)
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, party).Return(
+			errors.New("the package of `abcd` can't change from paid to expired"),
+		)
 
-		if i > 100 && i < 200 {
-			price := boosterPrice(i)
-			assert.Equal(t, 40, price)
+		eng.checkExpiredParties(ctx)
+		assert.Empty(t, notifier.sent())
+	})
+
+	t.Run("partially paid parties are kept", func(t *testing.T) {
+		eng, _, store, _, _, nowPayments, ctx := setup(t)
+		notifier := newTestNotifier(eng)
+
+		party := &rpstore.TwitterParty{
+			TwitterName: "abcd", DiscordID: "user-1", TotalPrice: 30, Status: rpstore.PartyReserved, CreatedAt: daysAgo(8),
 		}
+		store.EXPECT().UnpaidTwitterParties(config.BoosterCampaignID).Return([]*rpstore.TwitterParty{party})
+		nowPayments.EXPECT().InvoiceStatus(ctx, gomock.Any()).Return(
+			&payment.InvoiceStatus{Status: payment.StatusPartiallyPaid, PaidAmount: 12}, nil)
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, party).Return(nil)
+
+		eng.checkExpiredParties(ctx)
+
+		assert.Equal(t, rpstore.PartyReserved, party.Status)
+		assert.Equal(t, rpstore.PaymentUnderpaid, party.PaymentCase)
+		assert.Len(t, notifier.sent(), 2)
+		assert.Equal(t, "Underpaid payment⚠️", notifier.sent()[0].Title)
+	})
+
+	t.Run("late payments are refused", func(t *testing.T) {
+		eng, _, store, _, _, _, ctx := setup(t)
+
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, "abcd").Return(
+			&rpstore.TwitterParty{
+				TwitterName: "abcd", DiscountCode: "1111", Status: rpstore.PartyExpired, ExpiredAt: daysAgo(1),
+			},
+		)
+
+		_, err := eng.CampaignClaim(ctx, config.BoosterCampaignID, "abcd")
+		assert.ErrorContains(t, err, "the discount code `1111` is expired")
+	})
+
+	t.Run("expired parties can register again", func(t *testing.T) {
+		eng, client, store, _, twitter, _, ctx := setup(t)
+
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, "abcd").Return(
+			&rpstore.TwitterParty{TwitterName: "abcd", Status: rpstore.PartyExpired, ExpiredAt: daysAgo(1)},
+		)
+		store.EXPECT().CampaignStatus(config.BoosterCampaignID).Return(&rpstore.CampaignStatus{AllPkgs: 10})
+		store.EXPECT().IsWhitelisted(config.BoosterCampaignID, "1234").Return(true)
+		client.EXPECT().GetValidatorInfo(ctx, "addr").Return(&pactus.GetValidatorResponse{}, nil)
+		twitter.EXPECT().UserInfo(ctx, "abcd").Return(&twitter_api.UserInfo{TwitterID: "1234", TwitterName: "abcd"}, nil)
 
-		if i > 200 {
-			price := boosterPrice(i)
-			assert.Equal(t, 50, price)
+		_, err := eng.CampaignPayment(ctx, config.BoosterCampaignID, "123456789", "abcd", "addr")
+		assert.ErrorContains(t, err, "you are not eligible:\n- this address is already a staked validator")
+	})
+}
+
+func TestPaymentEvents(t *testing.T) {
+	finished := func(orderID, invoiceID string) *payment.Event {
+		return &payment.Event{
+			Provider:  config.ProviderNowPayments,
+			OrderID:   orderID,
+			InvoiceID: invoiceID,
+			Status:    payment.StatusFinished,
 		}
 	}
+
+	t.Run("finished payment is fulfilled", func(t *testing.T) {
+		eng, _, store, wallet, _, _, _ := setup(t)
+		notifier := newTestNotifier(eng)
+
+		saved := rpstore.TwitterParty{
+			TwitterID: "1234", TwitterName: "abcd", DiscordID: "user-1", DiscountCode: "1111",
+			InvoiceID: "42", ValPubKey: "public-key", ValAddr: "val-addr",
+			AmountInPAC: 150, Status: rpstore.PartyReserved,
+		}
+		find := func(_, _ string) *rpstore.TwitterParty {
+			cpy := saved
+
+			return &cpy
+		}
+		store.EXPECT().FindTwitterPartyByDiscountCode(config.BoosterCampaignID, "1111").DoAndReturn(find)
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, "abcd").DoAndReturn(find).Times(2)
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, gomock.Any()).DoAndReturn(
+			func(_ string, party *rpstore.TwitterParty) error {
+				saved = *party
+
+				return nil
+			}).Times(2)
+		wallet.EXPECT().SignBondTransaction(gomock.Any(), "public-key", "val-addr", "Booster Program", utils.CoinToChange(150)).Return(
+			"tx-1", []byte("tx-1"), nil,
+		)
+		wallet.EXPECT().BroadcastTransaction(gomock.Any(), []byte("tx-1")).Return("tx-1", nil)
+		store.EXPECT().SaveTransaction(gomock.Any()).Return(nil)
+
+		eng.handlePaymentEvent(finished("1111", "42"))
+
+		assert.Equal(t, rpstore.PartyFulfilled, saved.Status)
+		assert.True(t, saved.PaymentFinished)
+		assert.Equal(t, "tx-1", saved.TransactionID)
+
+		records, err := audit.Search(eng.auditLog.Path(), audit.Filter{Actor: "system:payment-event"})
+		assert.NoError(t, err)
+		assert.Len(t, records, 2)
+		assert.Equal(t, "reserved -> paid", records[0].Result)
+		assert.Equal(t, auditPayout, records[1].Command)
+		assert.Equal(t, "done", records[1].Result)
+		assert.Equal(t, "tx-1", records[1].TxID)
+
+		assert.Len(t, notifier.sent(), 1)
+		assert.Equal(t, "user-1", notifier.sent()[0].UserID)
+		assert.Equal(t, StatusSuccess, notifier.sent()[0].Status)
+		assert.Contains(t, notifier.sent()[0].Message, "tx-1")
+	})
+
+	t.Run("fulfilled party is not paid twice", func(t *testing.T) {
+		eng, _, store, _, _, _, _ := setup(t)
+		notifier := newTestNotifier(eng)
+
+		store.EXPECT().FindTwitterPartyByDiscountCode(config.BoosterCampaignID, "1111").Return(
+			&rpstore.TwitterParty{TwitterName: "abcd", DiscountCode: "1111", Status: rpstore.PartyFulfilled},
+		)
+
+		eng.handlePaymentEvent(finished("1111", "42"))
+		assert.Empty(t, notifier.sent())
+	})
+
+	t.Run("unfinished payment updates the payment status", func(t *testing.T) {
+		eng, _, store, _, _, _, _ := setup(t)
+
+		party := &rpstore.TwitterParty{TwitterName: "abcd", DiscountCode: "1111", Status: rpstore.PartyReserved}
+		store.EXPECT().FindTwitterPartyByDiscountCode(config.BoosterCampaignID, "1111").Return(party)
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, party).Return(nil)
+
+		eng.handlePaymentEvent(&payment.Event{
+			Provider: config.ProviderNowPayments, OrderID: "1111", Status: payment.StatusConfirming,
+		})
+
+		assert.Equal(t, string(payment.StatusConfirming), party.PaymentStatus)
+		assert.Equal(t, rpstore.PartyReserved, party.Status)
+	})
+
+	t.Run("unknown payment", func(t *testing.T) {
+		eng, _, store, _, _, _, _ := setup(t)
+		notifier := newTestNotifier(eng)
+
+		store.EXPECT().FindTwitterPartyByDiscountCode(config.BoosterCampaignID, "1111").Return(
+			&rpstore.TwitterParty{TwitterName: "abcd", InvoiceID: "43", Status: rpstore.PartyReserved},
+		)
+		store.EXPECT().FindTwitterPartyByDiscountCode(config.BoosterCampaignID, "2222").Return(nil)
+
+		eng.handlePaymentEvent(finished("1111", "42"))
+		eng.handlePaymentEvent(finished("2222", "44"))
+
+		assert.Len(t, notifier.sent(), 2)
+		assert.Empty(t, notifier.sent()[0].UserID)
+		assert.Contains(t, notifier.sent()[0].Message, "no discount code matches it")
+	})
+
+	t.Run("late payment", func(t *testing.T) {
+		eng, _, store, _, _, _, _ := setup(t)
+		notifier := newTestNotifier(eng)
+
+		party := &rpstore.TwitterParty{
+			TwitterName: "abcd", DiscordID: "user-1", DiscountCode: "1111", TotalPrice: 30, Status: rpstore.PartyExpired,
+		}
+		store.EXPECT().FindTwitterPartyByDiscountCode(config.BoosterCampaignID, "1111").Return(party)
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, party).Return(nil)
+
+		event := finished("1111", "42")
+		event.PaidAmount = 30
+		eng.handlePaymentEvent(event)
+
+		assert.Equal(t, rpstore.PartyExpired, party.Status)
+		assert.False(t, party.PaymentFinished)
+		assert.Equal(t, rpstore.PaymentLate, party.PaymentCase)
+		assert.InDelta(t, 30, party.PaidAmount, 0)
+
+		assert.Len(t, notifier.sent(), 2)
+		assert.Equal(t, "Late payment⚠️", notifier.sent()[0].Title)
+		assert.Empty(t, notifier.sent()[0].UserID)
+		assert.Equal(t, "user-1", notifier.sent()[1].UserID)
+	})
+
+	t.Run("underpaid payment", func(t *testing.T) {
+		eng, _, store, _, _, _, _ := setup(t)
+		notifier := newTestNotifier(eng)
+
+		party := &rpstore.TwitterParty{
+			TwitterName: "abcd", DiscordID: "user-1", DiscountCode: "1111", TotalPrice: 30, Status: rpstore.PartyReserved,
+		}
+		store.EXPECT().FindTwitterPartyByDiscountCode(config.BoosterCampaignID, "1111").Return(party).Times(3)
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, party).Return(nil).Times(2)
+
+		event := &payment.Event{
+			Provider: config.ProviderNowPayments, OrderID: "1111", Status: payment.StatusPartiallyPaid, PaidAmount: 20,
+		}
+		eng.handlePaymentEvent(event)
+
+		assert.Equal(t, rpstore.PartyReserved, party.Status)
+		assert.Equal(t, rpstore.PaymentUnderpaid, party.PaymentCase)
+		assert.Len(t, notifier.sent(), 2)
+		assert.Equal(t, "Underpaid payment⚠️", notifier.sent()[0].Title)
+		assert.Contains(t, notifier.sent()[1].Message, "$20.00")
+
+		// The same case is not reported again, and the status doesn't go back.
+		event.Status = payment.StatusConfirming
+		event.PaidAmount = 25
+		eng.handlePaymentEvent(event)
+		assert.Len(t, notifier.sent(), 2)
+		assert.Equal(t, string(payment.StatusPartiallyPaid), party.PaymentStatus)
+		assert.InDelta(t, 25, party.PaidAmount, 0)
+
+		// The same event changes nothing.
+		eng.handlePaymentEvent(event)
+	})
+
+	t.Run("overpaid payment", func(t *testing.T) {
+		eng, _, store, wallet, _, _, _ := setup(t)
+		notifier := newTestNotifier(eng)
+
+		saved := rpstore.TwitterParty{
+			TwitterName: "abcd", DiscordID: "user-1", DiscountCode: "1111", TotalPrice: 30,
+			AmountInPAC: 150, Status: rpstore.PartyReserved,
+		}
+		find := func(_, _ string) *rpstore.TwitterParty {
+			cpy := saved
+
+			return &cpy
+		}
+		store.EXPECT().FindTwitterPartyByDiscountCode(config.BoosterCampaignID, "1111").DoAndReturn(find)
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, "abcd").DoAndReturn(find).Times(2)
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, gomock.Any()).DoAndReturn(
+			func(_ string, party *rpstore.TwitterParty) error {
+				saved = *party
+
+				return nil
+			}).Times(2)
+		wallet.EXPECT().SignBondTransaction(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(
+			"tx-1", []byte("tx-1"), nil,
+		)
+		wallet.EXPECT().BroadcastTransaction(gomock.Any(), []byte("tx-1")).Return("tx-1", nil)
+		store.EXPECT().SaveTransaction(gomock.Any()).Return(nil)
+
+		event := finished("1111", "")
+		event.PaidAmount = 45.5
+		eng.handlePaymentEvent(event)
+
+		// The package is sent and the extra amount waits for a refund.
+		assert.Equal(t, rpstore.PartyFulfilled, saved.Status)
+		assert.Equal(t, rpstore.PaymentOverpaid, saved.PaymentCase)
+		assert.Len(t, notifier.sent(), 3)
+		assert.Equal(t, "Overpaid payment⚠️", notifier.sent()[0].Title)
+		assert.Equal(t, StatusSuccess, notifier.sent()[2].Status)
+	})
+
+	t.Run("full queue rejects the events", func(t *testing.T) {
+		eng, _, _, _, _, _, _ := setup(t)
+
+		for i := 0; i < paymentEventsQueueSize; i++ {
+			assert.NoError(t, eng.queuePaymentEvent(finished("1111", "42")))
+		}
+		assert.Error(t, eng.queuePaymentEvent(finished("1111", "42")))
+	})
+}
+
+func TestReconcileInvoices(t *testing.T) {
+	t.Run("invoices are reconciled", func(t *testing.T) {
+		eng, _, store, wallet, _, nowPayments, ctx := setup(t)
+		notifier := newTestNotifier(eng)
+
+		partial := &rpstore.TwitterParty{TwitterName: "partial", Status: rpstore.PartyReserved, PaymentStatus: "waiting"}
+		waiting := &rpstore.TwitterParty{TwitterName: "waiting", Status: rpstore.PartyReserved, PaymentStatus: "waiting"}
+		finished := &rpstore.TwitterParty{TwitterName: "finished", Status: rpstore.PartyReserved}
+		failing := &rpstore.TwitterParty{TwitterName: "failing", Status: rpstore.PartyReserved}
+		store.EXPECT().UnpaidTwitterParties(config.BoosterCampaignID).Return(
+			[]*rpstore.TwitterParty{partial, waiting, finished, failing},
+		)
+
+		nowPayments.EXPECT().InvoiceStatus(ctx, partial).Return(
+			&payment.InvoiceStatus{Status: payment.StatusPartiallyPaid}, nil)
+		nowPayments.EXPECT().InvoiceStatus(ctx, waiting).Return(
+			&payment.InvoiceStatus{Status: payment.StatusWaiting}, nil)
+		nowPayments.EXPECT().InvoiceStatus(ctx, finished).Return(
+			&payment.InvoiceStatus{Status: payment.StatusFinished}, nil)
+		nowPayments.EXPECT().InvoiceStatus(ctx, failing).Return(nil, errors.New("service unavailable"))
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, partial).Return(nil)
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, finished).Return(nil)
+
+		// The bond transaction of the paid party fails, so it stays unfulfilled.
+		paid := &rpstore.TwitterParty{
+			TwitterName: "finished", DiscountCode: "1111", ValPubKey: "public-key", ValAddr: "val-addr",
+			AmountInPAC: 150, PaymentFinished: true, Status: rpstore.PartyPaid,
+		}
+		legacy := &rpstore.TwitterParty{
+			TwitterName: "legacy", DiscountCode: "2222", TransactionID: "tx-1", Status: rpstore.PartyFulfilled,
+		}
+		store.EXPECT().TwitterParties(config.BoosterCampaignID).Return([]*rpstore.TwitterParty{paid, legacy}).Times(2)
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, "finished").Return(paid)
+		wallet.EXPECT().SignBondTransaction(gomock.Any(), "public-key", "val-addr", "Booster Program", utils.CoinToChange(150)).Return(
+			"", nil, errors.New("wallet is locked"),
+		)
+
+		nowPayments.EXPECT().PaymentLink(gomock.Any()).DoAndReturn(func(party *rpstore.TwitterParty) string {
+			return "https://pay/" + party.DiscountCode
+		}).Times(2)
+
+		report := eng.reconcileInvoices(ctx)
+		assert.Equal(t, 4, report.Checked)
+		assert.Equal(t, 2, report.Updated)
+		assert.Equal(t, 1, report.Failed)
+		assert.Zero(t, report.Fulfilled)
+		assert.Equal(t, rpstore.PartyPaid, finished.Status)
+		assert.Equal(t, string(payment.StatusPartiallyPaid), partial.PaymentStatus)
+
+		assert.Len(t, report.Mismatches, 2)
+		assert.Equal(t, "1111", report.Mismatches[0].DiscountCode)
+		assert.Equal(t, "https://pay/1111", report.Mismatches[0].PaymentLink)
+		assert.Contains(t, report.Mismatches[0].Reason, "no bond transaction is sent")
+		assert.Equal(t, "2222", report.Mismatches[1].DiscountCode)
+		assert.Contains(t, report.Mismatches[1].Reason, "the invoice is not paid")
+
+		assert.Len(t, notifier.sent(), 1)
+		assert.Equal(t, "Booster payout failed🚨", notifier.sent()[0].Title)
+
+		// The mismatches are alerted once.
+		eng.alertMismatches(report)
+		eng.alertMismatches(report)
+		assert.Len(t, notifier.sent(), 3)
+
+		eng.alertMismatches(&PaymentReport{Mismatches: report.Mismatches[:1]})
+		eng.alertMismatches(report)
+		assert.Len(t, notifier.sent(), 4)
+	})
+
+	t.Run("late payments of the expired parties are polled", func(t *testing.T) {
+		eng, _, store, _, _, nowPayments, ctx := setup(t)
+		eng.latePaymentWindow = time.Hour
+		notifier := newTestNotifier(eng)
+
+		late := &rpstore.TwitterParty{
+			TwitterName: "late", DiscountCode: "1111", InvoiceID: "41", TotalPrice: 30,
+			Status: rpstore.PartyExpired, ExpiredAt: time.Now().Add(-10 * time.Minute).Unix(),
+		}
+		old := &rpstore.TwitterParty{
+			TwitterName: "old", DiscountCode: "2222", InvoiceID: "42", TotalPrice: 30,
+			Status: rpstore.PartyExpired, ExpiredAt: time.Now().Add(-2 * time.Hour).Unix(),
+		}
+		store.EXPECT().UnpaidTwitterParties(config.BoosterCampaignID).Return([]*rpstore.TwitterParty{})
+		store.EXPECT().TwitterParties(config.BoosterCampaignID).Return([]*rpstore.TwitterParty{late, old}).Times(2)
+		nowPayments.EXPECT().InvoiceStatus(ctx, late).Return(
+			&payment.InvoiceStatus{Status: payment.StatusFinished, PaidAmount: 30}, nil)
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, late).Return(nil)
+		nowPayments.EXPECT().PaymentLink(gomock.Any()).Return("https://pay/1111").AnyTimes()
+
+		report := eng.reconcileInvoices(ctx)
+		assert.Equal(t, 1, report.Checked)
+		assert.Equal(t, 1, report.Updated)
+		assert.Equal(t, rpstore.PartyExpired, late.Status)
+		assert.Equal(t, rpstore.PaymentLate, late.PaymentCase)
+		assert.Len(t, report.Cases, 1)
+		assert.Equal(t, "1111", report.Cases[0].DiscountCode)
+
+		// The admins and the user are told about the late payment.
+		assert.Len(t, notifier.sent(), 2)
+		assert.Equal(t, "Late payment⚠️", notifier.sent()[0].Title)
+	})
+
+	t.Run("failed payouts are retried with a backoff", func(t *testing.T) {
+		eng, _, store, wallet, _, nowPayments, ctx := setup(t)
+		notifier := newTestNotifier(eng)
+
+		paid := &rpstore.TwitterParty{
+			TwitterName: "finished", DiscountCode: "1111", ValPubKey: "public-key", ValAddr: "val-addr",
+			AmountInPAC: 150, PaymentFinished: true, Status: rpstore.PartyPaid,
+		}
+		store.EXPECT().UnpaidTwitterParties(config.BoosterCampaignID).Return([]*rpstore.TwitterParty{}).Times(3)
+		store.EXPECT().TwitterParties(config.BoosterCampaignID).Return([]*rpstore.TwitterParty{paid}).Times(6)
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, "finished").Return(paid).Times(2)
+		wallet.EXPECT().SignBondTransaction(gomock.Any(), "public-key", "val-addr", "Booster Program", utils.CoinToChange(150)).Return(
+			"", nil, errors.New("wallet is locked"),
+		).Times(2)
+		nowPayments.EXPECT().PaymentLink(paid).Return("https://pay/1111").AnyTimes()
+
+		eng.reconcileInvoices(ctx)
+		assert.Len(t, notifier.sent(), 1)
+		assert.Equal(t, "Booster payout failed🚨", notifier.sent()[0].Title)
+
+		// The payout is not retried before its retry time.
+		eng.reconcileInvoices(ctx)
+		assert.Len(t, notifier.sent(), 1)
+
+		// The payout fails again after its retry time, but the admins are not alerted again.
+		key := payoutFailureKey(config.BoosterCampaignID, "finished")
+		eng.payoutFailures[key].retryAt = time.Now()
+		eng.reconcileInvoices(ctx)
+		assert.Len(t, notifier.sent(), 1)
+		assert.Equal(t, 2, eng.payoutFailures[key].attempts)
+		assert.WithinDuration(t, time.Now().Add(2*minPayoutRetryDelay), eng.payoutFailures[key].retryAt, time.Minute)
+
+		eng.clearPayoutFailure(key)
+		assert.True(t, eng.isPayoutRetryDue(config.BoosterCampaignID, "finished", time.Now()))
+	})
+
+	t.Run("payment report command", func(t *testing.T) {
+		eng, _, store, _, _, nowPayments, ctx := setup(t)
+
+		underpaid := &rpstore.TwitterParty{
+			TwitterName: "abcd", DiscountCode: "1111", TotalPrice: 30, PaidAmount: 10,
+			Status: rpstore.PartyExpired, PaymentCase: rpstore.PaymentLate,
+		}
+		store.EXPECT().UnpaidTwitterParties(config.BoosterCampaignID).Return([]*rpstore.TwitterParty{})
+		store.EXPECT().TwitterParties(config.BoosterCampaignID).Return([]*rpstore.TwitterParty{underpaid}).Times(2)
+		nowPayments.EXPECT().PaymentLink(underpaid).Return("https://pay/1111")
+
+		res, err := eng.Run(ctx, NewCLICaller(), "payment-report")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusSuccess, res.Status)
+		assert.Zero(t, res.Data.(*PaymentReport).Checked)
+		assert.Len(t, res.Data.(*PaymentReport).Cases, 1)
+		assert.Equal(t, "$10.00 is paid after the discount code is expired", res.Data.(*PaymentReport).Cases[0].Reason)
+
+		_, err = eng.Run(ctx, anyone, "payment-report")
+		assert.Error(t, err)
+	})
+}
+
+func TestWebhooks(t *testing.T) {
+	newServer := func(addr string) *webhook.Server {
+		return webhook.NewServer(&webhook.Config{
+			ListenAddr:      addr,
+			MaxBodySize:     1024,
+			ShutdownTimeout: time.Second,
+		}, log.NewSubLogger("webhook"))
+	}
+
+	t.Run("provider webhooks are served", func(t *testing.T) {
+		eng, _, _, _, _, nowPayments, _ := setup(t)
+		eng.webhooks = newServer("127.0.0.1:0")
+		eng.webhookListenAddr = "127.0.0.1:0"
+
+		nowPayments.EXPECT().WebhookHandler().Return(
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			}))
+
+		eng.startWebhooks()
+		defer eng.webhooks.Stop()
+
+		resp, err := http.Post(fmt.Sprintf("http://%s/%s", eng.webhooks.Addr(), config.ProviderNowPayments),
+			"application/json", http.NoBody)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	})
+
+	t.Run("port is taken", func(t *testing.T) {
+		eng, _, _, _, _, nowPayments, _ := setup(t)
+		notifier := newTestNotifier(eng)
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		eng.webhooks = newServer(listener.Addr().String())
+		eng.webhookListenAddr = listener.Addr().String()
+		nowPayments.EXPECT().WebhookHandler().Return(nil)
+
+		eng.startWebhooks()
+		assert.Len(t, notifier.sent(), 1)
+		assert.Equal(t, "Webhook server failed🚨", notifier.sent()[0].Title)
+	})
+
+	t.Run("no listen address", func(t *testing.T) {
+		eng, _, _, _, _, nowPayments, _ := setup(t)
+		nowPayments.EXPECT().WebhookHandler().Return(nil)
+
+		eng.startWebhooks()
+		assert.Empty(t, eng.webhooks.Addr())
+		assert.NoError(t, eng.webhooks.Stop())
+	})
+}
+
+func TestApplyInvoiceStatus(t *testing.T) {
+	party := &rpstore.TwitterParty{TotalPrice: 30, Status: rpstore.PartyReserved}
+
+	// The reconciler sees the sum of the two payments of the invoice.
+	assert.True(t, applyInvoiceStatus(party, &payment.InvoiceStatus{Status: payment.StatusPartiallyPaid, PaidAmount: 20}))
+	assert.InDelta(t, 20, party.PaidAmount, 0)
+
+	// The late event of the first payment has its own amount only.
+	assert.False(t, applyInvoiceStatus(party, &payment.InvoiceStatus{Status: payment.StatusWaiting, PaidAmount: 5}))
+	assert.InDelta(t, 20, party.PaidAmount, 0)
+	assert.Equal(t, string(payment.StatusPartiallyPaid), party.PaymentStatus)
+	assert.Equal(t, rpstore.PaymentUnderpaid, party.PaymentCase)
+
+	assert.True(t, applyInvoiceStatus(party, &payment.InvoiceStatus{Status: payment.StatusFinished, PaidAmount: 30}))
+	assert.InDelta(t, 30, party.PaidAmount, 0)
+	assert.Equal(t, rpstore.PartyPaid, party.Status)
+	assert.Empty(t, party.PaymentCase)
+
+	t.Run("two equal partial payments", func(t *testing.T) {
+		party := &rpstore.TwitterParty{TotalPrice: 30, Status: rpstore.PartyReserved}
+		first := &payment.Event{PaymentID: "1", Status: payment.StatusPartiallyPaid, PaidAmount: 15}
+		second := &payment.Event{PaymentID: "2", Status: payment.StatusFinished, PaidAmount: 15}
+
+		assert.True(t, applyInvoiceStatus(party, first.InvoiceStatus()))
+		assert.InDelta(t, 15, party.PaidAmount, 0)
+
+		// The same event is sent again.
+		assert.False(t, applyInvoiceStatus(party, first.InvoiceStatus()))
+		assert.InDelta(t, 15, party.PaidAmount, 0)
+
+		assert.True(t, applyInvoiceStatus(party, second.InvoiceStatus()))
+		assert.InDelta(t, 30, party.PaidAmount, 0)
+		assert.Equal(t, rpstore.PartyPaid, party.Status)
+
+		// The polled status has both of the payments, they are not counted again.
+		applyInvoiceStatus(party, &payment.InvoiceStatus{
+			Status: payment.StatusFinished, PaidAmount: 30, Payments: map[string]float64{"1": 15, "2": 15},
+		})
+		assert.InDelta(t, 30, party.PaidAmount, 0)
+	})
+}
+
+func TestResolvePayment(t *testing.T) {
+	// mockParty keeps the party in memory, like the store.
+	mockParty := func(store *rpstore.MockIStore, party rpstore.TwitterParty) *rpstore.TwitterParty {
+		saved := &party
+		store.EXPECT().FindTwitterParty(config.BoosterCampaignID, party.TwitterName).DoAndReturn(
+			func(_, _ string) *rpstore.TwitterParty {
+				cpy := *saved
+
+				return &cpy
+			}).AnyTimes()
+		store.EXPECT().SaveTwitterParty(config.BoosterCampaignID, gomock.Any()).DoAndReturn(
+			func(_ string, party *rpstore.TwitterParty) error {
+				*saved = *party
+
+				return nil
+			}).AnyTimes()
+
+		return saved
+	}
+
+	underpaid := rpstore.TwitterParty{
+		TwitterName: "abcd", DiscordID: "user-1", DiscountCode: "1111", InvoiceID: "42",
+		ValPubKey: "public-key", ValAddr: "val-addr", AmountInPAC: 150, TotalPrice: 30,
+		Status: rpstore.PartyReserved, PaymentStatus: "partially_paid",
+		PaidAmount: 15, PaymentCase: rpstore.PaymentUnderpaid,
+	}
+	refund := &payment.Refund{Address: "refund-addr", Currency: "usdtbsc"}
+
+	t.Run("top up", func(t *testing.T) {
+		eng, _, store, _, _, nowPayments, ctx := setup(t)
+		notifier := newTestNotifier(eng)
+		saved := mockParty(store, underpaid)
+
+		nowPayments.EXPECT().CreateInvoice(ctx, gomock.Any()).DoAndReturn(
+			func(_ context.Context, party *rpstore.TwitterParty) error {
+				assert.Equal(t, 15, party.TotalPrice)
+				party.InvoiceID = "43"
+
+				return nil
+			})
+		nowPayments.EXPECT().PaymentLink(gomock.Any()).Return("https://pay/43")
+
+		party, err := eng.ResolvePayment(ctx, config.BoosterCampaignID, "abcd", rpstore.ResolutionTopUp, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "43", party.InvoiceID)
+		assert.Equal(t, "43", saved.InvoiceID)
+		assert.InDelta(t, 15, saved.PaidBefore, 0)
+		assert.Empty(t, saved.PaymentCase)
+		assert.Len(t, saved.Adjustments, 1)
+		assert.Equal(t, rpstore.ResolutionTopUp, saved.Adjustments[0].Resolution)
+		assert.Equal(t, rpstore.PaymentUnderpaid, saved.Adjustments[0].Case)
+		assert.InDelta(t, 15, saved.Adjustments[0].Amount, 0)
+
+		assert.Len(t, notifier.sent(), 1)
+		assert.Equal(t, "user-1", notifier.sent()[0].UserID)
+		assert.Contains(t, notifier.sent()[0].Message, "https://pay/43")
+
+		// Paying the top-up invoice pays the party.
+		assert.True(t, applyInvoiceStatus(saved, &payment.InvoiceStatus{Status: payment.StatusFinished, PaidAmount: 15}))
+		assert.InDelta(t, 30, saved.PaidAmount, 0)
+		assert.Equal(t, rpstore.PartyPaid, saved.Status)
+		assert.Empty(t, saved.PaymentCase)
+	})
+
+	t.Run("convert underpaid payment", func(t *testing.T) {
+		eng, _, store, wallet, _, _, ctx := setup(t)
+		saved := mockParty(store, underpaid)
+
+		wallet.EXPECT().SignBondTransaction(gomock.Any(), "public-key", "val-addr", "Booster Program", utils.CoinToChange(75)).Return(
+			"tx-1", []byte("tx-1"), nil,
+		)
+		wallet.EXPECT().BroadcastTransaction(gomock.Any(), []byte("tx-1")).Return("tx-1", nil)
+		store.EXPECT().SaveTransaction(gomock.Any()).Return(nil)
+
+		party, err := eng.ResolvePayment(ctx, config.BoosterCampaignID, "abcd", rpstore.ResolutionConvert, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "tx-1", party.TransactionID)
+		assert.Equal(t, rpstore.PartyFulfilled, saved.Status)
+		assert.Equal(t, int64(75), saved.AmountInPAC)
+		assert.Equal(t, int64(150), saved.Adjustments[0].AmountInPAC)
+	})
+
+	t.Run("late payment can't be converted if the campaign is full", func(t *testing.T) {
+		eng, _, store, _, _, _, ctx := setup(t)
+		late := underpaid
+		late.Status = rpstore.PartyExpired
+		late.PaymentCase = rpstore.PaymentLate
+		saved := mockParty(store, late)
+
+		store.EXPECT().ReservePackage(config.BoosterCampaignID, gomock.Any(), gomock.Any()).Return(0, rpstore.ErrCampaignFull)
+
+		_, err := eng.ResolvePayment(ctx, config.BoosterCampaignID, "abcd", rpstore.ResolutionConvert, nil)
+		assert.ErrorIs(t, err, rpstore.ErrCampaignFull)
+		assert.Equal(t, rpstore.PaymentLate, saved.PaymentCase)
+	})
+
+	t.Run("refund underpaid payment", func(t *testing.T) {
+		eng, _, store, _, _, nowPayments, ctx := setup(t)
+		saved := mockParty(store, underpaid)
+
+		_, err := eng.ResolvePayment(ctx, config.BoosterCampaignID, "abcd", rpstore.ResolutionRefund, nil)
+		assert.EqualError(t, err, "the refund address and currency are required")
+
+		nowPayments.EXPECT().Refund(ctx, gomock.Any(),
+			&payment.Refund{ID: "refund-1111-1", Amount: 15, Address: "refund-addr", Currency: "usdtbsc"}).Return("refund-1", nil)
+
+		_, err = eng.ResolvePayment(ctx, config.BoosterCampaignID, "abcd", rpstore.ResolutionRefund, refund)
+		assert.NoError(t, err)
+		assert.Equal(t, rpstore.PartyExpired, saved.Status)
+		assert.InDelta(t, 15, saved.RefundedAmount, 0)
+		assert.Equal(t, "refund-1", saved.Adjustments[0].RefundID)
+		assert.False(t, saved.Adjustments[0].Pending)
+
+		store.EXPECT().TwitterParties(config.BoosterCampaignID).Return([]*rpstore.TwitterParty{saved})
+		_, err = eng.ResolvePayment(ctx, config.BoosterCampaignID, "abcd", rpstore.ResolutionRefund, refund)
+		assert.EqualError(t, err, "the payment of `abcd` has no case to resolve")
+	})
+
+	t.Run("refund overpaid payment", func(t *testing.T) {
+		eng, _, store, _, _, nowPayments, ctx := setup(t)
+		overpaid := underpaid
+		overpaid.Status = rpstore.PartyFulfilled
+		overpaid.TransactionID = "tx-1"
+		overpaid.PaidAmount = 45.5
+		overpaid.PaymentCase = rpstore.PaymentOverpaid
+		saved := mockParty(store, overpaid)
+
+		_, err := eng.ResolvePayment(ctx, config.BoosterCampaignID, "abcd", rpstore.ResolutionConvert, nil)
+		assert.EqualError(t, err, "the overpaid payments can only be refunded")
+		_, err = eng.ResolvePayment(ctx, config.BoosterCampaignID, "abcd", rpstore.ResolutionTopUp, nil)
+		assert.Error(t, err)
+
+		nowPayments.EXPECT().Refund(gomock.Any(), gomock.Any(),
+			&payment.Refund{ID: "refund-1111-1", Amount: 15.5, Address: "refund-addr", Currency: "usdtbsc"}).Return("refund-1", nil)
+
+		res, err := eng.Run(ctx, NewCLICaller(),
+			"payment-resolve abcd refund --refund-address=refund-addr --refund-currency=usdtbsc")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusSuccess, res.Status)
+		assert.Equal(t, rpstore.PartyFulfilled, saved.Status)
+		assert.InDelta(t, 15.5, saved.RefundedAmount, 0)
+		assert.Empty(t, saved.PaymentCase)
+	})
+
+	t.Run("failed refund is kept pending and sent again with the same key", func(t *testing.T) {
+		eng, _, store, _, _, nowPayments, ctx := setup(t)
+		saved := mockParty(store, underpaid)
+
+		pendingRefund := &payment.Refund{ID: "refund-1111-1", Amount: 15, Address: "refund-addr", Currency: "usdtbsc"}
+		nowPayments.EXPECT().Refund(ctx, gomock.Any(), pendingRefund).Return("", errors.New("timeout"))
+
+		_, err := eng.ResolvePayment(ctx, config.BoosterCampaignID, "abcd", rpstore.ResolutionRefund, refund)
+		assert.ErrorContains(t, err, "the refund is pending")
+		assert.Equal(t, rpstore.PaymentUnderpaid, saved.PaymentCase)
+		assert.Len(t, saved.Adjustments, 1)
+		assert.True(t, saved.Adjustments[0].Pending)
+		assert.Zero(t, saved.RefundedAmount)
+
+		_, err = eng.ResolvePayment(ctx, config.BoosterCampaignID, "abcd", rpstore.ResolutionConvert, nil)
+		assert.EqualError(t, err, "a refund of $15.00 is pending for `abcd`, it can only be resolved by refund")
+
+		// The same key can't be sent to another destination.
+		_, err = eng.ResolvePayment(ctx, config.BoosterCampaignID, "abcd", rpstore.ResolutionRefund,
+			&payment.Refund{Address: "another-addr", Currency: "usdtbsc"})
+		assert.EqualError(t, err,
+			"the pending refund is sent to `refund-addr` in usdtbsc, it can only be retried to the same address")
+		assert.True(t, saved.Adjustments[0].Pending)
+
+		// The retry is sent to the stored destination, the refund arguments can be omitted.
+		nowPayments.EXPECT().Refund(ctx, gomock.Any(), pendingRefund).Return("refund-1", nil)
+
+		_, err = eng.ResolvePayment(ctx, config.BoosterCampaignID, "abcd", rpstore.ResolutionRefund, &payment.Refund{})
+		assert.NoError(t, err)
+		assert.Empty(t, saved.PaymentCase)
+		assert.Len(t, saved.Adjustments, 1)
+		assert.False(t, saved.Adjustments[0].Pending)
+		assert.Equal(t, "refund-1", saved.Adjustments[0].RefundID)
+		assert.InDelta(t, 15, saved.RefundedAmount, 0)
+	})
+
+	t.Run("only admins resolve payments", func(t *testing.T) {
+		eng, _, _, _, _, _, ctx := setup(t)
+
+		_, err := eng.Run(ctx, anyone, "payment-resolve abcd refund")
+		assert.Error(t, err)
+	})
 }
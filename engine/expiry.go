@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kehiy/RoboPac/config"
+	"github.com/kehiy/RoboPac/store"
+)
+
+func (be *BotEngine) expireParties() {
+	ticker := time.NewTicker(be.expiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-be.ctx.Done():
+			return
+
+		case <-ticker.C:
+			be.checkExpiredParties(be.ctx)
+		}
+	}
+}
+
+// checkExpiredParties expires the unpaid discount codes after the payment expiry of their campaign,
+// so their packages are released. The payment is checked once more before expiring,
+// the parties that have paid in time are kept. The users are told through the frontend they came from.
+func (be *BotEngine) checkExpiredParties(ctx context.Context) {
+	now := time.Now()
+	for _, campaign := range be.campaigns {
+		for _, unpaid := range be.store.UnpaidTwitterParties(campaign.ID) {
+			if ctx.Err() != nil {
+				return
+			}
+
+			if now.Before(campaign.PaymentExpiry(unpaid.CreatedAt)) {
+				continue
+			}
+
+			be.expireParty(ctx, campaign, unpaid, now)
+		}
+	}
+}
+
+// expireParty expires the party, unless it is paid. The parties that have paid a part of the total price
+// are not expired either, their packages are kept until the admins resolve their payments.
+// The store doesn't let a paid party be expired, so it is safe to check the payment without a lock.
+func (be *BotEngine) expireParty(ctx context.Context, campaign *config.Campaign, party *store.TwitterParty, now time.Time) {
+	previous := *party
+	if _, err := be.checkInvoice(ctx, campaign, party); err != nil {
+		be.logger.Error("unable to check the payment of the expired party",
+			"error", err, "campaign", campaign.ID, "twitterName", party.TwitterName)
+
+		return
+	}
+
+	if !party.PaymentFinished && party.PaidAmount == 0 {
+		party.Status = store.PartyExpired
+		party.ExpiredAt = now.Unix()
+	}
+
+	if err := be.savePaymentStatus(NewSystemCaller(jobExpiry), campaign, party, &previous); err != nil {
+		be.logger.Error("unable to save the expired party",
+			"error", err, "campaign", campaign.ID, "twitterName", party.TwitterName)
+
+		return
+	}
+
+	if party.IsExpired() {
+		be.logger.Info("discount code expired", "campaign", campaign.ID,
+			"twitterName", party.TwitterName, "discountCode", party.DiscountCode)
+		be.notifyExpiredParty(campaign.Title, party)
+	}
+}
+
+func (be *BotEngine) notifyExpiredParty(title string, party *store.TwitterParty) {
+	be.notifyUser(partyFrontend(party), party.DiscordID, StatusWarning, title, fmt.Sprintf(
+		"Your discount code `%s` for the Twitter account `%s` is expired and its package is released."+
+			" Payments for this code are not accepted anymore, you can register again to get a new one.",
+		party.DiscountCode, party.TwitterName))
+}
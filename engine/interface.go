@@ -1,25 +1,37 @@
 package engine
 
-import "github.com/kehiy/RoboPac/store"
+import (
+	"context"
+
+	"github.com/kehiy/RoboPac/client"
+	"github.com/kehiy/RoboPac/payment"
+	"github.com/kehiy/RoboPac/store"
+)
 
 type IEngine interface {
-	NetworkHealth() (*NetHealthResponse, error)
-	NetworkStatus() (*NetStatus, error)
-	NodeInfo(addr string) (*NodeInfo, error)
-	RewardCalculate(int64, string) (int64, string, int64, error)
+	NetworkHealth(ctx context.Context) (*NetHealthResponse, error)
+	NetworkStatus(ctx context.Context) (*NetStatus, error)
+	NetworkConsistency(ctx context.Context) (*client.ConsistencyReport, error)
+	NodeInfo(ctx context.Context, addr string) (*NodeInfo, error)
+	RewardCalculate(context.Context, int64, string) (int64, string, int64, error)
 
-	ClaimerInfo(discordID string) (*store.Claimer, error)
-	Claim(discordID string, testnetAddr string, mainnetAddr string) (string, error)
-	ClaimStatus() *store.ClaimStatus
+	ClaimerInfo(ctx context.Context, discordID string) (*store.Claimer, error)
+	Claim(ctx context.Context, discordID string, testnetAddr string, mainnetAddr string) (string, error)
+	ClaimStatus(ctx context.Context) *store.ClaimStatus
 
-	BotWallet() (string, int64)
+	BotWallet(ctx context.Context) (string, int64)
 
-	BoosterWhitelist(string, string) error
-	BoosterClaim(string) (*store.TwitterParty, error)
-	BoosterPayment(string, string, string) (*store.TwitterParty, error)
-	BoosterStatus() *store.BoosterStatus
+	CampaignWhitelist(ctx context.Context, campaignID, twitterName, authorizedDiscordID string) error
+	CampaignClaim(ctx context.Context, campaignID, twitterName string) (*store.TwitterParty, error)
+	CampaignPayment(ctx context.Context, campaignID, discordID, twitterName, valAddr string) (*store.TwitterParty, error)
+	CampaignStatus(ctx context.Context, campaignID string) (*store.CampaignStatus, error)
+	ResolvePayment(ctx context.Context, campaignID, twitterName string, resolution store.Resolution,
+		refund *payment.Refund) (*store.TwitterParty, error)
 
-	Run(input string) (string, error)
+	Commands() []*Command
+	Run(ctx context.Context, caller *Caller, input string) (*Result, error)
+	Execute(ctx context.Context, caller *Caller, in *Input) (*Result, error)
+	RegisterNotifier(frontend string, n Notifier)
 
 	Stop()
 	Start()
@@ -0,0 +1,289 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/kehiy/RoboPac/config"
+	"github.com/kehiy/RoboPac/payment"
+	"github.com/kehiy/RoboPac/store"
+)
+
+// invoicePaymentID is the payment ID of the sum of the payments of an invoice, when the provider has no payment IDs.
+const invoicePaymentID = "invoice"
+
+// PaymentMismatch is a party that its payment and its bond transaction don't match.
+type PaymentMismatch struct {
+	Campaign     string `json:"campaign"`
+	TwitterName  string `json:"twitter_name"`
+	DiscountCode string `json:"discount_code"`
+	InvoiceID    string `json:"invoice_id"`
+	PaymentLink  string `json:"payment_link"`
+	TxID         string `json:"tx_id,omitempty"`
+	Reason       string `json:"reason"`
+}
+
+func (m *PaymentMismatch) key() string {
+	return fmt.Sprintf("%s/%s/%s", m.Campaign, m.DiscountCode, m.Reason)
+}
+
+// PaymentReport is the result of reconciling the invoices.
+type PaymentReport struct {
+	CheckedAt time.Time `json:"checked_at"`
+	// Checked is the number of the invoices that are checked.
+	Checked int `json:"checked"`
+	// Updated is the number of the parties that their payment status is changed.
+	Updated int `json:"updated"`
+	// Fulfilled is the number of the paid parties that their bond transaction is sent.
+	Fulfilled int `json:"fulfilled"`
+	// Failed is the number of the invoices that could not be checked.
+	Failed     int                `json:"failed"`
+	Mismatches []*PaymentMismatch `json:"mismatches"`
+	// Cases are the payments that don't match their total price and wait for the admins to resolve them.
+	Cases []*PaymentMismatch `json:"cases"`
+}
+
+func (be *BotEngine) reconcileInvoicesLoop() {
+	ticker := time.NewTicker(be.paymentReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-be.ctx.Done():
+			return
+
+		case <-ticker.C:
+			report := be.reconcileInvoices(be.ctx)
+			be.alertMismatches(report)
+		}
+	}
+}
+
+// reconcileInvoices checks the invoices of the unpaid parties, in case their IPN callback is lost,
+// and fulfils the paid parties, in case their users never come back to claim them.
+// The invoices of the expired parties are checked for the late payments too, during the late payment window.
+// At the end the parties are checked for a paid invoice without a bond transaction,
+// or a bond transaction without a paid invoice.
+func (be *BotEngine) reconcileInvoices(ctx context.Context) *PaymentReport {
+	report := &PaymentReport{
+		CheckedAt:  time.Now(),
+		Mismatches: []*PaymentMismatch{},
+		Cases:      []*PaymentMismatch{},
+	}
+
+	caller := NewSystemCaller(jobInvoiceReconciler)
+	for _, campaign := range be.campaigns {
+		for _, party := range be.store.UnpaidTwitterParties(campaign.ID) {
+			if ctx.Err() != nil {
+				return report
+			}
+
+			be.reconcileInvoice(ctx, caller, campaign, party, report)
+		}
+
+		// The failed bond transactions are retried with a backoff, not on every run.
+		for _, party := range be.store.TwitterParties(campaign.ID) {
+			if ctx.Err() != nil {
+				return report
+			}
+
+			switch {
+			case be.isLatePaymentOpen(party, report.CheckedAt):
+				be.reconcileInvoice(ctx, caller, campaign, party, report)
+
+			case party.Status == store.PartyPaid && be.isPayoutRetryDue(campaign.ID, party.TwitterName, report.CheckedAt):
+				if be.fulfilPaidParty(ctx, caller, campaign, party) {
+					report.Fulfilled++
+				}
+			}
+		}
+
+		for _, party := range be.store.TwitterParties(campaign.ID) {
+			if reason := paymentMismatch(party); reason != "" {
+				report.Mismatches = append(report.Mismatches, be.newPaymentMismatch(campaign, party, reason))
+			}
+
+			if party.PaymentCase != "" {
+				report.Cases = append(report.Cases, be.newPaymentMismatch(campaign, party, describePaymentCase(party)))
+			}
+		}
+	}
+
+	return report
+}
+
+func (be *BotEngine) newPaymentMismatch(campaign *config.Campaign, party *store.TwitterParty,
+	reason string,
+) *PaymentMismatch {
+	return &PaymentMismatch{
+		Campaign:     campaign.ID,
+		TwitterName:  party.TwitterName,
+		DiscountCode: party.DiscountCode,
+		InvoiceID:    party.InvoiceID,
+		PaymentLink:  be.paymentLink(campaign.ID, party),
+		TxID:         party.TransactionID,
+		Reason:       reason,
+	}
+}
+
+// reconcileInvoice moves the unpaid party to the current status of its invoice.
+func (be *BotEngine) reconcileInvoice(ctx context.Context, caller *Caller, campaign *config.Campaign,
+	party *store.TwitterParty, report *PaymentReport,
+) {
+	report.Checked++
+
+	previous := *party
+	changed, err := be.checkInvoice(ctx, campaign, party)
+	if err != nil {
+		be.logger.Error("unable to check the invoice", "error", err,
+			"campaign", campaign.ID, "twitterName", party.TwitterName, "invoiceID", party.InvoiceID)
+		report.Failed++
+
+		return
+	}
+
+	if !changed {
+		return
+	}
+
+	// The party may be paid or expired meanwhile, then it is reconciled on the next run.
+	if err := be.savePaymentStatus(caller, campaign, party, &previous); err != nil {
+		be.logger.Warn("unable to save the payment status of the party", "error", err,
+			"campaign", campaign.ID, "twitterName", party.TwitterName)
+
+		return
+	}
+
+	be.logger.Info("payment status of the party is changed", "campaign", campaign.ID,
+		"twitterName", party.TwitterName, "from", previous.PaymentStatus, "to", party.PaymentStatus)
+	report.Updated++
+}
+
+// isLatePaymentOpen returns true if the party is expired without any payment, and its invoice may still be paid
+// in the late payment window.
+func (be *BotEngine) isLatePaymentOpen(party *store.TwitterParty, now time.Time) bool {
+	if party.Status != store.PartyExpired || party.InvoiceID == "" || party.PaidAmount > 0 {
+		return false
+	}
+
+	return now.Before(time.Unix(party.ExpiredAt, 0).Add(be.latePaymentWindow))
+}
+
+// checkInvoice queries the invoice of the party from the provider of the campaign and applies its status
+// on the party. It returns true if the party is changed and it should be saved.
+func (be *BotEngine) checkInvoice(ctx context.Context, campaign *config.Campaign,
+	party *store.TwitterParty,
+) (bool, error) {
+	provider, err := be.provider(campaign)
+	if err != nil {
+		return false, err
+	}
+
+	status, err := provider.InvoiceStatus(ctx, party)
+	if err != nil {
+		return false, err
+	}
+
+	return applyInvoiceStatus(party, status), nil
+}
+
+// applyInvoiceStatus applies the status of the invoice on the party.
+// A finished invoice makes the party paid, unless it is expired. If the provider has sent the bond transaction
+// itself, its ID is kept, so the party is fulfilled without a payout.
+// On each new payment, the paid amount and the payment case of the party are updated.
+// A payment event carries the amount of its own payment, while the polled status has all the payments
+// of the invoice, so the payments are added up by their ID, and each one is counted once.
+// Once the invoice is paid partly, the paid amount and the payment status never go back.
+func applyInvoiceStatus(party *store.TwitterParty, status *payment.InvoiceStatus) bool {
+	paidAmount := max(party.PaidAmount, party.PaidBefore+addPayments(party, status))
+	newPayment := paidAmount != party.PaidAmount ||
+		(status.IsFinished() && party.PaymentStatus != string(payment.StatusFinished))
+
+	paymentStatus := status.Status
+	if party.PaidAmount > party.PaidBefore && payment.Status(party.PaymentStatus).IsAfter(paymentStatus) {
+		paymentStatus = payment.Status(party.PaymentStatus)
+	}
+
+	changed := party.PaymentStatus != string(paymentStatus)
+	party.PaymentStatus = string(paymentStatus)
+
+	if status.IsFinished() && !party.IsExpired() {
+		changed = true
+		party.PaymentFinished = true
+		party.Status = store.PartyPaid
+		if status.TxID != "" {
+			party.TransactionID = status.TxID
+		}
+	}
+
+	if newPayment {
+		changed = true
+		party.PaidAmount = paidAmount
+		party.PaymentCase = paymentCaseOf(party)
+	}
+
+	return changed
+}
+
+// addPayments records the new payments of the status on the party, and returns the sum of the payments
+// of the current invoice. The status of a provider without payment IDs has the sum of the payments of the invoice.
+// A payment that is reported again only counts if its amount is grown, e.g. it is confirmed more.
+func addPayments(party *store.TwitterParty, status *payment.InvoiceStatus) float64 {
+	payments := status.Payments
+	if len(payments) == 0 && status.PaidAmount > 0 {
+		payments = map[string]float64{invoicePaymentID: status.PaidAmount}
+	}
+
+	// The map is copied, so the copies of the party keep their own payments.
+	party.Payments = maps.Clone(party.Payments)
+	for id, amount := range payments {
+		if amount > party.Payments[id] {
+			if party.Payments == nil {
+				party.Payments = make(map[string]float64)
+			}
+			party.Payments[id] = amount
+		}
+	}
+
+	total := 0.0
+	for _, amount := range party.Payments {
+		total += amount
+	}
+
+	return total
+}
+
+// paymentMismatch returns the reason that the payment and the bond transaction of the party don't match,
+// or an empty string if they match.
+func paymentMismatch(party *store.TwitterParty) string {
+	switch {
+	case party.PaymentFinished && party.TransactionID == "":
+		return "the invoice is paid, but no bond transaction is sent"
+
+	case !party.PaymentFinished && party.TransactionID != "":
+		return "the bond transaction is sent, but the invoice is not paid"
+
+	default:
+		return ""
+	}
+}
+
+// alertMismatches alerts the admins about the mismatches that are not reported in the previous run.
+func (be *BotEngine) alertMismatches(report *PaymentReport) {
+	reported := make(map[string]bool, len(report.Mismatches))
+	for _, mismatch := range report.Mismatches {
+		key := mismatch.key()
+		reported[key] = true
+		if be.reportedMismatches[key] {
+			continue
+		}
+
+		be.alert(StatusWarning, "Payment mismatch⚠️", fmt.Sprintf(
+			"The Twitter account `%s` in the campaign `%s` with the discount code `%s`: %s.",
+			mismatch.TwitterName, mismatch.Campaign, mismatch.DiscountCode, mismatch.Reason))
+	}
+
+	be.reportedMismatches = reported
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kehiy/RoboPac/journal"
+	"github.com/kehiy/RoboPac/utils"
+)
+
+// ErrLimitReached is returned when a payout is blocked by the spending limits.
+var ErrLimitReached = errors.New("payout limit is reached, please try again later")
+
+// checkLimits checks the payout against the spending limits of the bot wallet.
+// The spent amounts are counted from the payout journal, so they survive restarts.
+// The admins are alerted when a limit is hit.
+func (be *BotEngine) checkLimits(entry *journal.Entry) error {
+	now := time.Now()
+	checks := []struct {
+		name  string
+		limit int64
+		spent func() int64
+	}{
+		{"per transaction", be.limits.MaxPerTx, func() int64 { return 0 }},
+		{"hourly", be.limits.MaxPerHour, func() int64 { return be.journal.Spent(now.Add(-time.Hour), "") }},
+		{"daily", be.limits.MaxPerDay, func() int64 { return be.journal.Spent(now.Add(-24*time.Hour), "") }},
+		{"per user", be.limits.MaxPerUser, func() int64 {
+			if entry.DiscordID == "" {
+				return 0
+			}
+
+			return be.journal.Spent(time.Time{}, entry.DiscordID)
+		}},
+	}
+
+	for _, check := range checks {
+		if check.limit == 0 {
+			continue
+		}
+
+		limit := utils.CoinToChange(float64(check.limit))
+		spent := check.spent()
+		if spent+entry.Amount <= limit {
+			continue
+		}
+
+		be.alert(StatusDanger, "Payout limit reached🚨", fmt.Sprintf(
+			"The %s payout of %v PAC to `%s` for `%s` (Discord ID: %s) is blocked. "+
+				"The %s limit is %v PAC and %v PAC is already spent.",
+			entry.Kind, utils.ChangeToCoin(entry.Amount), entry.Receiver, entry.Ref, entry.DiscordID,
+			check.name, check.limit, utils.ChangeToCoin(spent)))
+
+		return fmt.Errorf("%w: the %s limit", ErrLimitReached, check.name)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package engine
+
+// alertsQueueSize is the number of the alerts that can wait to be sent.
+// The alerts after that are only logged.
+const alertsQueueSize = 100
+
+// Alert is an event that the bot admins or a user should know about.
+// UserID is empty for the alerts of the admins.
+type Alert struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	Status  Status `json:"status"`
+	UserID  string `json:"user_id,omitempty"`
+}
+
+// Notifier delivers the alerts of the engine.
+// Each frontend registers its own notifier, e.g. Discord sends the admin alerts to the admin channel
+// and the user alerts to the user directly.
+type Notifier interface {
+	Notify(alert *Alert)
+}
+
+// RegisterNotifier adds the notifier of a frontend to receive the alerts of the engine.
+// The admin alerts are sent to all the frontends, the user alerts only to the frontend of the user.
+func (be *BotEngine) RegisterNotifier(frontend string, n Notifier) {
+	be.notifiersLock.Lock()
+	defer be.notifiersLock.Unlock()
+
+	be.notifiers[frontend] = n
+}
+
+// queuedAlert is an alert that waits to be sent. Frontend is empty for the alerts of the admins.
+type queuedAlert struct {
+	frontend string
+	alert    *Alert
+}
+
+// alert logs the alert and queues it for all the registered notifiers.
+func (be *BotEngine) alert(status Status, title, msg string) {
+	be.logger.Warn("alert", "title", title, "message", msg)
+
+	be.queueAlert("", &Alert{
+		Title:   title,
+		Message: msg,
+		Status:  status,
+	})
+}
+
+// notifyUser queues the alert of a user, for the frontend that the user came from.
+func (be *BotEngine) notifyUser(frontend, userID string, status Status, title, msg string) {
+	be.queueAlert(frontend, &Alert{
+		Title:   title,
+		Message: msg,
+		Status:  status,
+		UserID:  userID,
+	})
+}
+
+// queueAlert queues the alert, it doesn't wait for the alert to be sent.
+// The alerts are raised while the engine is locked, so the slow notifiers shouldn't hold the lock.
+func (be *BotEngine) queueAlert(frontend string, alert *Alert) {
+	select {
+	case be.alerts <- &queuedAlert{frontend: frontend, alert: alert}:
+
+	default:
+		be.logger.Error("the alerts queue is full, the alert is not sent",
+			"title", alert.Title, "userID", alert.UserID)
+	}
+}
+
+func (be *BotEngine) sendAlerts() {
+	for {
+		select {
+		case <-be.ctx.Done():
+			return
+
+		case queued := <-be.alerts:
+			be.sendAlert(queued)
+		}
+	}
+}
+
+// sendAlert sends the admin alerts to all the frontends, and the user alerts only to the frontend of the user.
+func (be *BotEngine) sendAlert(queued *queuedAlert) {
+	be.notifiersLock.RLock()
+	defer be.notifiersLock.RUnlock()
+
+	if queued.frontend == "" {
+		for _, n := range be.notifiers {
+			n.Notify(queued.alert)
+		}
+
+		return
+	}
+
+	n, ok := be.notifiers[queued.frontend]
+	if !ok {
+		be.logger.Warn("no notifier for the frontend, the user is not notified",
+			"frontend", queued.frontend, "userID", queued.alert.UserID, "title", queued.alert.Title)
+
+		return
+	}
+
+	n.Notify(queued.alert)
+}
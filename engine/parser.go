@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Input is a parsed command line.
+type Input struct {
+	Command string
+	Args    []string
+	Flags   map[string]string
+}
+
+// ParseInput splits a command line in a shell-like way.
+// Arguments can be quoted with single or double quotes, and a backslash escapes the next character.
+// Arguments like `--name=value` are parsed as named flags, a bare `--name` sets the flag to "true".
+// Everything after `--` is treated as a positional argument.
+//
+//	Example: claimer-info "tpc1p..." --name="John Doe"
+func ParseInput(line string) (*Input, error) {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	in := &Input{
+		Command: tokens[0],
+		Args:    []string{},
+		Flags:   make(map[string]string),
+	}
+
+	onlyArgs := false
+	for _, token := range tokens[1:] {
+		if onlyArgs || !strings.HasPrefix(token, "--") {
+			in.Args = append(in.Args, token)
+
+			continue
+		}
+
+		if token == "--" {
+			onlyArgs = true
+
+			continue
+		}
+
+		name, value, found := strings.Cut(strings.TrimPrefix(token, "--"), "=")
+		if !found {
+			value = "true"
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("invalid flag: %s", token)
+		}
+
+		if _, exists := in.Flags[name]; exists {
+			return nil, fmt.Errorf("duplicated flag: --%s", name)
+		}
+		in.Flags[name] = value
+	}
+
+	return in, nil
+}
+
+func tokenize(line string) ([]string, error) {
+	tokens := []string{}
+
+	var sb strings.Builder
+	inToken := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			sb.WriteRune(r)
+			escaped = false
+
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inToken = true
+
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				sb.WriteRune(r)
+			}
+
+		case r == '"' || r == '\'':
+			quote = r
+			inToken = true
+
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, sb.String())
+				sb.Reset()
+				inToken = false
+			}
+
+		default:
+			sb.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if escaped {
+		return nil, errors.New("unfinished escape character at the end of input")
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed quote: %c", quote)
+	}
+
+	if inToken {
+		tokens = append(tokens, sb.String())
+	}
+
+	return tokens, nil
+}
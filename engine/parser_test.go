@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		cmd   string
+		args  []string
+		flags map[string]string
+	}{
+		{
+			name:  "simple",
+			line:  "claimer-info tpc1p",
+			cmd:   "claimer-info",
+			args:  []string{"tpc1p"},
+			flags: map[string]string{},
+		},
+		{
+			name:  "extra spaces",
+			line:  "  reward-calc   100 \t month  ",
+			cmd:   "reward-calc",
+			args:  []string{"100", "month"},
+			flags: map[string]string{},
+		},
+		{
+			name:  "quotes and escapes",
+			line:  `cmd "hello world" 'it\s' a\ b "say \"hi\""`,
+			cmd:   "cmd",
+			args:  []string{"hello world", `it\s`, "a b", `say "hi"`},
+			flags: map[string]string{},
+		},
+		{
+			name:  "empty quoted argument",
+			line:  `cmd ""`,
+			cmd:   "cmd",
+			args:  []string{""},
+			flags: map[string]string{},
+		},
+		{
+			name:  "flags",
+			line:  `reward-calc 100 --time=month --memo="from robo pac" --verbose`,
+			cmd:   "reward-calc",
+			args:  []string{"100"},
+			flags: map[string]string{"time": "month", "memo": "from robo pac", "verbose": "true"},
+		},
+		{
+			name:  "end of flags",
+			line:  `cmd -- --not-a-flag`,
+			cmd:   "cmd",
+			args:  []string{"--not-a-flag"},
+			flags: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, err := ParseInput(tt.line)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.cmd, in.Command)
+			assert.Equal(t, tt.args, in.Args)
+			assert.Equal(t, tt.flags, in.Flags)
+		})
+	}
+}
+
+func TestParseInputErrors(t *testing.T) {
+	tests := []struct {
+		line string
+		err  string
+	}{
+		{line: "", err: "empty command"},
+		{line: `cmd "unclosed`, err: "unclosed quote: \""},
+		{line: `cmd abc\`, err: "unfinished escape character at the end of input"},
+		{line: `cmd --=value`, err: "invalid flag: --=value"},
+		{line: `cmd --a=1 --a=2`, err: "duplicated flag: --a"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseInput(tt.line)
+		assert.EqualError(t, err, tt.err, tt.line)
+	}
+}
+
+func TestBindArgs(t *testing.T) {
+	cmd := &Command{
+		Name: "reward-calc",
+		Args: []Arg{
+			{Name: "stake", Type: ArgTypeInteger},
+			{Name: "time", Choices: []string{"day", "month"}, Default: "day"},
+			{Name: "memo", Optional: true},
+		},
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		args, err := cmd.bindArgs(anyone, &Input{Args: []string{"100"}})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"stake": "100", "time": "day"}, args)
+	})
+
+	t.Run("flags and positional", func(t *testing.T) {
+		args, err := cmd.bindArgs(anyone, &Input{
+			Args:  []string{"100", "hello"},
+			Flags: map[string]string{"time": "month"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"stake": "100", "time": "month", "memo": "hello"}, args)
+	})
+
+	t.Run("caller argument", func(t *testing.T) {
+		claim := &Command{
+			Name: "claim",
+			Args: []Arg{
+				{Name: "discord-id", FromCaller: true},
+				{Name: "addr"},
+			},
+		}
+
+		args, err := claim.bindArgs(anyone, &Input{Args: []string{"addr-1"}})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"discord-id": anyone.ID, "addr": "addr-1"}, args)
+		assert.Equal(t, "claim [--discord-id=<caller>] <addr>", claim.Usage())
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		assert.Equal(t, "reward-calc <stake> [time=day] [memo]", cmd.Usage())
+	})
+}
@@ -0,0 +1,350 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/kehiy/RoboPac/config"
+	"github.com/kehiy/RoboPac/payment"
+	"github.com/kehiy/RoboPac/store"
+)
+
+// overpaymentTolerance is the part of the total price that the users can pay more without an overpaid case,
+// since the exchange rate of the payment changes until it is confirmed. At least one dollar is tolerated,
+// because the top-up invoices are rounded up to dollars.
+const overpaymentTolerance = 0.02
+
+// paymentCaseOf returns the case of the payment of the party, or an empty string if it matches the total price.
+func paymentCaseOf(party *store.TwitterParty) store.PaymentCase {
+	totalPrice := float64(party.TotalPrice)
+
+	switch {
+	case party.IsExpired():
+		return store.PaymentLate
+
+	case !party.PaymentFinished && party.PaidAmount > 0 && party.PaidAmount < totalPrice:
+		return store.PaymentUnderpaid
+
+	case party.PaidAmount-totalPrice > math.Max(totalPrice*overpaymentTolerance, 1):
+		return store.PaymentOverpaid
+
+	default:
+		return ""
+	}
+}
+
+// describePaymentCase describes the payment case of the party for the admins.
+func describePaymentCase(party *store.TwitterParty) string {
+	switch party.PaymentCase {
+	case store.PaymentUnderpaid:
+		return fmt.Sprintf("$%.2f of the total price of $%d is paid", party.PaidAmount, party.TotalPrice)
+
+	case store.PaymentOverpaid:
+		return fmt.Sprintf("$%.2f is paid for the total price of $%d", party.PaidAmount, party.TotalPrice)
+
+	case store.PaymentLate:
+		return fmt.Sprintf("$%.2f is paid after the discount code is expired", party.PaidAmount)
+
+	default:
+		return ""
+	}
+}
+
+// savePaymentStatus saves the payment status of the party and writes its status change to the audit log.
+// If the party has a new payment case, the admins and the user are told about it.
+func (be *BotEngine) savePaymentStatus(caller *Caller, campaign *config.Campaign,
+	party, previous *store.TwitterParty,
+) error {
+	if err := be.store.SaveTwitterParty(campaign.ID, party); err != nil {
+		return err
+	}
+	be.auditPartyStatus(caller, campaign.ID, previous, party)
+
+	if party.PaymentCase != "" && party.PaymentCase != previous.PaymentCase {
+		be.reportPaymentCase(campaign, party)
+	}
+
+	return nil
+}
+
+func (be *BotEngine) reportPaymentCase(campaign *config.Campaign, party *store.TwitterParty) {
+	var title, msg string
+	switch party.PaymentCase {
+	case store.PaymentUnderpaid:
+		title = "Underpaid payment⚠️"
+		msg = fmt.Sprintf("Your payment of $%.2f for the discount code `%s` is less than the total price of $%d."+
+			" The support team will contact you to top it up or refund it.",
+			party.PaidAmount, party.DiscountCode, party.TotalPrice)
+
+	case store.PaymentOverpaid:
+		title = "Overpaid payment⚠️"
+		msg = fmt.Sprintf("Your payment of $%.2f for the discount code `%s` is more than the total price of $%d."+
+			" The support team will contact you to refund the extra amount.",
+			party.PaidAmount, party.DiscountCode, party.TotalPrice)
+
+	case store.PaymentLate:
+		title = "Late payment⚠️"
+		msg = fmt.Sprintf("Your payment of $%.2f for the discount code `%s` is received after it is expired."+
+			" The support team will contact you to convert it to a package, if any is left, or refund it.",
+			party.PaidAmount, party.DiscountCode)
+
+	default:
+		return
+	}
+
+	be.alert(StatusWarning, title, fmt.Sprintf(
+		"The Twitter account `%s` in the campaign `%s` with the discount code `%s`: %s."+
+			" Resolve it by the `%s` command.",
+		party.TwitterName, campaign.ID, party.DiscountCode, describePaymentCase(party), CmdPaymentResolve))
+	be.notifyUser(partyFrontend(party), party.DiscordID, StatusWarning, campaign.Title, msg)
+}
+
+// ResolvePayment resolves the payment case of the party, as the admin decides.
+// The resolution is recorded on the party and the user is told about it.
+// A converted payment makes the party paid, so its stake coins are sent right away.
+func (be *BotEngine) ResolvePayment(ctx context.Context, campaignID, twitterName string,
+	resolution store.Resolution, refund *payment.Refund,
+) (*store.TwitterParty, error) {
+	campaign, party, err := be.resolvePayment(ctx, campaignID, twitterName, resolution, refund)
+	if err != nil {
+		return nil, err
+	}
+
+	if party.Status == store.PartyPaid && be.fulfilPaidParty(ctx, callerOrSystem(ctx, jobPaymentEvent), campaign, party) {
+		if fulfilled := be.store.FindTwitterParty(campaign.ID, party.TwitterName); fulfilled != nil {
+			return fulfilled, nil
+		}
+	}
+
+	return party, nil
+}
+
+// resolvePayment resolves the payment case of the party holding the engine lock,
+// so the same case can't be resolved twice at the same time.
+func (be *BotEngine) resolvePayment(ctx context.Context, campaignID, twitterName string,
+	resolution store.Resolution, refund *payment.Refund,
+) (*config.Campaign, *store.TwitterParty, error) {
+	be.Lock()
+	defer be.Unlock()
+
+	campaign, err := be.findCampaign(campaignID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider, err := be.provider(campaign)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	party := be.store.FindTwitterParty(campaign.ID, twitterName)
+	if party == nil {
+		return nil, nil, fmt.Errorf("no discount code generated for this Twitter account: `%v`", twitterName)
+	}
+
+	// A late payment may be for an expired reservation, that the party has reserved again after it.
+	if party.PaymentCase == "" {
+		for _, p := range be.store.TwitterParties(campaign.ID) {
+			if p.TwitterID == party.TwitterID && p.PaymentCase != "" {
+				party = p
+
+				break
+			}
+		}
+	}
+
+	if party.PaymentCase == "" {
+		return nil, nil, fmt.Errorf("the payment of `%s` has no case to resolve", party.TwitterName)
+	}
+
+	adjustment := &store.PaymentAdjustment{
+		Case:       party.PaymentCase,
+		Resolution: resolution,
+	}
+	if pending := pendingRefund(party); pending != nil {
+		if resolution != store.ResolutionRefund {
+			return nil, nil, fmt.Errorf("a refund of $%.2f is pending for `%s`, it can only be resolved by refund",
+				pending.Amount, party.TwitterName)
+		}
+		adjustment = pending
+	}
+	adjustment.ResolvedAt = time.Now().Unix()
+	if caller := callerFrom(ctx); caller != nil {
+		adjustment.ResolvedBy = caller.ID
+	}
+
+	var msg string
+	switch resolution {
+	case store.ResolutionTopUp:
+		msg, err = be.topUpPayment(ctx, provider, party, adjustment)
+	case store.ResolutionConvert:
+		msg, err = be.convertPayment(campaign, party, adjustment)
+	case store.ResolutionRefund:
+		msg, err = be.refundPayment(ctx, campaign, provider, party, refund, adjustment)
+	default:
+		err = fmt.Errorf("unknown resolution: %s", resolution)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	party.PaymentCase = ""
+	if !slices.Contains(party.Adjustments, adjustment) {
+		party.Adjustments = append(party.Adjustments, adjustment)
+	}
+	if err := be.store.SaveTwitterParty(campaign.ID, party); err != nil {
+		return nil, nil, err
+	}
+
+	be.logger.Info("payment case is resolved", "campaign", campaign.ID, "twitterName", party.TwitterName,
+		"case", adjustment.Case, "resolution", resolution, "resolvedBy", adjustment.ResolvedBy)
+	be.notifyUser(partyFrontend(party), party.DiscordID, StatusInfo, campaign.Title, msg)
+
+	return campaign, party, nil
+}
+
+// pendingRefund returns the refund of the party that is not confirmed by the provider, or nil if there is none.
+func pendingRefund(party *store.TwitterParty) *store.PaymentAdjustment {
+	if len(party.Adjustments) == 0 {
+		return nil
+	}
+
+	last := party.Adjustments[len(party.Adjustments)-1]
+	if last.Resolution != store.ResolutionRefund || !last.Pending {
+		return nil
+	}
+
+	return last
+}
+
+// topUpPayment creates a new invoice for the rest of the total price of an underpaid party.
+// The amount that is paid before is kept, so the party is paid once the new invoice is paid.
+func (be *BotEngine) topUpPayment(ctx context.Context, provider payment.IPaymentProvider,
+	party *store.TwitterParty, adjustment *store.PaymentAdjustment,
+) (string, error) {
+	if party.PaymentCase != store.PaymentUnderpaid {
+		return "", fmt.Errorf("only the underpaid payments can be topped up, the payment is %s", party.PaymentCase)
+	}
+
+	topUp := *party
+	topUp.TotalPrice = int(math.Ceil(float64(party.TotalPrice) - party.PaidAmount))
+	if err := provider.CreateInvoice(ctx, &topUp); err != nil {
+		return "", err
+	}
+
+	party.InvoiceID = topUp.InvoiceID
+	party.PaidBefore = party.PaidAmount
+	party.Payments = nil
+	party.PaymentStatus = string(payment.StatusWaiting)
+	adjustment.Amount = float64(topUp.TotalPrice)
+	adjustment.InvoiceID = topUp.InvoiceID
+
+	return fmt.Sprintf("Your payment of $%.2f for the discount code `%s` is less than the total price of $%d."+
+		" Please pay the rest of it, $%d, with this link: %s",
+		party.PaidAmount, party.DiscountCode, party.TotalPrice, topUp.TotalPrice, provider.PaymentLink(party)), nil
+}
+
+// convertPayment converts the payment of an underpaid or a late party to a package.
+// The package of an underpaid party is reduced by the part of the total price that is paid.
+// The package of a late party is reserved again, if the campaign is not full.
+func (be *BotEngine) convertPayment(campaign *config.Campaign, party *store.TwitterParty,
+	adjustment *store.PaymentAdjustment,
+) (string, error) {
+	if party.PaymentCase == store.PaymentOverpaid {
+		return "", errors.New("the overpaid payments can only be refunded")
+	}
+
+	amountInPAC := party.AmountInPAC
+	if party.PaidAmount < float64(party.TotalPrice) {
+		amountInPAC = int64(float64(party.AmountInPAC) * party.PaidAmount / float64(party.TotalPrice))
+	}
+
+	if amountInPAC <= 0 {
+		return "", errors.New("the paid amount is too low to convert, it can only be refunded")
+	}
+
+	if party.IsExpired() {
+		if _, err := be.store.ReservePackage(campaign.ID, campaign.MaxPackages, party); err != nil {
+			return "", fmt.Errorf("unable to reserve the package again: %w", err)
+		}
+	}
+
+	adjustment.AmountInPAC = party.AmountInPAC
+	party.AmountInPAC = amountInPAC
+	party.PaymentFinished = true
+	party.Status = store.PartyPaid
+
+	return fmt.Sprintf("Your payment of $%.2f for the discount code `%s` is converted to a package of %d PAC coins.",
+		party.PaidAmount, party.DiscountCode, party.AmountInPAC), nil
+}
+
+// refundPayment refunds the extra amount of an overpaid party, or all the payment of an underpaid or a late party.
+// The package of an underpaid party is released.
+// The refund is saved as pending before it is sent, so if it fails or the bot stops, it is sent again
+// with the same key and the same amount on the next resolve.
+func (be *BotEngine) refundPayment(ctx context.Context, campaign *config.Campaign, provider payment.IPaymentProvider,
+	party *store.TwitterParty, refund *payment.Refund, adjustment *store.PaymentAdjustment,
+) (string, error) {
+	if refund == nil {
+		refund = &payment.Refund{}
+	}
+
+	// A pending refund is sent again to the same destination, with the same key.
+	if adjustment.Pending {
+		if (refund.Address != "" && refund.Address != adjustment.RefundAddress) ||
+			(refund.Currency != "" && refund.Currency != adjustment.RefundCurrency) {
+			return "", fmt.Errorf("the pending refund is sent to `%s` in %s, it can only be retried to the same address",
+				adjustment.RefundAddress, adjustment.RefundCurrency)
+		}
+	} else {
+		if refund.Address == "" || refund.Currency == "" {
+			return "", errors.New("the refund address and currency are required")
+		}
+
+		amount := party.PaidAmount - party.RefundedAmount
+		if party.PaymentCase == store.PaymentOverpaid {
+			amount = party.PaidAmount - float64(party.TotalPrice)
+		}
+
+		adjustment.Amount = math.Round(amount*100) / 100
+		if adjustment.Amount <= 0 {
+			return "", errors.New("nothing is left to refund")
+		}
+
+		adjustment.RefundKey = fmt.Sprintf("refund-%s-%d", party.DiscountCode, len(party.Adjustments)+1)
+		adjustment.RefundAddress = refund.Address
+		adjustment.RefundCurrency = refund.Currency
+		adjustment.Pending = true
+		party.Adjustments = append(party.Adjustments, adjustment)
+		if err := be.store.SaveTwitterParty(campaign.ID, party); err != nil {
+			return "", err
+		}
+	}
+
+	refund = &payment.Refund{
+		ID:       adjustment.RefundKey,
+		Amount:   adjustment.Amount,
+		Currency: adjustment.RefundCurrency,
+		Address:  adjustment.RefundAddress,
+	}
+	refundID, err := provider.Refund(ctx, party, refund)
+	if err != nil {
+		return "", fmt.Errorf("the refund is pending, resolve it by refund again to retry: %w", err)
+	}
+
+	party.RefundedAmount += refund.Amount
+	adjustment.Pending = false
+	adjustment.RefundID = refundID
+
+	if party.Status == store.PartyReserved {
+		party.Status = store.PartyExpired
+		party.ExpiredAt = time.Now().Unix()
+	}
+
+	return fmt.Sprintf("$%.2f of your payment for the discount code `%s` is refunded to `%s` in %s.",
+		refund.Amount, party.DiscountCode, refund.Address, refund.Currency), nil
+}
@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kehiy/RoboPac/config"
+	"github.com/kehiy/RoboPac/payment"
+	"github.com/kehiy/RoboPac/store"
+)
+
+// paymentEventsQueueSize is the number of the payment events that can wait to be processed.
+// The events after that are rejected and the providers send them again later.
+const paymentEventsQueueSize = 100
+
+// The failed bond transaction of a paid party is retried by the reconciler after minPayoutRetryDelay at first,
+// and the delay is doubled each time it fails again, up to maxPayoutRetryDelay.
+const (
+	minPayoutRetryDelay = 5 * time.Minute
+	maxPayoutRetryDelay = 6 * time.Hour
+)
+
+// payoutFailure is the failed bond transaction of a paid party.
+type payoutFailure struct {
+	attempts int
+	retryAt  time.Time
+}
+
+// queuePaymentEvent queues the payment event of a provider webhook, it doesn't wait for the event to be processed.
+func (be *BotEngine) queuePaymentEvent(event *payment.Event) error {
+	select {
+	case be.paymentEvents <- event:
+		return nil
+
+	default:
+		return errors.New("the payment events queue is full")
+	}
+}
+
+func (be *BotEngine) processPaymentEvents() {
+	for {
+		select {
+		case <-be.ctx.Done():
+			return
+
+		case event := <-be.paymentEvents:
+			be.handlePaymentEvent(event)
+		}
+	}
+}
+
+// handlePaymentEvent records the payment status and the paid amount of the party and once the payment is finished,
+// it fulfils the party and tells the user about its bond transaction.
+// The party is found by its discount code, that is the order ID of the invoice.
+func (be *BotEngine) handlePaymentEvent(event *payment.Event) {
+	campaign, party := be.findPaymentParty(event)
+	if party == nil {
+		if !event.IsFinished() {
+			be.logger.Debug("no party for the payment", "provider", event.Provider,
+				"orderID", event.OrderID, "status", event.Status)
+
+			return
+		}
+
+		be.alert(StatusWarning, "Unknown payment⚠️", fmt.Sprintf(
+			"A %s payment is finished for the order `%s` and the invoice `%s`, but no discount code matches it.",
+			event.Provider, event.OrderID, event.InvoiceID))
+
+		return
+	}
+
+	caller := NewSystemCaller(jobPaymentEvent)
+	switch party.Status {
+	case store.PartyFulfilled:
+		be.logger.Debug("party is fulfilled before", "campaign", campaign.ID, "twitterName", party.TwitterName)
+
+		return
+
+	case store.PartyReserved, store.PartyExpired:
+		// A payment for an expired party is kept as a late payment, for the admins to resolve it.
+		previous := *party
+		if !applyInvoiceStatus(party, event.InvoiceStatus()) {
+			return
+		}
+
+		// The party may be expired meanwhile, the store doesn't let an expired party be paid.
+		if err := be.savePaymentStatus(caller, campaign, party, &previous); err != nil {
+			be.logger.Error("unable to save the payment status of the party", "error", err,
+				"campaign", campaign.ID, "twitterName", party.TwitterName)
+
+			return
+		}
+
+		if !party.PaymentFinished || party.IsExpired() {
+			return
+		}
+
+	case store.PartyPaid:
+	}
+
+	be.fulfilPaidParty(be.ctx, caller, campaign, party)
+}
+
+// fulfilPaidParty sends the bond transaction of the paid party and tells the user about it.
+// The admins are alerted the first time the bond transaction fails, the next failures are only logged.
+func (be *BotEngine) fulfilPaidParty(ctx context.Context, caller *Caller, campaign *config.Campaign,
+	party *store.TwitterParty,
+) bool {
+	key := payoutFailureKey(campaign.ID, party.TwitterName)
+	fulfilled, err := be.fulfilParty(ctx, caller, campaign, party.TwitterName)
+	if err != nil {
+		failure := be.recordPayoutFailure(key)
+		if failure.attempts > 1 {
+			be.logger.Warn("bond transaction of the paid party failed again", "error", err, "campaign", campaign.ID,
+				"twitterName", party.TwitterName, "attempts", failure.attempts, "retryAt", failure.retryAt)
+
+			return false
+		}
+
+		be.alert(StatusDanger, "Booster payout failed🚨", fmt.Sprintf(
+			"The bond transaction of the Twitter account `%s` in the campaign `%s` failed: %v."+
+				" It is retried automatically and the user can claim it again.", party.TwitterName, campaign.ID, err))
+
+		return false
+	}
+	be.clearPayoutFailure(key)
+
+	be.notifyUser(partyFrontend(fulfilled), fulfilled.DiscordID, StatusSuccess, campaign.Title, fmt.Sprintf(
+		"Your payment for the discount code `%s` is received and %d PAC coins are staked to your validator `%s`."+
+			" Transaction: https://pacscan.org/transactions/%s",
+		fulfilled.DiscountCode, fulfilled.AmountInPAC, fulfilled.ValAddr, fulfilled.TransactionID))
+
+	return true
+}
+
+func payoutFailureKey(campaignID, twitterName string) string {
+	return fmt.Sprintf("%s/%s", campaignID, twitterName)
+}
+
+// recordPayoutFailure records a failed bond transaction and sets the time of its next retry.
+// It returns a copy of the failure.
+func (be *BotEngine) recordPayoutFailure(key string) payoutFailure {
+	be.payoutFailuresLock.Lock()
+	defer be.payoutFailuresLock.Unlock()
+
+	failure, ok := be.payoutFailures[key]
+	if !ok {
+		failure = &payoutFailure{}
+		be.payoutFailures[key] = failure
+	}
+
+	delay := maxPayoutRetryDelay
+	if failure.attempts < 7 {
+		delay = min(minPayoutRetryDelay<<failure.attempts, maxPayoutRetryDelay)
+	}
+	failure.attempts++
+	failure.retryAt = time.Now().Add(delay)
+
+	return *failure
+}
+
+func (be *BotEngine) clearPayoutFailure(key string) {
+	be.payoutFailuresLock.Lock()
+	defer be.payoutFailuresLock.Unlock()
+
+	delete(be.payoutFailures, key)
+}
+
+// isPayoutRetryDue returns true if the bond transaction of the party has not failed, or its retry time is passed.
+func (be *BotEngine) isPayoutRetryDue(campaignID, twitterName string, now time.Time) bool {
+	be.payoutFailuresLock.Lock()
+	defer be.payoutFailuresLock.Unlock()
+
+	failure, ok := be.payoutFailures[payoutFailureKey(campaignID, twitterName)]
+
+	return !ok || !now.Before(failure.retryAt)
+}
+
+// findPaymentParty returns the party that the payment is made for.
+// The invoice of the party should match the invoice of the payment.
+func (be *BotEngine) findPaymentParty(event *payment.Event) (*config.Campaign, *store.TwitterParty) {
+	for _, campaign := range be.campaigns {
+		if campaign.Provider != event.Provider {
+			continue
+		}
+
+		party := be.store.FindTwitterPartyByDiscountCode(campaign.ID, event.OrderID)
+		if party == nil {
+			continue
+		}
+
+		if party.InvoiceID != "" && party.InvoiceID != event.InvoiceID {
+			continue
+		}
+
+		return campaign, party
+	}
+
+	return nil, nil
+}
+
+// partyFrontend returns the frontend that the party is registered from.
+func partyFrontend(party *store.TwitterParty) string {
+	if party.Frontend == "" {
+		return FrontendDiscord
+	}
+
+	return party.Frontend
+}
@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kehiy/RoboPac/journal"
+	"github.com/kehiy/RoboPac/store"
+)
+
+// payout sends a bond transaction for the payout exactly once, if it is in the spending limits.
+// Each step is written to the payout journal before the next one starts. If the bot stops in the middle,
+// the payout is finished by reconcilePayouts on the next start.
+// The payout is written to the audit log on behalf of the caller, once its transaction may be sent.
+// The caller should hold the engine lock, and the context bounds the wallet calls to the node.
+func (be *BotEngine) payout(ctx context.Context, caller *Caller, kind, ref, discordID, pubKey, receiver, memo string,
+	amount int64,
+) (string, error) {
+	key := journal.Key(kind, ref)
+	if entry := be.journal.Find(key); entry != nil {
+		if entry.State == journal.StateDone {
+			return "", fmt.Errorf("`%s` is already paid in transaction %s", ref, entry.TxID)
+		}
+
+		if !entry.IsFinished() {
+			return "", fmt.Errorf("the previous payout of `%s` is not finished yet, please contact the support team", ref)
+		}
+	}
+
+	entry := &journal.Entry{
+		Key:       key,
+		Kind:      kind,
+		Ref:       ref,
+		DiscordID: discordID,
+		Receiver:  receiver,
+		Amount:    amount,
+		State:     journal.StateIntent,
+		DryRun:    be.dryRun,
+	}
+	if err := be.checkLimits(entry); err != nil {
+		return "", err
+	}
+
+	if err := be.journal.Record(entry); err != nil {
+		return "", err
+	}
+
+	txID, rawTx, err := be.wallet.SignBondTransaction(ctx, pubKey, receiver, memo, amount)
+	if err != nil {
+		be.abortPayout(entry)
+
+		return "", err
+	}
+
+	entry.State = journal.StateSigned
+	entry.TxID = txID
+	entry.RawTx = rawTx
+	if err := be.journal.Record(entry); err != nil {
+		be.abortPayout(entry)
+
+		return "", err
+	}
+
+	// From here the transaction may be on the way, so the payout can't be aborted anymore.
+	sentID, err := be.wallet.BroadcastTransaction(ctx, rawTx)
+	if err != nil {
+		be.logger.Error("unable to broadcast the payout, it is checked on the next start",
+			"error", err, "key", key, "txID", txID)
+		be.auditPayout(caller, entry, err)
+
+		return "", err
+	}
+
+	if sentID == "" {
+		err = errors.New("can't send bond transaction")
+		be.auditPayout(caller, entry, err)
+
+		return "", err
+	}
+
+	return txID, be.finishPayout(caller, entry)
+}
+
+// finishPayout saves the transaction ID of a broadcast payout in the store and writes it to the audit log.
+func (be *BotEngine) finishPayout(caller *Caller, entry *journal.Entry) error {
+	entry.State = journal.StateBroadcast
+	if err := be.journal.Record(entry); err != nil {
+		be.logger.Error("unable to record the payout broadcast", "error", err, "key", entry.Key, "txID", entry.TxID)
+	}
+
+	if err := be.applyPayout(entry); err != nil {
+		be.logger.Error("unable to save the payout, it is saved on the next start",
+			"error", err, "key", entry.Key, "txID", entry.TxID)
+		be.auditPayout(caller, entry, err)
+
+		return err
+	}
+
+	entry.State = journal.StateDone
+	if err := be.journal.Record(entry); err != nil {
+		be.logger.Error("unable to record the payout", "error", err, "key", entry.Key, "txID", entry.TxID)
+	}
+	be.auditPayout(caller, entry, nil)
+	be.trackTransaction(entry.Kind, entry.Ref, entry.TxID)
+
+	return nil
+}
+
+// applyPayout saves the transaction ID of the payout in the store.
+// It can be applied more than once.
+func (be *BotEngine) applyPayout(entry *journal.Entry) error {
+	switch entry.Kind {
+	case store.TxKindClaim:
+		return be.store.AddClaimTransaction(entry.Ref, entry.TxID)
+
+	default:
+		// The campaign payouts have the campaign ID as their kind.
+		campaign, err := be.findCampaign(entry.Kind)
+		if err != nil {
+			return err
+		}
+
+		party := be.store.FindTwitterParty(campaign.ID, entry.Ref)
+		if party == nil {
+			return fmt.Errorf("no party found in campaign `%s` for the Twitter account: %s", campaign.ID, entry.Ref)
+		}
+		party.TransactionID = entry.TxID
+		party.Status = store.PartyFulfilled
+
+		return be.store.SaveTwitterParty(campaign.ID, party)
+	}
+}
+
+func (be *BotEngine) abortPayout(entry *journal.Entry) {
+	entry.State = journal.StateAborted
+	if err := be.journal.Record(entry); err != nil {
+		be.logger.Error("unable to record the aborted payout", "error", err, "key", entry.Key)
+	}
+}
+
+// reconcilePayouts finishes the payouts that were interrupted by a crash or a failure.
+// Payouts that are not signed yet are aborted. Signed payouts are looked up on the blockchain and
+// broadcast again if they are not found. Since the same signed transaction is sent again,
+// the reward can't be paid twice. The simulated payouts of a dry run are aborted and never sent.
+func (be *BotEngine) reconcilePayouts(ctx context.Context) {
+	be.Lock()
+	defer be.Unlock()
+
+	caller := NewSystemCaller(jobPayoutReconciler)
+	for _, entry := range be.journal.Unfinished() {
+		if entry.DryRun {
+			be.logger.Info("the unfinished simulated payout is aborted", "key", entry.Key, "state", entry.State)
+			be.abortPayout(entry)
+
+			continue
+		}
+
+		be.logger.Info("reconciling the unfinished payout", "key", entry.Key, "state", entry.State, "txID", entry.TxID)
+
+		switch entry.State {
+		case journal.StateIntent:
+			be.abortPayout(entry)
+
+		case journal.StateSigned:
+			if _, err := be.clientMgr.GetTransactionData(ctx, entry.TxID); err != nil {
+				if _, err := be.wallet.BroadcastTransaction(ctx, entry.RawTx); err != nil {
+					be.logger.Error("unable to send the unfinished payout, it needs operator review",
+						"error", err, "key", entry.Key, "txID", entry.TxID)
+					be.flagPayout(entry)
+					be.auditPayout(caller, entry, err)
+
+					continue
+				}
+			}
+
+			_ = be.finishPayout(caller, entry)
+
+		case journal.StateBroadcast:
+			_ = be.finishPayout(caller, entry)
+
+		case journal.StateDone, journal.StateAborted:
+		}
+	}
+}
+
+// flagPayout flags the transaction of the payout for operator review.
+func (be *BotEngine) flagPayout(entry *journal.Entry) {
+	err := be.store.SaveTransaction(&store.Transaction{
+		TxID:        entry.TxID,
+		Kind:        entry.Kind,
+		Ref:         entry.Ref,
+		Status:      store.TxStatusFailed,
+		SentAt:      entry.UpdatedAt,
+		NeedsReview: true,
+	})
+	if err != nil {
+		be.logger.Error("unable to flag the payout", "error", err, "key", entry.Key, "txID", entry.TxID)
+	}
+}
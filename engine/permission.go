@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kehiy/RoboPac/config"
+)
+
+// Role defines what a caller is allowed to do.
+// Roles are ordered, a caller with a higher role can run the commands of lower roles.
+type Role int
+
+const (
+	RoleAnyone Role = iota
+	RoleViewer
+	RoleCampaignOperator
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleAnyone:
+		return "anyone"
+	case RoleViewer:
+		return config.RoleViewer
+	case RoleCampaignOperator:
+		return config.RoleCampaignOperator
+	case RoleAdmin:
+		return config.RoleAdmin
+	default:
+		return fmt.Sprintf("role(%d)", int(r))
+	}
+}
+
+const (
+	FrontendCLI     = "cli"
+	FrontendDiscord = "discord"
+	// FrontendSystem is the bot itself, for the changes that no user has asked for, like the payment events.
+	FrontendSystem = "system"
+)
+
+// Caller is the person who runs a command.
+type Caller struct {
+	// ID is the user ID in the frontend, e.g. the Discord user ID.
+	ID string
+	// Roles are the role IDs of the user in the frontend, e.g. the Discord guild roles.
+	Roles []string
+	// Frontend is the name of the frontend the command came from.
+	Frontend string
+	// JoinedAt is when the user joined the frontend, e.g. the Discord guild. It is zero if it is unknown.
+	JoinedAt time.Time
+}
+
+type callerKey struct{}
+
+// withCaller returns a copy of the context that carries the caller of the command.
+func withCaller(ctx context.Context, caller *Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// callerFrom returns the caller of the command, or nil if the context doesn't carry it.
+func callerFrom(ctx context.Context) *Caller {
+	caller, _ := ctx.Value(callerKey{}).(*Caller)
+
+	return caller
+}
+
+// NewCLICaller returns the caller for the command line interface.
+// It is run by the operator of the bot and has the admin role.
+func NewCLICaller() *Caller {
+	return &Caller{Frontend: FrontendCLI}
+}
+
+// NewSystemCaller returns the caller for a background job of the bot, e.g. `system:invoice-reconciler`.
+// It is only used to record the changes of the job in the audit log, it can't run commands.
+func NewSystemCaller(job string) *Caller {
+	return &Caller{ID: job, Frontend: FrontendSystem}
+}
+
+// callerOrSystem returns the caller of the command, or the system caller of the job
+// if the context doesn't carry a caller.
+func callerOrSystem(ctx context.Context, job string) *Caller {
+	if caller := callerFrom(ctx); caller != nil {
+		return caller
+	}
+
+	return NewSystemCaller(job)
+}
+
+// NewDiscordCaller returns a caller for a Discord guild member.
+func NewDiscordCaller(userID string, roles []string) *Caller {
+	return &Caller{
+		ID:       userID,
+		Roles:    roles,
+		Frontend: FrontendDiscord,
+	}
+}
+
+// String returns the caller in the form of `frontend:id`, e.g. `discord:123456789`.
+func (c *Caller) String() string {
+	if c.ID == "" {
+		return c.Frontend
+	}
+
+	return fmt.Sprintf("%s:%s", c.Frontend, c.ID)
+}
+
+// roleOf returns the highest role of the caller defined in the config.
+func (be *BotEngine) roleOf(caller *Caller) Role {
+	if caller.Frontend == FrontendCLI {
+		return RoleAdmin
+	}
+
+	for _, role := range []Role{RoleAdmin, RoleCampaignOperator, RoleViewer} {
+		members, ok := be.roles[role.String()]
+		if !ok {
+			continue
+		}
+
+		if caller.ID != "" && slices.Contains(members.DiscordIDs, caller.ID) {
+			return role
+		}
+
+		for _, r := range caller.Roles {
+			if slices.Contains(members.DiscordRoles, r) {
+				return role
+			}
+		}
+	}
+
+	return RoleAnyone
+}
+
+// checkPermission checks the caller has the role of the command.
+// Only admins can run a command on behalf of another user.
+func (be *BotEngine) checkPermission(caller *Caller, cmd *Command, args map[string]string) error {
+	role := be.roleOf(caller)
+	if role < cmd.Role {
+		return fmt.Errorf("unauthorized person: the `%s` command requires the %s role", cmd.Name, cmd.Role)
+	}
+
+	for _, arg := range cmd.Args {
+		if arg.FromCaller && args[arg.Name] != caller.ID && role < RoleAdmin {
+			return fmt.Errorf("unauthorized person: only admins can set the %s", arg.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Status string
+
+const (
+	StatusInfo    Status = "info"
+	StatusSuccess Status = "success"
+	StatusWarning Status = "warning"
+	StatusDanger  Status = "danger"
+)
+
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type Link struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Result is the outcome of a command. Frontends render it in their own way,
+// String returns the plain text form of it.
+type Result struct {
+	Command string  `json:"command"`
+	Title   string  `json:"title"`
+	Status  Status  `json:"status"`
+	Message string  `json:"message,omitempty"`
+	Fields  []Field `json:"fields,omitempty"`
+	Links   []Link  `json:"links,omitempty"`
+	Note    string  `json:"note,omitempty"`
+	TxID    string  `json:"tx_id,omitempty"`
+	// Simulated is set in dry-run mode, no transaction is broadcast and the store changes are not real.
+	Simulated bool `json:"simulated,omitempty"`
+	Data      any  `json:"data,omitempty"`
+}
+
+const simulatedNote = "🧪 SIMULATED: dry-run mode, no transaction is broadcast"
+
+func newResult(status Status, msg string) *Result {
+	return &Result{
+		Status:  status,
+		Message: msg,
+	}
+}
+
+func (r *Result) addField(name string, value any) *Result {
+	r.Fields = append(r.Fields, Field{Name: name, Value: fmt.Sprint(value)})
+
+	return r
+}
+
+// addLink adds a link to the result, the empty links are skipped.
+func (r *Result) addLink(name, url string) *Result {
+	if url == "" {
+		return r
+	}
+
+	r.Links = append(r.Links, Link{Name: name, URL: url})
+
+	return r
+}
+
+func (r *Result) String() string {
+	var sb strings.Builder
+	if r.Simulated {
+		sb.WriteString(simulatedNote + "\n")
+	}
+
+	if r.Message != "" {
+		sb.WriteString(r.Message + "\n")
+	}
+
+	for _, f := range r.Fields {
+		sb.WriteString(fmt.Sprintf("%s: %s\n", f.Name, f.Value))
+	}
+
+	for _, l := range r.Links {
+		sb.WriteString(fmt.Sprintf("%s: %s\n", l.Name, l.URL))
+	}
+
+	if r.Note != "" {
+		sb.WriteString(fmt.Sprintf("\n> Note📝: %s\n", r.Note))
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func txLink(txID string) string {
+	return fmt.Sprintf("https://pacscan.org/transactions/%s", txID)
+}
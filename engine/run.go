@@ -1,225 +1,698 @@
 package engine
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/kehiy/RoboPac/client"
+	"github.com/kehiy/RoboPac/payment"
+	"github.com/kehiy/RoboPac/store"
 	"github.com/kehiy/RoboPac/utils"
 	"github.com/pactus-project/pactus/util"
 )
 
 const (
-	CmdClaim            = "claim"             //!
-	CmdClaimerInfo      = "claimer-info"      //!
-	CmdNodeInfo         = "node-info"         //!
-	CmdNetworkStatus    = "network"           //!
-	CmdNetworkHealth    = "network-health"    //!
-	CmdBotWallet        = "wallet"            //!
-	CmdClaimStatus      = "claim-status"      //!
-	CmdRewardCalc       = "calc-reward"       //!
-	CmdBoosterPayment   = "booster-payment"   //!
-	CmdBoosterClaim     = "booster-claim"     //!
-	CmdBoosterWhitelist = "booster-whitelist" //!
-	CmdBoosterStatus    = "booster-status"    //!
+	CmdHelp               = "help"                //!
+	CmdClaim              = "claim"               //!
+	CmdClaimerInfo        = "claimer-info"        //!
+	CmdNodeInfo           = "node-info"           //!
+	CmdNetworkStatus      = "network"             //!
+	CmdNetworkHealth      = "network-health"      //!
+	CmdNetworkConsistency = "network-consistency" //!
+	CmdBotWallet          = "wallet"              //!
+	CmdClaimStatus        = "claim-status"        //!
+	CmdRewardCalc         = "calc-reward"         //!
+	CmdBoosterPayment     = "booster-payment"     //!
+	CmdBoosterClaim       = "booster-claim"       //!
+	CmdBoosterWhitelist   = "booster-whitelist"   //!
+	CmdBoosterStatus      = "booster-status"      //!
+	CmdTxReview           = "tx-review"           //!
+	CmdPaymentReport      = "payment-report"      //!
+	CmdPaymentResolve     = "payment-resolve"     //!
 )
 
+const defaultCommandTimeout = 30 * time.Second
+
+func (be *BotEngine) newCommands() []*Command {
+	// The campaign commands run for the first campaign, unless another campaign is chosen.
+	ids := campaignIDs(be.campaigns)
+	campaignArg := Arg{Name: "campaign", Desc: "the campaign", Choices: ids}
+	if len(ids) > 0 {
+		campaignArg.Default = ids[0]
+	}
+
+	return []*Command{
+		{
+			Name:    CmdHelp,
+			Title:   "RoboPac Help 🆘",
+			Desc:    "Help command for RoboPac",
+			Handler: be.helpHandler,
+		},
+		{
+			Name:  CmdClaim,
+			Title: "Claim Result💸",
+			Desc:  "Command to claim the Pactus TestNet rewards coins",
+			Args: []Arg{
+				{Name: "discord-id", Desc: "Discord ID of the claimer", FromCaller: true},
+				{Name: "testnet-addr", Desc: "Testnet validator address (tpc1p...)"},
+				{Name: "mainnet-addr", Desc: "Mainnet validator address (pc1p...)"},
+			},
+			Timeout: time.Minute,
+			Audited: true,
+			Handler: be.claimHandler,
+		},
+		{
+			Name:  CmdClaimerInfo,
+			Title: "Claimer Infoℹ️",
+			Desc:  "Get claimer info",
+			Args: []Arg{
+				{Name: "testnet-addr", Desc: "Testnet address"},
+			},
+			Handler: be.claimerInfoHandler,
+		},
+		{
+			Name:  CmdNodeInfo,
+			Title: "Node Info🛟",
+			Desc:  "Get node info",
+			Args: []Arg{
+				{Name: "validator-address", Desc: "Validator address"},
+			},
+			Handler: be.nodeInfoHandler,
+		},
+		{
+			Name:        CmdRewardCalc,
+			DiscordName: "reward-calc",
+			Title:       "Validator reward calculation🧮",
+			Desc:        "calculates how much PAC coins you will earn in a (day/month/year) based on your stake.",
+			Args: []Arg{
+				{Name: "stake", Desc: "your validator stake amount", Type: ArgTypeInteger},
+				{Name: "time", Desc: "in a day/month/year", Choices: []string{"day", "month", "year"}, Default: "day"},
+			},
+			Handler: be.rewardCalcHandler,
+		},
+		{
+			Name:    CmdNetworkHealth,
+			Title:   "Network Health🧑‍⚕️",
+			Desc:    "network health status",
+			Handler: be.networkHealthHandler,
+		},
+		{
+			Name:        CmdNetworkStatus,
+			DiscordName: "network-status",
+			Title:       "Network Status🕸️",
+			Desc:        "status of The Pactus network",
+			Handler:     be.networkStatusHandler,
+		},
+		{
+			Name:    CmdNetworkConsistency,
+			Title:   "Network Consistency🔗",
+			Desc:    "Compare the blockchain between all the RPC nodes, to find the lagging nodes and the forks",
+			Handler: be.networkConsistencyHandler,
+		},
+		{
+			Name:    CmdBotWallet,
+			Title:   "Bot Wallet🪙",
+			Desc:    "The RoboPac wallet info",
+			Handler: be.botWalletHandler,
+		},
+		{
+			Name:    CmdClaimStatus,
+			Title:   "Claim's Status📃",
+			Desc:    "TestNet reward claim status",
+			Handler: be.claimStatusHandler,
+		},
+		{
+			Name:  CmdBoosterStatus,
+			Title: "Campaign Status ✨",
+			Desc:  "Validator Booster Program Status",
+			Args: []Arg{
+				campaignArg,
+			},
+			Handler: be.boosterStatusHandler,
+		},
+		{
+			Name:  CmdBoosterPayment,
+			Title: "Campaign Payment ✨",
+			Desc:  "Create payment link in Validator Booster Program",
+			Args: []Arg{
+				{Name: "discord-id", Desc: "Discord ID of the participant", FromCaller: true},
+				{Name: "twitter-username", Desc: "your Twitter username"},
+				{Name: "validator-address", Desc: "your validator address"},
+				campaignArg,
+			},
+			Timeout: time.Minute,
+			Audited: true,
+			Handler: be.boosterPaymentHandler,
+		},
+		{
+			Name:  CmdBoosterClaim,
+			Title: "Campaign Claim ✨",
+			Desc:  "Claim the stake PAC coin in Validator Booster Program",
+			Args: []Arg{
+				{Name: "twitter-username", Desc: "your Twitter username"},
+				campaignArg,
+			},
+			Audited: true,
+			Handler: be.boosterClaimHandler,
+		},
+		{
+			Name:  CmdBoosterWhitelist,
+			Title: "Campaign Whitelist ✨",
+			Desc:  "Whitelist a non-active Twitter account in Validator Booster Program",
+			Args: []Arg{
+				{Name: "twitter-username", Desc: "Twitter username"},
+				{Name: "authorized-discord-id", Desc: "Discord ID of the authorized person", FromCaller: true},
+				campaignArg,
+			},
+			Role:    RoleCampaignOperator,
+			Audited: true,
+			Handler: be.boosterWhitelistHandler,
+		},
+		{
+			Name:    CmdTxReview,
+			Title:   "Transactions to Review🔎",
+			Desc:    "Payout transactions that are not confirmed in time",
+			Role:    RoleAdmin,
+			Handler: be.txReviewHandler,
+		},
+		{
+			Name:    CmdPaymentReport,
+			Title:   "Payment Report🧾",
+			Desc:    "Reconcile the pending invoices and report the payments that don't match their bond transactions",
+			Role:    RoleAdmin,
+			Audited: true,
+			Handler: be.paymentReportHandler,
+		},
+		{
+			Name:  CmdPaymentResolve,
+			Title: "Payment Resolution🧾",
+			Desc:  "Top up, convert or refund an underpaid, overpaid or late payment",
+			Args: []Arg{
+				{Name: "twitter-username", Desc: "Twitter username of the participant"},
+				{Name: "resolution", Desc: "how the payment is resolved", Choices: []string{
+					string(store.ResolutionTopUp), string(store.ResolutionConvert), string(store.ResolutionRefund),
+				}},
+				{Name: "refund-address", Desc: "the address that the refund is sent to", Optional: true},
+				{Name: "refund-currency", Desc: "the currency of the refund, e.g. usdtbsc", Optional: true},
+				campaignArg,
+			},
+			Timeout: time.Minute,
+			Role:    RoleAdmin,
+			Audited: true,
+			Handler: be.paymentResolveHandler,
+		},
+	}
+}
+
+// Commands returns the list of commands supported by the engine.
+func (be *BotEngine) Commands() []*Command {
+	return be.commands
+}
+
 // The input is always string.
 //
-//	The input format is like: [Command] <Arguments ...>
+//	The input format is like: [Command] <Arguments ...> <--flag=value ...>
 //
-// The output is always string, but format might be JSON. ???
-func (be *BotEngine) Run(input string) (string, error) {
-	cmd, args := be.parseQuery(input)
-
-	switch cmd {
-	case CmdClaim:
-		if err := CheckArgs(3, args); err != nil {
-			return "", err
-		}
+// The output is a Result that each frontend renders in its own way.
+func (be *BotEngine) Run(ctx context.Context, caller *Caller, input string) (*Result, error) {
+	in, err := ParseInput(input)
+	if err != nil {
+		return nil, err
+	}
 
-		txHash, err := be.Claim(args[0], args[1], args[2])
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf("Reward claimed successfully✅\nYour claim transaction: https://pacscan.org/transactions/%s", txHash), nil
+	return be.Execute(ctx, caller, in)
+}
 
-	case CmdClaimerInfo:
-		if err := CheckArgs(1, args); err != nil {
-			return "", err
-		}
+// Execute runs an already parsed input.
+// Frontends that don't deal with text, like Discord, can build the input directly.
+// The command is canceled when the context is canceled or the command deadline is reached.
+func (be *BotEngine) Execute(ctx context.Context, caller *Caller, in *Input) (*Result, error) {
+	cmd := be.findCommand(in.Command)
+	if cmd == nil {
+		return nil, fmt.Errorf("unknown command: %s", in.Command)
+	}
 
-		claimer, err := be.ClaimerInfo(args[0])
-		if err != nil {
-			return "", err
-		}
+	args, err := cmd.bindArgs(caller, in)
+	if err != nil {
+		return nil, err
+	}
 
-		return fmt.Sprintf("TestNet Address: %s\namount: %v PACs\nIsClaimed: %v\n txHash: %s",
-			args[0], util.ChangeToString(claimer.TotalReward), claimer.IsClaimed(), claimer.ClaimedTxID), nil
+	if err := be.checkPermission(caller, cmd, args); err != nil {
+		return nil, err
+	}
 
-	case CmdNetworkHealth:
-		health, err := be.NetworkHealth()
-		if err != nil {
-			return "", err
-		}
+	timeout := be.timeoutOf(cmd)
+	ctx, cancel := context.WithTimeout(withCaller(ctx, caller), timeout)
+	defer cancel()
 
-		var status string
-		if health.HealthStatus {
-			status = "Healthy✅"
-		} else {
-			status = "UnHealthy❌"
-		}
+	res, err := cmd.Handler(ctx, args)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && err != nil {
+		err = TimeoutError{Command: cmd.Name, Timeout: timeout}
+	}
 
-		return fmt.Sprintf("Network is %s\nCurrentTime: %v\nLastBlockTime: %v\nTime Diff: %v\nLast Block Height: %v",
-			status, health.CurrentTime.Format("02/01/2006, 15:04:05"), health.LastBlockTime.Format("02/01/2006, 15:04:05"), health.TimeDifference, utils.FormatNumber(int64(health.LastBlockHeight))), nil
+	if cmd.Audited {
+		be.audit(caller, cmd.Name, args, res, err)
+	}
 
-	case CmdNodeInfo:
-		if err := CheckArgs(1, args); err != nil {
-			return "", err
-		}
+	if err != nil {
+		return nil, err
+	}
+	res.Command = cmd.Name
+	if res.Title == "" {
+		res.Title = cmd.Title
+	}
+	res.Simulated = be.dryRun
 
-		nodeInfo, err := be.NodeInfo(args[0])
-		if err != nil {
-			return "", err
-		}
+	return res, nil
+}
 
-		var pip19Score string
-		if nodeInfo.AvailabilityScore >= 0.9 {
-			pip19Score = fmt.Sprintf("%v✅", nodeInfo.AvailabilityScore)
-		} else {
-			pip19Score = fmt.Sprintf("%v⚠️", nodeInfo.AvailabilityScore)
+func (be *BotEngine) findCommand(name string) *Command {
+	for _, cmd := range be.commands {
+		if cmd.Name == name {
+			return cmd
 		}
+	}
 
-		return fmt.Sprintf("PeerID: %s\nIP Address: %s\nAgent: %s\n"+
-			"Moniker: %s\nCountry: %s\nCity: %s\nRegion Name: %s\nTimeZone: %s\n"+
-			"ISP: %s\n\nValidator Info🔍\nNumber: %v\nPIP-19 Score: %s\nStake: %v PAC's\n",
-			nodeInfo.PeerID, nodeInfo.IPAddress, nodeInfo.Agent, nodeInfo.Moniker, nodeInfo.Country,
-			nodeInfo.City, nodeInfo.RegionName, nodeInfo.TimeZone, nodeInfo.ISP, utils.FormatNumber(int64(nodeInfo.ValidatorNum)),
-			pip19Score, utils.FormatNumber(int64(util.ChangeToCoin(nodeInfo.StakeAmount)))), nil
-
-	case CmdNetworkStatus:
-		net, err := be.NetworkStatus()
-		if err != nil {
-			return "", err
-		}
+	return nil
+}
 
-		return fmt.Sprintf("Network Name: %s\nConnected Peers: %v\n"+
-			"Validators Count: %v\nAccounts Count: %v\nCurrent Block Height: %v\nTotal Power: %v PAC\nTotal Committee Power: %v PAC\nCirculating Supply: %v PAC\n"+
-			"\n> Note📝: This info is from one random network node. Non-blockchain data may not be consistent.",
-			net.NetworkName,
-			utils.FormatNumber(int64(net.ConnectedPeersCount)),
-			utils.FormatNumber(int64(net.ValidatorsCount)),
-			utils.FormatNumber(int64(net.TotalAccounts)),
-			utils.FormatNumber(int64(net.CurrentBlockHeight)),
-			utils.FormatNumber(int64(util.ChangeToCoin(net.TotalNetworkPower))),
-			utils.FormatNumber(int64(util.ChangeToCoin(net.TotalCommitteePower))),
-			utils.FormatNumber(int64(util.ChangeToCoin(net.CirculatingSupply))),
-		), nil
-
-	case CmdBotWallet:
-		addr, blnc := be.BotWallet()
-		return fmt.Sprintf("Address: https://pacscan.org/address/%s\nBalance: %v PAC\n", addr, utils.FormatNumber(int64(util.ChangeToCoin(blnc)))), nil
-
-	case CmdClaimStatus:
-		cs := be.ClaimStatus()
-		return fmt.Sprintf("Claimed rewards count: %v\nClaimed coins: %v PAC's\nNot-claimed rewards count: %v\nNot-claim coins: %v PAC's\n",
-			cs.Claimed, util.ChangeToString(cs.ClaimedAmount), cs.NotClaimed, util.ChangeToString(cs.NotClaimedAmount)), nil
-
-	case CmdRewardCalc:
-		if err := CheckArgs(2, args); err != nil {
-			return "", err
-		}
+// timeoutOf returns the deadline of the command.
+// The configured timeout of the command has priority over the default timeout of the command.
+func (be *BotEngine) timeoutOf(cmd *Command) time.Duration {
+	if timeout, ok := be.commandTimeouts[cmd.Name]; ok {
+		return timeout
+	}
 
-		stake, err := strconv.Atoi(args[0])
-		if err != nil {
-			return "", err
-		}
+	if cmd.Timeout > 0 {
+		return cmd.Timeout
+	}
 
-		reward, time, totalPower, err := be.RewardCalculate(int64(stake), args[1])
-		if err != nil {
-			return "", err
-		}
+	if be.commandTimeout > 0 {
+		return be.commandTimeout
+	}
 
-		return fmt.Sprintf("Approximately you earn %v PAC reward, with %v PAC stake 🔒 on your validator in one %s ⏰ with %v PAC total power ⚡ of committee."+
-			"\n\n> Note📝: This is an estimation and the number can get changed by changes of your stake amount, total power and ...",
-			utils.FormatNumber(reward), utils.FormatNumber(int64(stake)), time, utils.FormatNumber(totalPower)), nil
+	return defaultCommandTimeout
+}
+
+func (be *BotEngine) helpHandler(ctx context.Context, _ map[string]string) (*Result, error) {
+	res := newResult(StatusInfo, "RoboPac is a robot that provides support and information about the Pactus Blockchain.\n"+
+		"Here is a list of commands supported by RoboPac:")
 
-	case CmdBoosterPayment:
-		if err := CheckArgs(3, args); err != nil {
-			return "", err
+	for _, cmd := range be.commands {
+		if cmd.Name == CmdHelp || cmd.Role != RoleAnyone {
+			continue
 		}
+		res.addField("/"+cmd.SlashName(), cmd.Desc)
+	}
+
+	return res, nil
+}
+
+func (be *BotEngine) claimHandler(ctx context.Context, args map[string]string) (*Result, error) {
+	txHash, err := be.Claim(ctx, args["discord-id"], args["testnet-addr"], args["mainnet-addr"])
+	if err != nil {
+		return nil, err
+	}
+
+	res := newResult(StatusSuccess, "Reward claimed successfully✅").
+		addLink("Your claim transaction", txLink(txHash))
+	res.TxID = txHash
+	res.Data = map[string]string{"tx_id": txHash}
+
+	return res, nil
+}
+
+func (be *BotEngine) claimerInfoHandler(ctx context.Context, args map[string]string) (*Result, error) {
+	testnetAddr := args["testnet-addr"]
+	claimer, err := be.ClaimerInfo(ctx, testnetAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	res := newResult(StatusInfo, "").
+		addField("TestNet Address", testnetAddr).
+		addField("Amount", fmt.Sprintf("%v PACs", util.ChangeToString(claimer.TotalReward))).
+		addField("IsClaimed", claimer.IsClaimed())
+	if claimer.IsClaimed() {
+		res.addField("Transaction Status", be.txStatus(claimer.ClaimedTxID)).
+			addLink("Claim transaction", txLink(claimer.ClaimedTxID))
+	}
+	res.Data = claimer
+
+	return res, nil
+}
+
+func (be *BotEngine) networkHealthHandler(ctx context.Context, _ map[string]string) (*Result, error) {
+	health, err := be.NetworkHealth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := newResult(StatusSuccess, "Network is Healthy✅")
+	if !health.HealthStatus {
+		res = newResult(StatusDanger, "Network is UnHealthy❌")
+	}
+
+	res.addField("CurrentTime", health.CurrentTime.Format("02/01/2006, 15:04:05")).
+		addField("LastBlockTime", health.LastBlockTime.Format("02/01/2006, 15:04:05")).
+		addField("Time Diff", health.TimeDifference).
+		addField("Last Block Height", utils.FormatNumber(int64(health.LastBlockHeight)))
+	for _, node := range health.Nodes {
+		res.addField(node.Name, describeNodeHealth(node))
+	}
+	if len(health.Cache) > 0 {
+		res.addField("Cache Hit Rate", describeCacheStats(health.Cache))
+	}
+	res.Data = health
+
+	return res, nil
+}
+
+// describeNodeHealth describes the health of an RPC node in one line.
+func describeNodeHealth(node *client.NodeHealth) string {
+	switch {
+	case node.CircuitOpen:
+		return fmt.Sprintf("❌ left out until %s, last error: %s",
+			node.OpenUntil.Format("15:04:05"), node.LastError)
+
+	case !node.IsHealthy():
+		return fmt.Sprintf("⚠️ %d blocks behind, height %d", node.HeightLag, node.Height)
+
+	default:
+		return fmt.Sprintf("✅ height %d, latency %s", node.Height, node.Latency.Round(time.Millisecond))
+	}
+}
+
+// describeCacheStats describes the total hit rate of the cached chain queries, and the hit rate of each query.
+func describeCacheStats(stats []*client.CacheStats) string {
+	total := &client.CacheStats{}
+	queries := make([]string, 0, len(stats))
+	for _, s := range stats {
+		total.Hits += s.Hits
+		total.Misses += s.Misses
+		queries = append(queries, fmt.Sprintf("%s %.0f%%", s.Query, 100*s.HitRate()))
+	}
+
+	return fmt.Sprintf("%.0f%% of %d calls (%s)", 100*total.HitRate(), total.Hits+total.Misses,
+		strings.Join(queries, ", "))
+}
+
+func (be *BotEngine) nodeInfoHandler(ctx context.Context, args map[string]string) (*Result, error) {
+	nodeInfo, err := be.NodeInfo(ctx, args["validator-address"])
+	if err != nil {
+		return nil, err
+	}
+
+	var pip19Score string
+	if nodeInfo.AvailabilityScore >= 0.9 {
+		pip19Score = fmt.Sprintf("%v✅", nodeInfo.AvailabilityScore)
+	} else {
+		pip19Score = fmt.Sprintf("%v⚠️", nodeInfo.AvailabilityScore)
+	}
+
+	res := newResult(StatusInfo, "").
+		addField("PeerID", nodeInfo.PeerID).
+		addField("IP Address", nodeInfo.IPAddress).
+		addField("Agent", nodeInfo.Agent).
+		addField("Moniker", nodeInfo.Moniker).
+		addField("Country", nodeInfo.Country).
+		addField("City", nodeInfo.City).
+		addField("Region Name", nodeInfo.RegionName).
+		addField("TimeZone", nodeInfo.TimeZone).
+		addField("ISP", nodeInfo.ISP).
+		addField("Validator Number", utils.FormatNumber(int64(nodeInfo.ValidatorNum))).
+		addField("PIP-19 Score", pip19Score).
+		addField("Stake", fmt.Sprintf("%v PAC's", utils.FormatNumber(int64(util.ChangeToCoin(nodeInfo.StakeAmount)))))
+	res.Data = nodeInfo
+
+	return res, nil
+}
+
+func (be *BotEngine) networkStatusHandler(ctx context.Context, _ map[string]string) (*Result, error) {
+	net, err := be.NetworkStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		discordID := args[0]
-		twitterName := args[1]
-		valAddr := args[2]
+	res := newResult(StatusInfo, "").
+		addField("Network Name", net.NetworkName).
+		addField("Connected Peers", utils.FormatNumber(int64(net.ConnectedPeersCount))).
+		addField("Validators Count", utils.FormatNumber(int64(net.ValidatorsCount))).
+		addField("Accounts Count", utils.FormatNumber(int64(net.TotalAccounts))).
+		addField("Current Block Height", utils.FormatNumber(int64(net.CurrentBlockHeight))).
+		addField("Total Power", fmt.Sprintf("%v PAC", utils.FormatNumber(int64(util.ChangeToCoin(net.TotalNetworkPower))))).
+		addField("Total Committee Power", fmt.Sprintf("%v PAC", utils.FormatNumber(int64(util.ChangeToCoin(net.TotalCommitteePower))))).
+		addField("Circulating Supply", fmt.Sprintf("%v PAC", utils.FormatNumber(int64(util.ChangeToCoin(net.CirculatingSupply)))))
+	res.Note = "This info is from the healthiest network node, run network-consistency to compare all the nodes. " +
+		"Non-blockchain data may not be consistent."
+	res.Data = net
+
+	return res, nil
+}
+
+func (be *BotEngine) networkConsistencyHandler(ctx context.Context, _ map[string]string) (*Result, error) {
+	report, err := be.NetworkConsistency(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		party, err := be.BoosterPayment(discordID, twitterName, valAddr)
-		if err != nil {
-			return "", err
+	var res *Result
+	switch {
+	case len(report.Forked) > 0:
+		res = newResult(StatusDanger, fmt.Sprintf("%d nodes are on a possible fork❌", len(report.Forked)))
+	case !report.IsConsistent():
+		res = newResult(StatusWarning, "Some nodes are lagging, unreachable or don't agree with the others⚠️")
+	default:
+		res = newResult(StatusSuccess, "All the nodes agree on the blockchain✅")
+	}
+
+	res.addField("Majority Height", utils.FormatNumber(int64(report.MajorityHeight))).
+		addField("Common Height", utils.FormatNumber(int64(report.CommonHeight)))
+	for _, snapshot := range report.Snapshots {
+		res.addField(snapshot.Name, describeSnapshot(report, snapshot))
+	}
+	res.Data = report
+
+	return res, nil
+}
+
+func (be *BotEngine) botWalletHandler(ctx context.Context, _ map[string]string) (*Result, error) {
+	addr, blnc := be.BotWallet(ctx)
+
+	res := newResult(StatusInfo, "").
+		addField("Balance", fmt.Sprintf("%v PAC", utils.FormatNumber(int64(util.ChangeToCoin(blnc))))).
+		addLink("Address", fmt.Sprintf("https://pacscan.org/address/%s", addr))
+	res.Data = map[string]any{"address": addr, "balance": blnc}
+
+	return res, nil
+}
+
+func (be *BotEngine) claimStatusHandler(ctx context.Context, _ map[string]string) (*Result, error) {
+	cs := be.ClaimStatus(ctx)
+
+	res := newResult(StatusInfo, "").
+		addField("Claimed rewards count", cs.Claimed).
+		addField("Claimed coins", fmt.Sprintf("%v PAC's", util.ChangeToString(cs.ClaimedAmount))).
+		addField("Not-claimed rewards count", cs.NotClaimed).
+		addField("Not-claim coins", fmt.Sprintf("%v PAC's", util.ChangeToString(cs.NotClaimedAmount)))
+	res.Data = cs
+
+	return res, nil
+}
+
+func (be *BotEngine) rewardCalcHandler(ctx context.Context, args map[string]string) (*Result, error) {
+	stake, err := strconv.Atoi(args["stake"])
+	if err != nil {
+		return nil, err
+	}
+
+	reward, time, totalPower, err := be.RewardCalculate(ctx, int64(stake), args["time"])
+	if err != nil {
+		return nil, err
+	}
+
+	res := newResult(StatusInfo, fmt.Sprintf("Approximately you earn %v PAC reward, with %v PAC stake 🔒 on your validator in one %s ⏰ with %v PAC total power ⚡ of committee.",
+		utils.FormatNumber(reward), utils.FormatNumber(int64(stake)), time, utils.FormatNumber(totalPower)))
+	res.Note = "This is an estimation and the number can get changed by changes of your stake amount, total power and ..."
+	res.Data = map[string]any{"reward": reward, "stake": stake, "time": time, "total_power": totalPower}
+
+	return res, nil
+}
+
+func (be *BotEngine) boosterPaymentHandler(ctx context.Context, args map[string]string) (*Result, error) {
+	party, err := be.CampaignPayment(ctx, args["campaign"], args["discord-id"], args["twitter-username"],
+		args["validator-address"])
+	if err != nil {
+		return nil, err
+	}
+
+	expiryDate := be.paymentExpiry(args["campaign"], party)
+	res := newResult(StatusInfo, fmt.Sprintf("Validator `%s` registered to receive %v stake-PAC coins in total price of $%v."+
+		" Visit the payment link to pay it.",
+		party.ValAddr, party.AmountInPAC, party.TotalPrice)).
+		addField("Discount code expiry", expiryDate.Format("2006-01-02")).
+		addLink("Payment", be.paymentLink(args["campaign"], party))
+	res.Title = be.campaignTitle(args["campaign"])
+	res.Data = party
+
+	return res, nil
+}
+
+func (be *BotEngine) boosterClaimHandler(ctx context.Context, args map[string]string) (*Result, error) {
+	party, err := be.CampaignClaim(ctx, args["campaign"], args["twitter-username"])
+	if err != nil {
+		return nil, err
+	}
+
+	var res *Result
+	if party.PaymentFinished {
+		res = newResult(StatusSuccess, fmt.Sprintf("Validator `%s` received %v stake-PAC coins.",
+			party.ValAddr, party.AmountInPAC)).
+			addField("Transaction Status", be.txStatus(party.TransactionID)).
+			addLink("Transaction", txLink(party.TransactionID))
+		res.TxID = party.TransactionID
+	} else {
+		expiryDate := be.paymentExpiry(args["campaign"], party)
+		res = newResult(StatusWarning, fmt.Sprintf("Validator `%s` registered to receive %v stake-PAC coins in total price of $%v."+
+			" Visit the payment link and pay the total amount.",
+			party.ValAddr, party.AmountInPAC, party.TotalPrice)).
+			addField("Discount code expiry", expiryDate.Format("2006-01-02")).
+			addLink("Payment", be.paymentLink(args["campaign"], party))
+		if party.PaidAmount > 0 {
+			res.addField("Paid Amount", fmt.Sprintf("$%.2f", party.PaidAmount))
 		}
-		expiryDate := time.Unix(party.CreatedAt, 0).AddDate(0, 0, 7)
-		msg := fmt.Sprintf("Validator `%s` registered to receive %v stake-PAC coins in total price of $%v."+
-			" Visit https://nowpayments.io/payment/?iid=%v to pay it."+
-			" The Discount code will expire on %v",
-			party.ValAddr, party.AmountInPAC, party.TotalPrice, party.NowPaymentsInvoiceID, expiryDate.Format("2006-01-02"))
-		return msg, nil
-
-	case CmdBoosterClaim:
-		if err := CheckArgs(1, args); err != nil {
-			return "", err
+		if party.PaymentCase == store.PaymentUnderpaid {
+			res.Note = "Your payment is less than the total price, the support team will contact you to top it up or refund it."
 		}
+	}
+	res.Title = be.campaignTitle(args["campaign"])
+	res.Data = party
 
-		twitterName := args[0]
-		party, err := be.BoosterClaim(twitterName)
-		if err != nil {
-			return "", err
-		}
+	return res, nil
+}
 
-		var msg string
-		if party.NowPaymentsFinished {
-			msg = fmt.Sprintf("Validator `%s` received %v stake-PAC coins."+
-				" Transaction: https://pacscan.org/transactions/%v.",
-				party.ValAddr, party.AmountInPAC, party.TransactionID)
-		} else {
-			expiryDate := time.Unix(party.CreatedAt, 0).AddDate(0, 0, 7)
-			msg = fmt.Sprintf("Validator `%s` registered to receive %v stake-PAC coins in total price of $%v."+
-				" Visit https://nowpayments.io/payment/?iid=%v and pay the total amount."+
-				" The Discount code will expire on %v",
-				party.ValAddr, party.AmountInPAC, party.TotalPrice, party.NowPaymentsInvoiceID, expiryDate.Format("2006-01-02"))
-		}
-		return msg, nil
+func (be *BotEngine) boosterWhitelistHandler(ctx context.Context, args map[string]string) (*Result, error) {
+	twitterName := args["twitter-username"]
+	err := be.CampaignWhitelist(ctx, args["campaign"], twitterName, args["authorized-discord-id"])
+	if err != nil {
+		return nil, err
+	}
 
-	case CmdBoosterWhitelist:
-		if err := CheckArgs(2, args); err != nil {
-			return "", err
-		}
+	res := newResult(StatusSuccess, fmt.Sprintf("Twitter `%s` whitelisted", twitterName))
+	res.Title = be.campaignTitle(args["campaign"])
 
-		twitterName := args[0]
-		authorizedDiscordID := args[1]
-		err := be.BoosterWhitelist(twitterName, authorizedDiscordID)
-		if err != nil {
-			return "", err
-		}
-		msg := fmt.Sprintf("Twitter `%s` whitelisted", twitterName)
-		return msg, nil
+	return res, nil
+}
 
-	case CmdBoosterStatus:
-		bs := be.BoosterStatus()
+func (be *BotEngine) boosterStatusHandler(ctx context.Context, args map[string]string) (*Result, error) {
+	bs, err := be.CampaignStatus(ctx, args["campaign"])
+	if err != nil {
+		return nil, err
+	}
 
-		return fmt.Sprintf("Total Coins: %v PAC\nTotal Packages: %v\nClaimed Packages: %v\nUnClaimed Packages: %v\nPayment Done: %v\nPayment Waiting: %v\nWhite Listed: %v\n",
-			bs.Pac, bs.AllPkgs, bs.ClaimedPkgs, bs.UnClaimedPkgs, bs.PaymentDone, bs.PaymentWaiting, bs.Whitelists), nil
+	res := newResult(StatusInfo, "").
+		addField("Total Coins", fmt.Sprintf("%v PAC", bs.Pac)).
+		addField("Total Packages", bs.AllPkgs).
+		addField("Claimed Packages", bs.ClaimedPkgs).
+		addField("UnClaimed Packages", bs.UnClaimedPkgs).
+		addField("Payment Done", bs.PaymentDone).
+		addField("Payment Waiting", bs.PaymentWaiting).
+		addField("Expired", bs.Expired).
+		addField("White Listed", bs.Whitelists)
+	res.Title = be.campaignTitle(args["campaign"])
+	res.Data = bs
+
+	return res, nil
+}
 
-	default:
-		return "", fmt.Errorf("unknown command: %s", cmd)
+func (be *BotEngine) txReviewHandler(_ context.Context, _ map[string]string) (*Result, error) {
+	be.RLock()
+	txs := be.store.ReviewTransactions()
+	be.RUnlock()
+
+	if len(txs) == 0 {
+		return newResult(StatusSuccess, "No transaction needs review✅"), nil
+	}
+
+	res := newResult(StatusWarning, fmt.Sprintf("%d transactions are not confirmed in time, "+
+		"check them before sending them again.", len(txs)))
+	for _, tx := range txs {
+		res.addField(fmt.Sprintf("%s: %s", tx.Kind, tx.Ref),
+			fmt.Sprintf("%s, sent at %s", txLink(tx.TxID), time.Unix(tx.SentAt, 0).Format("2006-01-02 15:04:05")))
 	}
+	res.Data = txs
+
+	return res, nil
 }
 
-func (be *BotEngine) parseQuery(query string) (string, []string) {
-	subs := strings.Split(query, " ")
-	if len(subs) == 0 {
-		return "", nil
+func (be *BotEngine) paymentReportHandler(ctx context.Context, _ map[string]string) (*Result, error) {
+	report := be.reconcileInvoices(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	res := newResult(StatusSuccess, "All the payments match their bond transactions✅")
+	if len(report.Mismatches) > 0 {
+		res = newResult(StatusWarning, fmt.Sprintf("%d payments don't match their bond transactions, "+
+			"check them manually.", len(report.Mismatches)))
+	}
+
+	res.addField("Checked Invoices", report.Checked).
+		addField("Updated Parties", report.Updated).
+		addField("Fulfilled Parties", report.Fulfilled).
+		addField("Failed Checks", report.Failed)
+	for _, m := range report.Mismatches {
+		res.addField(fmt.Sprintf("%s: %s", m.Campaign, m.TwitterName),
+			fmt.Sprintf("%s (discount code `%s`, invoice %s)", m.Reason, m.DiscountCode, m.PaymentLink))
+	}
+	for _, c := range report.Cases {
+		res.addField(fmt.Sprintf("%s: %s (to resolve)", c.Campaign, c.TwitterName),
+			fmt.Sprintf("%s (discount code `%s`, invoice %s)", c.Reason, c.DiscountCode, c.PaymentLink))
+	}
+	res.Data = report
+
+	return res, nil
+}
+
+func (be *BotEngine) paymentResolveHandler(ctx context.Context, args map[string]string) (*Result, error) {
+	party, err := be.ResolvePayment(ctx, args["campaign"], args["twitter-username"],
+		store.Resolution(args["resolution"]), &payment.Refund{
+			Address:  args["refund-address"],
+			Currency: args["refund-currency"],
+		})
+	if err != nil {
+		return nil, err
+	}
+	if party == nil || len(party.Adjustments) == 0 {
+		return nil, fmt.Errorf("the payment of `%s` is resolved, but its resolution is not recorded",
+			args["twitter-username"])
+	}
+
+	adjustment := party.Adjustments[len(party.Adjustments)-1]
+	res := newResult(StatusSuccess, fmt.Sprintf("The %s payment of `%s` is resolved by %s.",
+		adjustment.Case, party.TwitterName, adjustment.Resolution)).
+		addField("Paid Amount", fmt.Sprintf("$%.2f", party.PaidAmount)).
+		addField("Total Price", fmt.Sprintf("$%d", party.TotalPrice)).
+		addField("Status", party.Status)
+	switch adjustment.Resolution {
+	case store.ResolutionTopUp:
+		res.addField("Top-up Amount", fmt.Sprintf("$%.0f", adjustment.Amount)).
+			addLink("Payment", be.paymentLink(args["campaign"], party))
+	case store.ResolutionConvert:
+		res.addField("Package", fmt.Sprintf("%d PAC", party.AmountInPAC))
+		if party.TransactionID != "" {
+			res.addLink("Transaction", txLink(party.TransactionID))
+		}
+	case store.ResolutionRefund:
+		res.addField("Refunded Amount", fmt.Sprintf("$%.2f", adjustment.Amount)).
+			addField("Refund ID", adjustment.RefundID)
 	}
+	res.Title = be.campaignTitle(args["campaign"])
+	res.Data = party
 
-	return subs[0], subs[1:]
+	return res, nil
 }
@@ -1,6 +1,10 @@
 package engine
 
-import "time"
+import (
+	"time"
+
+	"github.com/kehiy/RoboPac/client"
+)
 
 type NetHealthResponse struct {
 	HealthStatus    bool
@@ -8,6 +12,10 @@ type NetHealthResponse struct {
 	LastBlockTime   time.Time
 	LastBlockHeight uint32
 	TimeDifference  int64
+	// Nodes are the health of the RPC nodes that the bot is connected to.
+	Nodes []*client.NodeHealth
+	// Cache are the hits and the misses of the cached chain queries.
+	Cache []*client.CacheStats
 }
 
 type NetStatus struct {
@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kehiy/RoboPac/client"
+	"github.com/kehiy/RoboPac/store"
+	"github.com/kehiy/RoboPac/utils"
+)
+
+// trackTransaction adds a payout transaction to the tracker.
+// Simulated transactions are never broadcast, so they are not tracked.
+// The caller should hold the engine lock.
+func (be *BotEngine) trackTransaction(kind, ref, txID string) {
+	if be.dryRun {
+		return
+	}
+
+	tx := &store.Transaction{
+		TxID:   txID,
+		Kind:   kind,
+		Ref:    ref,
+		Status: store.TxStatusPending,
+		SentAt: time.Now().Unix(),
+	}
+
+	if err := be.store.SaveTransaction(tx); err != nil {
+		be.logger.Error("unable to track the transaction",
+			"error", err,
+			"kind", kind,
+			"ref", ref,
+			"txID", txID,
+		)
+	}
+}
+
+func (be *BotEngine) trackTransactions() {
+	ticker := time.NewTicker(be.txTrackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-be.ctx.Done():
+			return
+
+		case <-ticker.C:
+			be.checkTransactions(be.ctx)
+		}
+	}
+}
+
+// blockEventsQueueSize is the number of the transaction events that can wait to be checked by the tracker.
+const blockEventsQueueSize = 100
+
+// confirmTransactions confirms the pending transactions as soon as they are seen in a new block,
+// so they don't wait for the next check of the tracker.
+func (be *BotEngine) confirmTransactions(sub *client.Subscription) {
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-be.ctx.Done():
+			return
+
+		case e := <-sub.C:
+			be.confirmTransaction(e)
+		}
+	}
+}
+
+func (be *BotEngine) confirmTransaction(e *client.Event) {
+	be.Lock()
+	defer be.Unlock()
+
+	pending := be.store.TransactionInfo(e.TxID)
+	if pending == nil || pending.Status != store.TxStatusPending {
+		return
+	}
+
+	tx := *pending
+	tx.Status = store.TxStatusConfirmed
+	tx.Height = e.Height
+	tx.CheckedAt = time.Now().Unix()
+	if err := be.store.SaveTransaction(&tx); err != nil {
+		be.logger.Error("unable to update the transaction", "error", err, "txID", tx.TxID)
+
+		return
+	}
+	be.logger.Info("transaction confirmed", "txID", tx.TxID, "kind", tx.Kind, "height", tx.Height)
+}
+
+// checkTransactions looks up the pending transactions on the blockchain.
+// Found transactions are confirmed with their block height. Transactions that are not found
+// before the confirmation timeout are marked as failed and flagged for operator review.
+func (be *BotEngine) checkTransactions(ctx context.Context) {
+	be.RLock()
+	pendings := be.store.PendingTransactions()
+	be.RUnlock()
+
+	for _, pending := range pendings {
+		if ctx.Err() != nil {
+			return
+		}
+
+		tx := *pending
+		tx.CheckedAt = time.Now().Unix()
+
+		txData, err := be.clientMgr.GetTransactionData(ctx, tx.TxID)
+		switch {
+		case err == nil:
+			tx.Status = store.TxStatusConfirmed
+			tx.Height = txData.BlockHeight
+			be.logger.Info("transaction confirmed", "txID", tx.TxID, "kind", tx.Kind, "height", tx.Height)
+
+		case time.Since(time.Unix(tx.SentAt, 0)) > be.txConfirmTimeout:
+			tx.Status = store.TxStatusFailed
+			tx.NeedsReview = true
+			be.logger.Warn("transaction is not confirmed, it needs operator review",
+				"error", err,
+				"txID", tx.TxID,
+				"kind", tx.Kind,
+				"ref", tx.Ref,
+			)
+
+		default:
+			continue
+		}
+
+		be.Lock()
+		err = be.store.SaveTransaction(&tx)
+		be.Unlock()
+		if err != nil {
+			be.logger.Error("unable to update the transaction", "error", err, "txID", tx.TxID)
+		}
+	}
+}
+
+// txStatus returns the text form of the transaction status.
+func (be *BotEngine) txStatus(txID string) string {
+	be.RLock()
+	defer be.RUnlock()
+
+	tx := be.store.TransactionInfo(txID)
+	if tx == nil {
+		return "unknown"
+	}
+
+	switch tx.Status {
+	case store.TxStatusConfirmed:
+		return fmt.Sprintf("confirmed✅ at height %s", utils.FormatNumber(int64(tx.Height)))
+	case store.TxStatusFailed:
+		return "not confirmed❌, under operator review"
+	case store.TxStatusPending:
+		return "pending⏳"
+	default:
+		return string(tx.Status)
+	}
+}
@@ -0,0 +1,206 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is the progress of a payout.
+type State string
+
+const (
+	// StateIntent is recorded before signing the transaction, nothing is sent yet.
+	StateIntent State = "intent"
+	// StateSigned is recorded after signing, the transaction may or may not be broadcast.
+	StateSigned State = "signed"
+	// StateBroadcast is recorded after the transaction is broadcast, but the store is not updated yet.
+	StateBroadcast State = "broadcast"
+	// StateDone is recorded after the store is updated.
+	StateDone State = "done"
+	// StateAborted is recorded when the payout is stopped before anything is sent.
+	StateAborted State = "aborted"
+)
+
+// ErrDone is returned when a payout that is already done is recorded again.
+var ErrDone = errors.New("the payout is already done")
+
+// Entry is one payout in the journal.
+// Key identifies the payout, so each reward is paid at most once, e.g. `claim:tpc1p...`.
+type Entry struct {
+	Key       string `json:"key"`
+	Kind      string `json:"kind"`
+	Ref       string `json:"ref"`
+	DiscordID string `json:"discord_id,omitempty"`
+	Receiver  string `json:"receiver"`
+	Amount    int64  `json:"amount"`
+	State     State  `json:"state"`
+	TxID      string `json:"tx_id,omitempty"`
+	RawTx     []byte `json:"raw_tx,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+
+	// DryRun is set for the simulated payouts, that must never be sent.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// IsFinished returns true if nothing is left to do for the payout.
+func (e *Entry) IsFinished() bool {
+	return e.State == StateDone || e.State == StateAborted
+}
+
+// Key returns the journal key of a payout.
+func Key(kind, ref string) string {
+	return fmt.Sprintf("%s:%s", kind, ref)
+}
+
+// Journal is a write-ahead log of the payouts.
+// Each change of an entry is appended to the file and synced before the next step of the payout,
+// so after a crash the last state of every payout can be recovered.
+type Journal struct {
+	lk sync.RWMutex
+
+	path    string
+	entries map[string]*Entry
+}
+
+// Open opens the journal at the given path and replays it. The file is created if it doesn't exist.
+func Open(path string) (*Journal, error) {
+	j := &Journal{
+		path:    path,
+		entries: make(map[string]*Entry),
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return j, nil
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		entry := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		j.entries[entry.Key] = entry
+	}
+
+	return j, scanner.Err()
+}
+
+// Record writes the new state of the entry to the journal.
+// The creation time is set on the first record. The entry is copied,
+// so the caller can keep changing it for the next steps.
+// A done payout is never replaced, otherwise its amount would be lost from the spent totals.
+func (j *Journal) Record(entry *Entry) error {
+	j.lk.Lock()
+	defer j.lk.Unlock()
+
+	if last, ok := j.entries[entry.Key]; ok && last.State == StateDone {
+		return fmt.Errorf("%w: %s", ErrDone, entry.Key)
+	}
+
+	now := time.Now().Unix()
+	if entry.CreatedAt == 0 {
+		entry.CreatedAt = now
+	}
+
+	cpy := *entry
+	cpy.UpdatedAt = now
+
+	data, err := json.Marshal(cpy)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	j.entries[cpy.Key] = &cpy
+
+	return nil
+}
+
+// Find returns a copy of the last state of the payout, or nil if it is not in the journal.
+func (j *Journal) Find(key string) *Entry {
+	j.lk.RLock()
+	defer j.lk.RUnlock()
+
+	entry, ok := j.entries[key]
+	if !ok {
+		return nil
+	}
+	cpy := *entry
+
+	return &cpy
+}
+
+// Unfinished returns copies of the payouts that are not done or aborted, from the oldest to the newest.
+func (j *Journal) Unfinished() []*Entry {
+	j.lk.RLock()
+	defer j.lk.RUnlock()
+
+	entries := []*Entry{}
+	for _, entry := range j.entries {
+		if !entry.IsFinished() {
+			cpy := *entry
+			entries = append(entries, &cpy)
+		}
+	}
+
+	sort.Slice(entries, func(i, k int) bool {
+		return entries[i].UpdatedAt < entries[k].UpdatedAt
+	})
+
+	return entries
+}
+
+// Spent returns the total amount of the payouts created since the given time, except the aborted ones.
+// If discordID is not empty, only the payouts of that user are counted.
+func (j *Journal) Spent(since time.Time, discordID string) int64 {
+	j.lk.RLock()
+	defer j.lk.RUnlock()
+
+	total := int64(0)
+	for _, entry := range j.entries {
+		if entry.State == StateAborted || entry.CreatedAt < since.Unix() {
+			continue
+		}
+
+		if discordID != "" && entry.DiscordID != discordID {
+			continue
+		}
+		total += entry.Amount
+	}
+
+	return total
+}
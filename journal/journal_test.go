@@ -0,0 +1,90 @@
+package journal
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	journalPath := path.Join(t.TempDir(), "payout_journal.jsonl")
+
+	j, err := Open(journalPath)
+	assert.NoError(t, err)
+	assert.Nil(t, j.Find(Key("claim", "addr-1")))
+
+	entry := &Entry{Key: Key("claim", "addr-1"), Kind: "claim", Ref: "addr-1", Amount: 10, State: StateIntent}
+	assert.NoError(t, j.Record(entry))
+
+	entry.State = StateSigned
+	entry.TxID = "tx-1"
+	entry.RawTx = []byte{1, 2, 3}
+	assert.NoError(t, j.Record(entry))
+
+	other := &Entry{Key: Key("booster", "abcd"), Kind: "booster", Ref: "abcd", Amount: 5, State: StateDone}
+	assert.NoError(t, j.Record(other))
+
+	t.Run("found entries are copies", func(t *testing.T) {
+		found := j.Find("claim:addr-1")
+		found.State = StateAborted
+
+		assert.Equal(t, StateSigned, j.Find("claim:addr-1").State)
+	})
+
+	t.Run("reopen replays the journal", func(t *testing.T) {
+		reopened, err := Open(journalPath)
+		assert.NoError(t, err)
+
+		found := reopened.Find("claim:addr-1")
+		assert.Equal(t, StateSigned, found.State)
+		assert.Equal(t, "tx-1", found.TxID)
+		assert.Equal(t, []byte{1, 2, 3}, found.RawTx)
+
+		unfinished := reopened.Unfinished()
+		assert.Len(t, unfinished, 1)
+		assert.Equal(t, "claim:addr-1", unfinished[0].Key)
+	})
+
+	t.Run("done payouts are not replaced", func(t *testing.T) {
+		again := &Entry{Key: Key("booster", "abcd"), Kind: "booster", Ref: "abcd", Amount: 20, State: StateIntent}
+		assert.ErrorIs(t, j.Record(again), ErrDone)
+		assert.Equal(t, StateDone, j.Find("booster:abcd").State)
+
+		reopened, err := Open(journalPath)
+		assert.NoError(t, err)
+		assert.Equal(t, StateDone, reopened.Find("booster:abcd").State)
+		assert.Equal(t, int64(5), reopened.Find("booster:abcd").Amount)
+	})
+
+	t.Run("corrupted journal", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(journalPath, []byte("{invalid"), 0o600))
+
+		_, err := Open(journalPath)
+		assert.ErrorContains(t, err, "line 1")
+	})
+}
+
+func TestSpent(t *testing.T) {
+	j, err := Open(path.Join(t.TempDir(), "payout_journal.jsonl"))
+	assert.NoError(t, err)
+
+	hourAgo := time.Now().Add(-time.Hour)
+	entries := []*Entry{
+		{Key: "claim:1", DiscordID: "user-1", Amount: 10, State: StateDone, CreatedAt: hourAgo.Add(-time.Minute).Unix()},
+		{Key: "claim:2", DiscordID: "user-1", Amount: 20, State: StateBroadcast},
+		{Key: "claim:3", DiscordID: "user-2", Amount: 40, State: StateSigned},
+		{Key: "claim:4", DiscordID: "user-2", Amount: 80, State: StateAborted},
+	}
+	for _, entry := range entries {
+		assert.NoError(t, j.Record(entry))
+	}
+
+	assert.Equal(t, int64(70), j.Spent(time.Time{}, ""))
+	assert.Equal(t, int64(60), j.Spent(hourAgo, ""))
+	assert.Equal(t, int64(30), j.Spent(time.Time{}, "user-1"))
+	assert.Equal(t, int64(20), j.Spent(hourAgo, "user-1"))
+	assert.Equal(t, int64(40), j.Spent(time.Time{}, "user-2"))
+}
@@ -1,11 +1,11 @@
 package nowpayments
 
 type Config struct {
-	Webhook    string
-	ListenPort string
-	APIToken   string
-	IPNSecret  string
-	APIUrl     string
-	Username   string
-	Password   string
+	// Webhook is the public URL of the IPN webhook, that is served by the webhook server on `/nowpayments`.
+	Webhook   string
+	APIToken  string
+	IPNSecret string
+	APIUrl    string
+	Username  string
+	Password  string
 }
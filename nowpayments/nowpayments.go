@@ -2,19 +2,61 @@ package nowpayments
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha512"
-	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 
+	"github.com/kehiy/RoboPac/payment"
 	"github.com/kehiy/RoboPac/store"
 	"github.com/pactus-project/pactus/util/logger"
 )
 
+// ProviderName is the name that the campaigns choose NowPayments by.
+const ProviderName = "nowpayments"
+
+// The statuses of the payments, from the NowPayments API.
+const (
+	PaymentWaiting       = "waiting"
+	PaymentConfirming    = "confirming"
+	PaymentConfirmed     = "confirmed"
+	PaymentSending       = "sending"
+	PaymentPartiallyPaid = "partially_paid"
+	PaymentFinished      = "finished"
+	PaymentFailed        = "failed"
+	PaymentRefunded      = "refunded"
+	PaymentExpired       = "expired"
+)
+
+// statusOf maps the NowPayments payment status to the invoice status.
+func statusOf(paymentStatus string) payment.Status {
+	switch paymentStatus {
+	case PaymentConfirming, PaymentConfirmed, PaymentSending:
+		return payment.StatusConfirming
+	case PaymentPartiallyPaid:
+		return payment.StatusPartiallyPaid
+	case PaymentFinished:
+		return payment.StatusFinished
+	case PaymentFailed:
+		return payment.StatusFailed
+	case PaymentRefunded:
+		return payment.StatusRefunded
+	case PaymentExpired:
+		return payment.StatusExpired
+	default:
+		return payment.StatusWaiting
+	}
+}
+
+// ErrInvalidSignature is returned when the IPN callback is not signed by the IPN secret.
+var ErrInvalidSignature = errors.New("invalid IPN signature")
+
 type NowPayments struct {
 	apiToken  string
 	ipnSecret []byte
@@ -22,36 +64,55 @@ type NowPayments struct {
 	apiURL    string
 	username  string
 	password  string
+
+	handlerLock sync.RWMutex
+	handler     payment.Handler
 }
 
+// NewNowPayments creates the NowPayments client. Its IPN webhook is served by the webhook server.
+// The IPN secret is used as it is to sign the callbacks, it is not decoded.
 func NewNowPayments(cfg *Config) (*NowPayments, error) {
-	ipnSecret, err := base64.StdEncoding.DecodeString(cfg.IPNSecret)
-	if err != nil {
-		return nil, err
+	if cfg.IPNSecret == "" {
+		logger.Warn("NowPayments IPN secret is not set, all the IPN callbacks are rejected")
 	}
-	s := &NowPayments{
+
+	return &NowPayments{
 		apiToken:  cfg.APIToken,
-		ipnSecret: ipnSecret,
+		ipnSecret: []byte(cfg.IPNSecret),
 		apiURL:    cfg.APIUrl,
 		webhook:   cfg.Webhook,
 		username:  cfg.Username,
 		password:  cfg.Password,
-	}
-	http.HandleFunc("/nowpayments", s.webhookFunc)
+	}, nil
+}
 
-	go func() {
-		for {
-			logger.Info("starting NowPayments webhook", "port", cfg.ListenPort)
-			err = http.ListenAndServe(fmt.Sprintf(":%v", cfg.ListenPort), nil)
-			if err != nil {
-				logger.Error("unable to start NowPayments webhook", "error", err)
-			}
-		}
-	}()
+func (*NowPayments) Name() string {
+	return ProviderName
+}
+
+// WebhookHandler returns the handler of the IPN callbacks.
+func (s *NowPayments) WebhookHandler() http.Handler {
+	return http.HandlerFunc(s.webhookFunc)
+}
+
+// SetEventHandler sets the handler of the payment events that are received through the IPN callbacks.
+func (s *NowPayments) SetEventHandler(handler payment.Handler) {
+	s.handlerLock.Lock()
+	defer s.handlerLock.Unlock()
+
+	s.handler = handler
+}
 
-	return s, nil
+func (s *NowPayments) eventHandler() payment.Handler {
+	s.handlerLock.RLock()
+	defer s.handlerLock.RUnlock()
+
+	return s.handler
 }
 
+// webhookFunc handles the IPN callbacks. The callbacks without a valid signature are rejected.
+// NowPayments retries the callbacks that are not answered with the status OK,
+// so the callback is accepted only when its event is handled.
 func (s *NowPayments) webhookFunc(w http.ResponseWriter, r *http.Request) {
 	logger.Debug("NowPayment webhook called")
 
@@ -63,56 +124,107 @@ func (s *NowPayments) webhookFunc(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logger.Debug("Callback result", "data", data)
-	msgMACHex := r.Header.Get("x-nowpayments-sig")
-	msgMAC, err := hex.DecodeString(msgMACHex)
+	err = s.verifySignature(data, r.Header.Get("x-nowpayments-sig"))
 	if err != nil {
-		logger.Error("Invalid sig hex", "error", err)
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(http.StatusUnauthorized)
+		logger.Error("rejecting the IPN callback", "error", err, "remote", r.RemoteAddr)
 		return
 	}
 
-	mac := hmac.New(sha512.New, s.ipnSecret)
-
-	var result map[string]interface{}
-	err = json.Unmarshal(data, &result)
+	payload := &ipnPayload{}
+	err = json.Unmarshal(data, payload)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		logger.Error("json.Unmarshal read error", "error", err)
 		return
 	}
-	// marshal it again, it will be sorted
-	sortedData, _ := json.Marshal(result)
 
-	if !bytes.Equal(sortedData, data) {
-		logger.Debug("data was not sorted")
+	event := &payment.Event{
+		Provider:   ProviderName,
+		OrderID:    payload.OrderID,
+		InvoiceID:  payload.InvoiceID.String(),
+		PaymentID:  payload.PaymentID.String(),
+		Status:     statusOf(payload.PaymentStatus),
+		PaidAmount: payload.paidAmount(),
+	}
+	handler := s.eventHandler()
+	if handler == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		logger.Warn("no payment handler is set, the IPN callback is rejected", "orderID", event.OrderID)
+		return
 	}
 
-	_, err = mac.Write(sortedData)
+	err = handler(event)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		logger.Error("mac.Write read error", "error", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		logger.Error("unable to handle the payment event", "error", err, "orderID", event.OrderID)
 		return
 	}
-	expectedMAC := mac.Sum(nil)
-	if !hmac.Equal(expectedMAC, msgMAC) {
-		/// TODO: fix me
-		// w.WriteHeader(http.StatusBadRequest)
-		logger.Error("HMAC is invalid", "expectedMAC", expectedMAC, "msgMAC", msgMAC)
-		// return
-	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
-func (s *NowPayments) CreatePayment(party *store.TwitterParty) error {
-	url := fmt.Sprintf("%v/v1/invoice", s.apiURL)
-	// jsonStr := fmt.Sprintf(`{"price_amount":%v,"price_currency":"usd","ipn_callback_url":"%v","order_id":"%v"}`,
-	// 	party.TotalPrice, s.webhook, party.DiscountCode)
+// verifySignature checks the HMAC-SHA512 signature of the callback.
+// The signature is calculated over the JSON body with its keys sorted.
+func (s *NowPayments) verifySignature(data []byte, msgMACHex string) error {
+	if len(s.ipnSecret) == 0 {
+		return fmt.Errorf("%w: no IPN secret is set", ErrInvalidSignature)
+	}
+
+	msgMAC, err := hex.DecodeString(msgMACHex)
+	if err != nil || len(msgMAC) == 0 {
+		return fmt.Errorf("%w: invalid signature hex", ErrInvalidSignature)
+	}
+
+	sortedData, err := sortJSON(data)
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha512.New, s.ipnSecret)
+	_, err = mac.Write(sortedData)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(mac.Sum(nil), msgMAC) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
 
+// sortJSON marshals the JSON data again with its keys sorted.
+// The numbers are kept as they are, so they are not changed by the float conversion.
+func sortJSON(data []byte) ([]byte, error) {
+	var result map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(result); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// CreateInvoice creates the invoice of the party, the discount code is the order ID of the invoice.
+func (s *NowPayments) CreateInvoice(ctx context.Context, party *store.TwitterParty) error {
+	url := fmt.Sprintf("%v/v1/invoice", s.apiURL)
 	jsonStr := fmt.Sprintf(`{"price_amount":%v,"price_currency":"usd","order_id":"%v"}`,
 		party.TotalPrice, party.DiscountCode)
+	if s.webhook != "" {
+		jsonStr = fmt.Sprintf(`{"price_amount":%v,"price_currency":"usd","ipn_callback_url":"%v","order_id":"%v"}`,
+			party.TotalPrice, s.webhook, party.DiscountCode)
+	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(jsonStr)))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(jsonStr)))
 	if err != nil {
 		return err
 	}
@@ -144,67 +256,161 @@ func (s *NowPayments) CreatePayment(party *store.TwitterParty) error {
 		return err
 	}
 
-	party.NowPaymentsInvoiceID = resultJSON["id"].(string)
+	invoiceID, ok := resultJSON["id"].(string)
+	if !ok {
+		return errors.New("invalid NowPayments:CreatePayment response")
+	}
+	party.InvoiceID = invoiceID
 
 	return nil
 }
 
-func (s *NowPayments) UpdatePayment(party *store.TwitterParty) error {
-	token, err := s.getJWTToken()
+// PaymentLink returns the link of the invoice page.
+func (*NowPayments) PaymentLink(party *store.TwitterParty) string {
+	return fmt.Sprintf("https://nowpayments.io/payment/?iid=%v", party.InvoiceID)
+}
+
+// Refund creates a payout of the refund amount to the address of the user.
+// NowPayments asks for the 2FA code of the payouts, so the refund should be verified on the dashboard
+// before it is sent.
+func (s *NowPayments) Refund(ctx context.Context, party *store.TwitterParty, refund *payment.Refund) (string, error) {
+	token, err := s.getJWTToken(ctx)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"withdrawals": []map[string]any{{
+			"address":            refund.Address,
+			"currency":           refund.Currency,
+			"fiat_amount":        refund.Amount,
+			"fiat_currency":      "usd",
+			"unique_external_id": refund.ID,
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%v/v1/payout", s.apiURL)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", s.apiToken)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	logger.Info("calling NowPayments:Payout", "twitter", party.TwitterName, "amount", refund.Amount)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Debug("Payout Response", "res", string(data))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to call NowPayments:Payout. Status code: %v, status: %v", resp.StatusCode, resp.Status)
+	}
+
+	var resultJSON map[string]interface{}
+	err = json.Unmarshal(data, &resultJSON)
+	if err != nil {
+		return "", err
+	}
+
+	refundID, ok := resultJSON["id"].(string)
+	if !ok {
+		return "", errors.New("invalid NowPayments:Payout response")
+	}
+
+	return refundID, nil
+}
+
+// InvoiceStatus returns the status of the invoice of the party, from the status of its payments.
+// The user may pay the invoice in more than one payment, so the paid amount is the sum of them.
+func (s *NowPayments) InvoiceStatus(ctx context.Context, party *store.TwitterParty) (*payment.InvoiceStatus, error) {
+	token, err := s.getJWTToken(ctx)
+	if err != nil {
+		return nil, err
 	}
 	url := fmt.Sprintf("%v/v1/payment/?invoiceId=%v",
-		s.apiURL, party.NowPaymentsInvoiceID)
-	fmt.Println(url)
-	req, err := http.NewRequest("GET", url, nil)
+		s.apiURL, party.InvoiceID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	req.Header.Set("x-api-key", s.apiToken)
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	logger.Info("calling NowPayments:ListOfPayments", "Twitter", party.TwitterName, "NowPaymentsInvoiceID", party.NowPaymentsInvoiceID)
+	logger.Info("calling NowPayments:ListOfPayments", "Twitter", party.TwitterName, "invoiceID", party.InvoiceID)
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	logger.Debug("ListOfPayments Response", "res", string(data))
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to call NowPayments:Payment. Status code: %v", resp.StatusCode)
+		return nil, fmt.Errorf("failed to call NowPayments:Payment. Status code: %v", resp.StatusCode)
 	}
 
 	var resultJSON map[string]interface{}
 	err = json.Unmarshal(data, &resultJSON)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	results := resultJSON["data"].([]interface{})
-	for _, payment := range results {
-		paymentStatus := payment.(map[string]interface{})["payment_status"]
+	results, ok := resultJSON["data"].([]interface{})
+	if !ok {
+		return nil, errors.New("invalid NowPayments:Payment response")
+	}
+
+	status := &payment.InvoiceStatus{Payments: make(map[string]float64)}
+	for _, result := range results {
+		paymentInfo, ok := result.(map[string]interface{})
+		if !ok {
+			continue
+		}
 
-		if paymentStatus == "finished" {
-			party.NowPaymentsFinished = true
+		paymentStatus, _ := paymentInfo["payment_status"].(string)
+		if statusOf(paymentStatus).IsAfter(status.Status) {
+			status.Status = statusOf(paymentStatus)
+		}
+		paid := paidAmount(toFloat(paymentInfo["price_amount"]),
+			toFloat(paymentInfo["pay_amount"]), toFloat(paymentInfo["actually_paid"]))
+		status.PaidAmount += paid
+		if paymentID := toString(paymentInfo["payment_id"]); paymentID != "" {
+			status.Payments[paymentID] += paid
 		}
 	}
 
-	return nil
+	// No payment is made for the invoice yet.
+	if status.Status == "" {
+		status.Status = payment.StatusWaiting
+	}
+
+	return status, nil
 }
 
-func (s *NowPayments) getJWTToken() (string, error) {
+func (s *NowPayments) getJWTToken(ctx context.Context) (string, error) {
 	url := fmt.Sprintf("%v/v1/auth", s.apiURL)
 	jsonStr := fmt.Sprintf(`{"email":"%v","password":"%v"}`, s.username, s.password)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(jsonStr)))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(jsonStr)))
 	if err != nil {
 		return "", err
 	}
@@ -1,11 +1,19 @@
 package nowpayments
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/kehiy/RoboPac/payment"
+	"github.com/kehiy/RoboPac/store"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -18,6 +26,13 @@ func TestNetworkInfo(t *testing.T) {
 	nowPayments, err := NewNowPayments(&cfg)
 	require.NoError(t, err)
 
+	var received *payment.Event
+	nowPayments.SetEventHandler(func(event *payment.Event) error {
+		received = event
+
+		return nil
+	})
+
 	w := httptest.NewRecorder()
 	data := `{"actually_paid":0,"actually_paid_at_fiat":0,"fee":{"currency":"usdtbsc","depositFee":0,"serviceFee":0,"withdrawalFee":0},"invoice_id":4978049764,"order_description":null,"order_id":"181563","outcome_amount":46.5258178,"outcome_currency":"usdtbsc","parent_payment_id":null,"pay_address":"35AW2C7VeU6z6dxG1rrWEDR3qHBarkHGYw","pay_amount":0.00096324,"pay_currency":"btc","payin_extra_id":null,"payment_extra_ids":null,"payment_id":5613681154,"payment_status":"finished","price_amount":50,"price_currency":"usd","purchase_id":"4621639450","updated_at":1708011564521}`
 	jsonRaeder := strings.NewReader(data)
@@ -29,4 +44,203 @@ func TestNetworkInfo(t *testing.T) {
 	nowPayments.webhookFunc(w, request)
 
 	assert.Equal(t, w.Code, 200)
+	assert.Equal(t, "181563", received.OrderID)
+	assert.Equal(t, "4978049764", received.InvoiceID)
+	assert.Equal(t, "5613681154", received.PaymentID)
+	assert.Equal(t, ProviderName, received.Provider)
+	assert.Zero(t, received.PaidAmount)
+	assert.True(t, received.IsFinished())
+}
+
+func TestWebhook(t *testing.T) {
+	nowPayments := &NowPayments{ipnSecret: []byte("secret")}
+	data := `{"invoice_id":1,"order_id":"12345678","pay_amount":0.00000012,"payment_status":"finished"}`
+
+	sign := func(data string) string {
+		mac := hmac.New(sha512.New, []byte("secret"))
+		_, _ = mac.Write([]byte(data))
+
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	call := func(data, sig string) int {
+		request := httptest.NewRequest("POST", "/nowpayments", strings.NewReader(data))
+		request.Header.Set("x-nowpayments-sig", sig)
+		w := httptest.NewRecorder()
+		nowPayments.webhookFunc(w, request)
+
+		return w.Code
+	}
+
+	events := []*payment.Event{}
+	var handlerErr error
+	nowPayments.SetEventHandler(func(event *payment.Event) error {
+		events = append(events, event)
+
+		return handlerErr
+	})
+
+	t.Run("valid signature", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, call(data, sign(data)))
+		assert.Len(t, events, 1)
+		assert.Equal(t, "12345678", events[0].OrderID)
+		assert.Equal(t, "1", events[0].InvoiceID)
+		assert.Zero(t, events[0].PaidAmount)
+	})
+
+	t.Run("keys are sorted before signing", func(t *testing.T) {
+		unsorted := `{"payment_status":"finished","order_id":"12345678","invoice_id":1,"pay_amount":0.00000012}`
+
+		assert.Equal(t, http.StatusOK, call(unsorted, sign(data)))
+		assert.Len(t, events, 2)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		tampered := strings.Replace(data, "12345678", "87654321", 1)
+
+		assert.Equal(t, http.StatusUnauthorized, call(tampered, sign(data)))
+		assert.Equal(t, http.StatusUnauthorized, call(data, ""))
+		assert.Equal(t, http.StatusUnauthorized, call(data, "not-hex"))
+		assert.Len(t, events, 2)
+	})
+
+	t.Run("no IPN secret", func(t *testing.T) {
+		noSecret := &NowPayments{}
+		assert.ErrorIs(t, noSecret.verifySignature([]byte(data), sign(data)), ErrInvalidSignature)
+	})
+
+	t.Run("handler fails", func(t *testing.T) {
+		handlerErr = errors.New("queue is full")
+
+		assert.Equal(t, http.StatusServiceUnavailable, call(data, sign(data)))
+	})
+}
+
+// fakeNowPayments is a local NowPayments API that has one invoice with the given payments.
+func fakeNowPayments(t *testing.T, payments string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/invoice", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		if r.Header.Get("x-api-key") != "api-key" {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+		assert.JSONEq(t, `{"price_amount":30,"price_currency":"usd","ipn_callback_url":"https://bot/nowpayments","order_id":"1111"}`,
+			string(body))
+		_, _ = w.Write([]byte(`{"id":"42","order_id":"1111"}`))
+	})
+	mux.HandleFunc("/v1/auth", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"token":"jwt-token"}`))
+	})
+	mux.HandleFunc("/v1/payment/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer jwt-token" || r.URL.Query().Get("invoiceId") != "42" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":` + payments + `}`))
+	})
+	mux.HandleFunc("/v1/payout", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		if r.Header.Get("Authorization") != "Bearer jwt-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+		assert.JSONEq(t, `{"withdrawals":[{"address":"refund-addr","currency":"usdtbsc","fiat_amount":12.5,`+
+			`"fiat_currency":"usd","unique_external_id":"refund-1111-1"}]}`, string(body))
+		_, _ = w.Write([]byte(`{"id":"5000000713","withdrawals":[]}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestInvoice(t *testing.T) {
+	newClient := func(apiURL string) *NowPayments {
+		return &NowPayments{apiToken: "api-key", apiURL: apiURL, webhook: "https://bot/nowpayments"}
+	}
+
+	t.Run("create invoice", func(t *testing.T) {
+		server := fakeNowPayments(t, `[]`)
+		party := &store.TwitterParty{DiscountCode: "1111", TotalPrice: 30}
+
+		err := newClient(server.URL).CreateInvoice(context.Background(), party)
+		assert.NoError(t, err)
+		assert.Equal(t, "42", party.InvoiceID)
+		assert.Equal(t, "https://nowpayments.io/payment/?iid=42", newClient(server.URL).PaymentLink(party))
+	})
+
+	t.Run("invalid api key", func(t *testing.T) {
+		server := fakeNowPayments(t, `[]`)
+		client := newClient(server.URL)
+		client.apiToken = "invalid"
+
+		err := client.CreateInvoice(context.Background(), &store.TwitterParty{DiscountCode: "1111", TotalPrice: 30})
+		assert.ErrorContains(t, err, "Status code: 403")
+	})
+
+	tests := []struct {
+		name     string
+		payments string
+		status   payment.Status
+		paid     float64
+	}{
+		{"no payment", `[]`, payment.StatusWaiting, 0},
+		{"confirming", `[{"payment_status":"sending"}]`, payment.StatusConfirming, 0},
+		{
+			"partially paid",
+			`[{"payment_status":"expired"},` +
+				`{"payment_status":"partially_paid","price_amount":30,"pay_amount":0.0006,"actually_paid":0.0002}]`,
+			payment.StatusPartiallyPaid, 10,
+		},
+		{
+			"topped up",
+			`[{"payment_status":"partially_paid","price_amount":30,"pay_amount":"0.0006","actually_paid":"0.0002"},` +
+				`{"payment_status":"finished","price_amount":30,"pay_amount":0.0006,"actually_paid":0.0004}]`,
+			payment.StatusFinished, 30,
+		},
+		{"expired", `[{"payment_status":"expired"}]`, payment.StatusExpired, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeNowPayments(t, tt.payments)
+
+			status, err := newClient(server.URL).InvoiceStatus(context.Background(), &store.TwitterParty{InvoiceID: "42"})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.status, status.Status)
+			assert.InDelta(t, tt.paid, status.PaidAmount, 0.001)
+			assert.Empty(t, status.TxID)
+		})
+	}
+
+	t.Run("payments by their ID", func(t *testing.T) {
+		server := fakeNowPayments(t,
+			`[{"payment_id":5613681154,"payment_status":"partially_paid","price_amount":30,"pay_amount":0.0006,"actually_paid":0.0002},`+
+				`{"payment_id":"5613681155","payment_status":"partially_paid","price_amount":30,"pay_amount":0.0006,"actually_paid":0.0002}]`)
+
+		status, err := newClient(server.URL).InvoiceStatus(context.Background(), &store.TwitterParty{InvoiceID: "42"})
+		assert.NoError(t, err)
+		assert.InDelta(t, 20, status.PaidAmount, 0.001)
+		assert.Equal(t, map[string]float64{"5613681154": 10, "5613681155": 10}, status.Payments)
+	})
+
+	t.Run("refund", func(t *testing.T) {
+		server := fakeNowPayments(t, `[]`)
+
+		refundID, err := newClient(server.URL).Refund(context.Background(), &store.TwitterParty{DiscountCode: "1111"},
+			&payment.Refund{ID: "refund-1111-1", Amount: 12.5, Currency: "usdtbsc", Address: "refund-addr"})
+		assert.NoError(t, err)
+		assert.Equal(t, "5000000713", refundID)
+	})
 }
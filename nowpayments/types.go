@@ -0,0 +1,66 @@
+package nowpayments
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// ipnPayload is the payment update that NowPayments sends to the IPN callback.
+// The order ID is the discount code of the party that the invoice is created for.
+type ipnPayload struct {
+	PaymentID     json.Number `json:"payment_id"`
+	InvoiceID     json.Number `json:"invoice_id"`
+	OrderID       string      `json:"order_id"`
+	PaymentStatus string      `json:"payment_status"`
+	PriceAmount   json.Number `json:"price_amount"`
+	PriceCurrency string      `json:"price_currency"`
+	PayAmount     json.Number `json:"pay_amount"`
+	PayCurrency   string      `json:"pay_currency"`
+	ActuallyPaid  json.Number `json:"actually_paid"`
+}
+
+// paidAmount returns the amount that is actually paid in USD.
+func (p *ipnPayload) paidAmount() float64 {
+	price, _ := p.PriceAmount.Float64()
+	pay, _ := p.PayAmount.Float64()
+	paid, _ := p.ActuallyPaid.Float64()
+
+	return paidAmount(price, pay, paid)
+}
+
+// paidAmount converts the amount that is actually paid in the pay currency to USD,
+// by the rate of the price amount to the pay amount. It is rounded to cents.
+func paidAmount(priceAmount, payAmount, actuallyPaid float64) float64 {
+	if payAmount <= 0 {
+		return 0
+	}
+
+	return math.Round(priceAmount*actuallyPaid/payAmount*100) / 100
+}
+
+// toString returns the ID of a JSON field, that may be sent as a number.
+func toString(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return ""
+	}
+}
+
+// toFloat returns the number of a JSON field, that may be sent as a string.
+func toFloat(value any) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+
+		return f
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,26 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/kehiy/RoboPac/store"
+)
+
+// IPaymentProvider sells the campaign packages. Each campaign chooses its provider in the config.
+type IPaymentProvider interface {
+	// Name returns the name of the provider, that the campaigns choose it by.
+	Name() string
+	// CreateInvoice creates the invoice of the party and sets its invoice ID.
+	CreateInvoice(ctx context.Context, party *store.TwitterParty) error
+	// InvoiceStatus queries the current status of the invoice of the party.
+	InvoiceStatus(ctx context.Context, party *store.TwitterParty) (*InvoiceStatus, error)
+	// PaymentLink returns the link that the user pays the invoice with.
+	PaymentLink(party *store.TwitterParty) string
+	// Refund sends back the amount of the refund to the user and returns the ID of the refund.
+	Refund(ctx context.Context, party *store.TwitterParty, refund *Refund) (string, error)
+	// WebhookHandler returns the handler of the provider callbacks, or nil if the provider has no callbacks.
+	WebhookHandler() http.Handler
+	// SetEventHandler sets the handler of the events that are received through the webhook.
+	SetEventHandler(handler Handler)
+}
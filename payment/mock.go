@@ -0,0 +1,140 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./payment/interface.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./payment/interface.go -destination=./payment/mock.go -package=payment
+//
+
+// Package payment is a generated GoMock package.
+package payment
+
+import (
+	context "context"
+	http "net/http"
+	reflect "reflect"
+
+	store "github.com/kehiy/RoboPac/store"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIPaymentProvider is a mock of IPaymentProvider interface.
+type MockIPaymentProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockIPaymentProviderMockRecorder
+}
+
+// MockIPaymentProviderMockRecorder is the mock recorder for MockIPaymentProvider.
+type MockIPaymentProviderMockRecorder struct {
+	mock *MockIPaymentProvider
+}
+
+// NewMockIPaymentProvider creates a new mock instance.
+func NewMockIPaymentProvider(ctrl *gomock.Controller) *MockIPaymentProvider {
+	mock := &MockIPaymentProvider{ctrl: ctrl}
+	mock.recorder = &MockIPaymentProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIPaymentProvider) EXPECT() *MockIPaymentProviderMockRecorder {
+	return m.recorder
+}
+
+// CreateInvoice mocks base method.
+func (m *MockIPaymentProvider) CreateInvoice(ctx context.Context, party *store.TwitterParty) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateInvoice", ctx, party)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateInvoice indicates an expected call of CreateInvoice.
+func (mr *MockIPaymentProviderMockRecorder) CreateInvoice(ctx, party any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInvoice", reflect.TypeOf((*MockIPaymentProvider)(nil).CreateInvoice), ctx, party)
+}
+
+// InvoiceStatus mocks base method.
+func (m *MockIPaymentProvider) InvoiceStatus(ctx context.Context, party *store.TwitterParty) (*InvoiceStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvoiceStatus", ctx, party)
+	ret0, _ := ret[0].(*InvoiceStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InvoiceStatus indicates an expected call of InvoiceStatus.
+func (mr *MockIPaymentProviderMockRecorder) InvoiceStatus(ctx, party any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvoiceStatus", reflect.TypeOf((*MockIPaymentProvider)(nil).InvoiceStatus), ctx, party)
+}
+
+// Name mocks base method.
+func (m *MockIPaymentProvider) Name() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Name")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Name indicates an expected call of Name.
+func (mr *MockIPaymentProviderMockRecorder) Name() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockIPaymentProvider)(nil).Name))
+}
+
+// PaymentLink mocks base method.
+func (m *MockIPaymentProvider) PaymentLink(party *store.TwitterParty) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PaymentLink", party)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// PaymentLink indicates an expected call of PaymentLink.
+func (mr *MockIPaymentProviderMockRecorder) PaymentLink(party any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PaymentLink", reflect.TypeOf((*MockIPaymentProvider)(nil).PaymentLink), party)
+}
+
+// Refund mocks base method.
+func (m *MockIPaymentProvider) Refund(ctx context.Context, party *store.TwitterParty, refund *Refund) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refund", ctx, party, refund)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Refund indicates an expected call of Refund.
+func (mr *MockIPaymentProviderMockRecorder) Refund(ctx, party, refund any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refund", reflect.TypeOf((*MockIPaymentProvider)(nil).Refund), ctx, party, refund)
+}
+
+// SetEventHandler mocks base method.
+func (m *MockIPaymentProvider) SetEventHandler(handler Handler) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetEventHandler", handler)
+}
+
+// SetEventHandler indicates an expected call of SetEventHandler.
+func (mr *MockIPaymentProviderMockRecorder) SetEventHandler(handler any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEventHandler", reflect.TypeOf((*MockIPaymentProvider)(nil).SetEventHandler), handler)
+}
+
+// WebhookHandler mocks base method.
+func (m *MockIPaymentProvider) WebhookHandler() http.Handler {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WebhookHandler")
+	ret0, _ := ret[0].(http.Handler)
+	return ret0
+}
+
+// WebhookHandler indicates an expected call of WebhookHandler.
+func (mr *MockIPaymentProviderMockRecorder) WebhookHandler() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WebhookHandler", reflect.TypeOf((*MockIPaymentProvider)(nil).WebhookHandler))
+}
@@ -0,0 +1,97 @@
+package payment
+
+import "errors"
+
+// ErrRefundNotSupported is returned when the provider can't refund the payments through its API.
+var ErrRefundNotSupported = errors.New("the payment provider doesn't support refunds, refund it from its dashboard")
+
+// Status is the status of an invoice. The providers map their own statuses to these ones.
+type Status string
+
+const (
+	StatusWaiting       Status = "waiting"
+	StatusConfirming    Status = "confirming"
+	StatusPartiallyPaid Status = "partially_paid"
+	StatusFinished      Status = "finished"
+	StatusExpired       Status = "expired"
+	StatusFailed        Status = "failed"
+	StatusRefunded      Status = "refunded"
+)
+
+// statusProgress is how far each status is in paying the invoice.
+var statusProgress = map[Status]int{
+	StatusFailed:        1,
+	StatusRefunded:      1,
+	StatusExpired:       1,
+	StatusWaiting:       2,
+	StatusConfirming:    3,
+	StatusPartiallyPaid: 4,
+	StatusFinished:      5,
+}
+
+// IsAfter returns true if the status is further than the other status in paying the invoice.
+// An invoice can have more than one payment, the status of the invoice is the furthest one.
+func (s Status) IsAfter(other Status) bool {
+	return statusProgress[s] > statusProgress[other]
+}
+
+// InvoiceStatus is the current status of an invoice.
+type InvoiceStatus struct {
+	Status Status
+	// PaidAmount is the amount that is actually paid for the invoice, in USD.
+	PaidAmount float64
+	// Payments are the amounts of the payments of the invoice in USD, by their payment ID.
+	// The providers without payment IDs only set the paid amount.
+	Payments map[string]float64
+	// TxID is the bond transaction, if the provider sends the stake coins itself.
+	TxID string
+}
+
+// IsFinished returns true if the invoice is paid completely.
+func (s *InvoiceStatus) IsFinished() bool {
+	return s.Status == StatusFinished
+}
+
+// Event is a change in the status of an invoice, that the provider sends to its webhook.
+// The order ID is the discount code of the party that the invoice is created for.
+type Event struct {
+	Provider  string
+	OrderID   string
+	InvoiceID string
+	// PaymentID is the payment of the invoice that the event is about, an invoice can have many payments.
+	PaymentID string
+	Status    Status
+	// PaidAmount is the amount that is actually paid by the payment, in USD.
+	PaidAmount float64
+}
+
+// InvoiceStatus returns the status of the invoice that the event is about.
+// It only has the payment of the event, not the other payments of the invoice.
+func (e *Event) InvoiceStatus() *InvoiceStatus {
+	status := &InvoiceStatus{Status: e.Status, PaidAmount: e.PaidAmount}
+	if e.PaymentID != "" {
+		status.Payments = map[string]float64{e.PaymentID: e.PaidAmount}
+	}
+
+	return status
+}
+
+// IsFinished returns true if the invoice is paid completely.
+func (e *Event) IsFinished() bool {
+	return e.Status == StatusFinished
+}
+
+// Refund is a refund of a payment, to an address of the user.
+type Refund struct {
+	// ID is the idempotency key of the refund, the providers send the refunds with the same ID once.
+	ID string
+	// Amount is the amount that is refunded, in USD.
+	Amount float64
+	// Currency is the currency that the refund is sent in, e.g. usdtbsc.
+	Currency string
+	Address  string
+}
+
+// Handler handles the events of the webhook of a provider.
+// If it returns an error the event is rejected, so the provider sends it again later.
+type Handler func(event *Event) error
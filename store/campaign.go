@@ -0,0 +1,344 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrCampaignFull is returned when all the packages of the campaign are reserved.
+	ErrCampaignFull = errors.New("program is finished")
+	// ErrAlreadyReserved is returned when the Twitter account has a package in the campaign.
+	ErrAlreadyReserved = errors.New("the Twitter account is already registered in the campaign")
+)
+
+// partyTransitions are the allowed changes of the party status.
+// A party is reserved first, then it is paid and fulfilled, or it is expired before the payment.
+var partyTransitions = map[PartyStatus][]PartyStatus{
+	PartyReserved:  {PartyReserved, PartyPaid, PartyExpired},
+	PartyPaid:      {PartyPaid, PartyFulfilled},
+	PartyFulfilled: {PartyFulfilled},
+	PartyExpired:   {PartyExpired},
+}
+
+// campaignParties are the participants of a campaign, keyed by their Twitter ID.
+// When the party of an expired reservation reserves again, the expired reservation is kept,
+// keyed by its discount code, so a late payment of its invoice can still be matched.
+type campaignParties struct {
+	parties map[string]*TwitterParty
+	path    string
+
+	expired     map[string]*TwitterParty
+	expiredPath string
+}
+
+// save saves the parties, and the expired reservations if they are changed.
+func (cp *campaignParties) save(expiredChanged bool) error {
+	if expiredChanged {
+		if err := saveMap(cp.expiredPath, cp.expired); err != nil {
+			return err
+		}
+	}
+
+	return saveMap(cp.path, cp.parties)
+}
+
+// lastExpired returns the discount code of the last expired reservation of the Twitter account,
+// or an empty string if there is none.
+func (cp *campaignParties) lastExpired(twitterID string) string {
+	var last *TwitterParty
+	for _, party := range cp.expired {
+		if party.TwitterID == twitterID && (last == nil || party.CreatedAt > last.CreatedAt) {
+			last = party
+		}
+	}
+
+	if last == nil {
+		return ""
+	}
+
+	return last.DiscountCode
+}
+
+// reservedPackages returns the number of the packages that are not released.
+func (cp *campaignParties) reservedPackages() int {
+	count := 0
+	for _, party := range cp.parties {
+		if !party.IsExpired() {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (s *Store) findCampaign(campaignID string) (*campaignParties, error) {
+	cp, found := s.campaigns[campaignID]
+	if !found {
+		return nil, fmt.Errorf("unknown campaign: %s", campaignID)
+	}
+
+	return cp, nil
+}
+
+// ReservePackage reserves a package of the campaign for the party, if less than maxPackages packages
+// are reserved. Zero maxPackages means no cap. The party of an expired reservation can reserve again.
+// It returns the number of the packages reserved before this one, that the price of the package is based on.
+func (s *Store) ReservePackage(campaignID string, maxPackages int, party *TwitterParty) (int, error) {
+	s.campaignsLock.Lock()
+	defer s.campaignsLock.Unlock()
+
+	cp, err := s.findCampaign(campaignID)
+	if err != nil {
+		return 0, err
+	}
+
+	existing, found := cp.parties[party.TwitterID]
+	if found && !existing.IsExpired() {
+		return 0, ErrAlreadyReserved
+	}
+
+	reserved := cp.reservedPackages()
+	if maxPackages > 0 && reserved >= maxPackages {
+		return 0, ErrCampaignFull
+	}
+
+	cpy := party.clone()
+	cpy.Status = PartyReserved
+	cpy.ExpiredAt = 0
+	cp.parties[cpy.TwitterID] = cpy
+
+	// The expired reservation is kept, unless it is the one that is reserved again.
+	archived, wasArchived := cp.expired[cpy.DiscountCode]
+	delete(cp.expired, cpy.DiscountCode)
+	archive := found && existing.DiscountCode != "" && existing.DiscountCode != cpy.DiscountCode
+	if archive {
+		cp.expired[existing.DiscountCode] = existing
+	}
+
+	if err := cp.save(archive || wasArchived); err != nil {
+		if found {
+			cp.parties[cpy.TwitterID] = existing
+		} else {
+			delete(cp.parties, cpy.TwitterID)
+		}
+		if archive {
+			delete(cp.expired, existing.DiscountCode)
+		}
+		if wasArchived {
+			cp.expired[cpy.DiscountCode] = archived
+		}
+
+		return 0, err
+	}
+	party.Status = PartyReserved
+	party.ExpiredAt = 0
+
+	return reserved, nil
+}
+
+// ReleasePackage removes the reservation of the party, if it is not paid yet.
+// The last expired reservation of the party, if there is any, takes its place again.
+func (s *Store) ReleasePackage(campaignID, twitterID string) error {
+	s.campaignsLock.Lock()
+	defer s.campaignsLock.Unlock()
+
+	cp, err := s.findCampaign(campaignID)
+	if err != nil {
+		return err
+	}
+
+	party, found := cp.parties[twitterID]
+	if !found {
+		return nil
+	}
+
+	if party.Status != PartyReserved {
+		return fmt.Errorf("the package of `%s` is %s and can't be released", party.TwitterName, party.Status)
+	}
+	delete(cp.parties, twitterID)
+
+	code := cp.lastExpired(twitterID)
+	expired := cp.expired[code]
+	if code != "" {
+		cp.parties[twitterID] = expired
+		delete(cp.expired, code)
+	}
+
+	if err := cp.save(code != ""); err != nil {
+		cp.parties[twitterID] = party
+		if code != "" {
+			cp.expired[code] = expired
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// SaveTwitterParty updates a reserved party, or an expired reservation that is kept by its discount code.
+// The status of the party can only move forward, e.g. a paid party can't be expired.
+func (s *Store) SaveTwitterParty(campaignID string, party *TwitterParty) error {
+	s.campaignsLock.Lock()
+	defer s.campaignsLock.Unlock()
+
+	cp, err := s.findCampaign(campaignID)
+	if err != nil {
+		return err
+	}
+
+	parties, key := cp.parties, party.TwitterID
+	existing, found := cp.parties[party.TwitterID]
+	if archived, ok := cp.expired[party.DiscountCode]; ok && (!found || existing.DiscountCode != party.DiscountCode) {
+		parties, key = cp.expired, party.DiscountCode
+		existing, found = archived, true
+	}
+
+	if !found {
+		return fmt.Errorf("no package is reserved for `%s`", party.TwitterName)
+	}
+
+	if !slices.Contains(partyTransitions[existing.Status], party.Status) {
+		return fmt.Errorf("the package of `%s` can't change from %s to %s",
+			party.TwitterName, existing.Status, party.Status)
+	}
+
+	parties[key] = party.clone()
+
+	if err := cp.save(key != party.TwitterID); err != nil {
+		parties[key] = existing
+
+		return err
+	}
+
+	return nil
+}
+
+// FindTwitterParty returns a copy of the party, or nil if it is not found.
+func (s *Store) FindTwitterParty(campaignID, twitterName string) *TwitterParty {
+	s.campaignsLock.RLock()
+	defer s.campaignsLock.RUnlock()
+
+	cp, err := s.findCampaign(campaignID)
+	if err != nil {
+		return nil
+	}
+
+	for _, party := range cp.parties {
+		if strings.EqualFold(party.TwitterName, twitterName) {
+			return party.clone()
+		}
+	}
+	return nil
+}
+
+// FindTwitterPartyByDiscountCode returns a copy of the party that has the discount code, or nil if it is not found.
+func (s *Store) FindTwitterPartyByDiscountCode(campaignID, discountCode string) *TwitterParty {
+	s.campaignsLock.RLock()
+	defer s.campaignsLock.RUnlock()
+
+	cp, err := s.findCampaign(campaignID)
+	if err != nil || discountCode == "" {
+		return nil
+	}
+
+	for _, party := range cp.parties {
+		if party.DiscountCode == discountCode {
+			return party.clone()
+		}
+	}
+
+	if party, found := cp.expired[discountCode]; found {
+		return party.clone()
+	}
+
+	return nil
+}
+
+// TwitterParties returns copies of all the parties of the campaign, with the expired reservations
+// that are reserved again, from the oldest to the newest.
+func (s *Store) TwitterParties(campaignID string) []*TwitterParty {
+	return s.filterParties(campaignID, func(*TwitterParty) bool { return true })
+}
+
+// UnpaidTwitterParties returns copies of the reserved parties of the campaign that are not paid yet,
+// from the oldest to the newest.
+func (s *Store) UnpaidTwitterParties(campaignID string) []*TwitterParty {
+	return s.filterParties(campaignID, func(party *TwitterParty) bool {
+		return party.Status == PartyReserved
+	})
+}
+
+func (s *Store) filterParties(campaignID string, filter func(*TwitterParty) bool) []*TwitterParty {
+	s.campaignsLock.RLock()
+	defer s.campaignsLock.RUnlock()
+
+	parties := []*TwitterParty{}
+	cp, err := s.findCampaign(campaignID)
+	if err != nil {
+		return parties
+	}
+
+	for _, party := range cp.parties {
+		if filter(party) {
+			parties = append(parties, party.clone())
+		}
+	}
+	for _, party := range cp.expired {
+		if filter(party) {
+			parties = append(parties, party.clone())
+		}
+	}
+
+	sort.Slice(parties, func(i, j int) bool {
+		return parties[i].CreatedAt < parties[j].CreatedAt
+	})
+
+	return parties
+}
+
+func (s *Store) CampaignStatus(campaignID string) *CampaignStatus {
+	s.campaignsLock.RLock()
+	defer s.campaignsLock.RUnlock()
+
+	bs := CampaignStatus{}
+
+	var parties map[string]*TwitterParty
+	if cp, err := s.findCampaign(campaignID); err == nil {
+		parties = cp.parties
+	}
+
+	for _, p := range parties {
+		switch p.Status {
+		case PartyExpired:
+			// The packages of the expired parties are released.
+			bs.Expired++
+
+			continue
+
+		case PartyReserved:
+			bs.PaymentWaiting++
+			bs.UnClaimedPkgs++
+
+		case PartyPaid:
+			bs.PaymentDone++
+			bs.UnClaimedPkgs++
+
+		case PartyFulfilled:
+			bs.PaymentDone++
+			bs.ClaimedPkgs++
+		}
+
+		bs.AllPkgs++
+		bs.Pac += int(p.AmountInPAC)
+		bs.Usdt += p.TotalPrice
+	}
+
+	bs.Whitelists = s.whitelistCount(campaignID)
+
+	return &bs
+}
@@ -5,10 +5,20 @@ type IStore interface {
 	AddClaimTransaction(testNetValAddr string, txID string) error
 	ClaimStatus() *ClaimStatus
 
-	SaveTwitterParty(party *TwitterParty) error
-	FindTwitterParty(twitterName string) *TwitterParty
+	ReservePackage(campaignID string, maxPackages int, party *TwitterParty) (int, error)
+	ReleasePackage(campaignID, twitterID string) error
+	SaveTwitterParty(campaignID string, party *TwitterParty) error
+	FindTwitterParty(campaignID, twitterName string) *TwitterParty
+	FindTwitterPartyByDiscountCode(campaignID, discountCode string) *TwitterParty
+	TwitterParties(campaignID string) []*TwitterParty
+	UnpaidTwitterParties(campaignID string) []*TwitterParty
 
-	WhitelistTwitterAccount(twitterID, twitterName, authorizedDiscordID string) error
-	IsWhitelisted(twitterID string) bool
-	BoosterStatus() *BoosterStatus
+	WhitelistTwitterAccount(campaignID, twitterID, twitterName, authorizedDiscordID string) error
+	IsWhitelisted(campaignID, twitterID string) bool
+	CampaignStatus(campaignID string) *CampaignStatus
+
+	SaveTransaction(tx *Transaction) error
+	TransactionInfo(txID string) *Transaction
+	PendingTransactions() []*Transaction
+	ReviewTransactions() []*Transaction
 }
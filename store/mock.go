@@ -52,18 +52,18 @@ func (mr *MockIStoreMockRecorder) AddClaimTransaction(testNetValAddr, txID any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddClaimTransaction", reflect.TypeOf((*MockIStore)(nil).AddClaimTransaction), testNetValAddr, txID)
 }
 
-// BoosterStatus mocks base method.
-func (m *MockIStore) BoosterStatus() *BoosterStatus {
+// CampaignStatus mocks base method.
+func (m *MockIStore) CampaignStatus(campaignID string) *CampaignStatus {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "BoosterStatus")
-	ret0, _ := ret[0].(*BoosterStatus)
+	ret := m.ctrl.Call(m, "CampaignStatus", campaignID)
+	ret0, _ := ret[0].(*CampaignStatus)
 	return ret0
 }
 
-// BoosterStatus indicates an expected call of BoosterStatus.
-func (mr *MockIStoreMockRecorder) BoosterStatus() *gomock.Call {
+// CampaignStatus indicates an expected call of CampaignStatus.
+func (mr *MockIStoreMockRecorder) CampaignStatus(campaignID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BoosterStatus", reflect.TypeOf((*MockIStore)(nil).BoosterStatus))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CampaignStatus", reflect.TypeOf((*MockIStore)(nil).CampaignStatus), campaignID)
 }
 
 // ClaimStatus mocks base method.
@@ -95,57 +95,184 @@ func (mr *MockIStoreMockRecorder) ClaimerInfo(testNetValAddr any) *gomock.Call {
 }
 
 // FindTwitterParty mocks base method.
-func (m *MockIStore) FindTwitterParty(twitterName string) *TwitterParty {
+func (m *MockIStore) FindTwitterParty(campaignID, twitterName string) *TwitterParty {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindTwitterParty", twitterName)
+	ret := m.ctrl.Call(m, "FindTwitterParty", campaignID, twitterName)
 	ret0, _ := ret[0].(*TwitterParty)
 	return ret0
 }
 
 // FindTwitterParty indicates an expected call of FindTwitterParty.
-func (mr *MockIStoreMockRecorder) FindTwitterParty(twitterName any) *gomock.Call {
+func (mr *MockIStoreMockRecorder) FindTwitterParty(campaignID, twitterName any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTwitterParty", reflect.TypeOf((*MockIStore)(nil).FindTwitterParty), twitterName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTwitterParty", reflect.TypeOf((*MockIStore)(nil).FindTwitterParty), campaignID, twitterName)
+}
+
+// FindTwitterPartyByDiscountCode mocks base method.
+func (m *MockIStore) FindTwitterPartyByDiscountCode(campaignID, discountCode string) *TwitterParty {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTwitterPartyByDiscountCode", campaignID, discountCode)
+	ret0, _ := ret[0].(*TwitterParty)
+	return ret0
+}
+
+// FindTwitterPartyByDiscountCode indicates an expected call of FindTwitterPartyByDiscountCode.
+func (mr *MockIStoreMockRecorder) FindTwitterPartyByDiscountCode(campaignID, discountCode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTwitterPartyByDiscountCode", reflect.TypeOf((*MockIStore)(nil).FindTwitterPartyByDiscountCode), campaignID, discountCode)
 }
 
 // IsWhitelisted mocks base method.
-func (m *MockIStore) IsWhitelisted(twitterID string) bool {
+func (m *MockIStore) IsWhitelisted(campaignID, twitterID string) bool {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "IsWhitelisted", twitterID)
+	ret := m.ctrl.Call(m, "IsWhitelisted", campaignID, twitterID)
 	ret0, _ := ret[0].(bool)
 	return ret0
 }
 
 // IsWhitelisted indicates an expected call of IsWhitelisted.
-func (mr *MockIStoreMockRecorder) IsWhitelisted(twitterID any) *gomock.Call {
+func (mr *MockIStoreMockRecorder) IsWhitelisted(campaignID, twitterID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsWhitelisted", reflect.TypeOf((*MockIStore)(nil).IsWhitelisted), twitterID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsWhitelisted", reflect.TypeOf((*MockIStore)(nil).IsWhitelisted), campaignID, twitterID)
+}
+
+// PendingTransactions mocks base method.
+func (m *MockIStore) PendingTransactions() []*Transaction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingTransactions")
+	ret0, _ := ret[0].([]*Transaction)
+	return ret0
+}
+
+// PendingTransactions indicates an expected call of PendingTransactions.
+func (mr *MockIStoreMockRecorder) PendingTransactions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingTransactions", reflect.TypeOf((*MockIStore)(nil).PendingTransactions))
+}
+
+// ReleasePackage mocks base method.
+func (m *MockIStore) ReleasePackage(campaignID, twitterID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleasePackage", campaignID, twitterID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleasePackage indicates an expected call of ReleasePackage.
+func (mr *MockIStoreMockRecorder) ReleasePackage(campaignID, twitterID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleasePackage", reflect.TypeOf((*MockIStore)(nil).ReleasePackage), campaignID, twitterID)
+}
+
+// ReservePackage mocks base method.
+func (m *MockIStore) ReservePackage(campaignID string, maxPackages int, party *TwitterParty) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReservePackage", campaignID, maxPackages, party)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReservePackage indicates an expected call of ReservePackage.
+func (mr *MockIStoreMockRecorder) ReservePackage(campaignID, maxPackages, party any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReservePackage", reflect.TypeOf((*MockIStore)(nil).ReservePackage), campaignID, maxPackages, party)
+}
+
+// ReviewTransactions mocks base method.
+func (m *MockIStore) ReviewTransactions() []*Transaction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReviewTransactions")
+	ret0, _ := ret[0].([]*Transaction)
+	return ret0
+}
+
+// ReviewTransactions indicates an expected call of ReviewTransactions.
+func (mr *MockIStoreMockRecorder) ReviewTransactions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReviewTransactions", reflect.TypeOf((*MockIStore)(nil).ReviewTransactions))
+}
+
+// SaveTransaction mocks base method.
+func (m *MockIStore) SaveTransaction(tx *Transaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTransaction", tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTransaction indicates an expected call of SaveTransaction.
+func (mr *MockIStoreMockRecorder) SaveTransaction(tx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTransaction", reflect.TypeOf((*MockIStore)(nil).SaveTransaction), tx)
 }
 
 // SaveTwitterParty mocks base method.
-func (m *MockIStore) SaveTwitterParty(party *TwitterParty) error {
+func (m *MockIStore) SaveTwitterParty(campaignID string, party *TwitterParty) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveTwitterParty", party)
+	ret := m.ctrl.Call(m, "SaveTwitterParty", campaignID, party)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SaveTwitterParty indicates an expected call of SaveTwitterParty.
-func (mr *MockIStoreMockRecorder) SaveTwitterParty(party any) *gomock.Call {
+func (mr *MockIStoreMockRecorder) SaveTwitterParty(campaignID, party any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTwitterParty", reflect.TypeOf((*MockIStore)(nil).SaveTwitterParty), campaignID, party)
+}
+
+// TransactionInfo mocks base method.
+func (m *MockIStore) TransactionInfo(txID string) *Transaction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransactionInfo", txID)
+	ret0, _ := ret[0].(*Transaction)
+	return ret0
+}
+
+// TransactionInfo indicates an expected call of TransactionInfo.
+func (mr *MockIStoreMockRecorder) TransactionInfo(txID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransactionInfo", reflect.TypeOf((*MockIStore)(nil).TransactionInfo), txID)
+}
+
+// TwitterParties mocks base method.
+func (m *MockIStore) TwitterParties(campaignID string) []*TwitterParty {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TwitterParties", campaignID)
+	ret0, _ := ret[0].([]*TwitterParty)
+	return ret0
+}
+
+// TwitterParties indicates an expected call of TwitterParties.
+func (mr *MockIStoreMockRecorder) TwitterParties(campaignID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TwitterParties", reflect.TypeOf((*MockIStore)(nil).TwitterParties), campaignID)
+}
+
+// UnpaidTwitterParties mocks base method.
+func (m *MockIStore) UnpaidTwitterParties(campaignID string) []*TwitterParty {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnpaidTwitterParties", campaignID)
+	ret0, _ := ret[0].([]*TwitterParty)
+	return ret0
+}
+
+// UnpaidTwitterParties indicates an expected call of UnpaidTwitterParties.
+func (mr *MockIStoreMockRecorder) UnpaidTwitterParties(campaignID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTwitterParty", reflect.TypeOf((*MockIStore)(nil).SaveTwitterParty), party)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnpaidTwitterParties", reflect.TypeOf((*MockIStore)(nil).UnpaidTwitterParties), campaignID)
 }
 
 // WhitelistTwitterAccount mocks base method.
-func (m *MockIStore) WhitelistTwitterAccount(twitterID, twitterName, authorizedDiscordID string) error {
+func (m *MockIStore) WhitelistTwitterAccount(campaignID, twitterID, twitterName, authorizedDiscordID string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WhitelistTwitterAccount", twitterID, twitterName, authorizedDiscordID)
+	ret := m.ctrl.Call(m, "WhitelistTwitterAccount", campaignID, twitterID, twitterName, authorizedDiscordID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // WhitelistTwitterAccount indicates an expected call of WhitelistTwitterAccount.
-func (mr *MockIStoreMockRecorder) WhitelistTwitterAccount(twitterID, twitterName, authorizedDiscordID any) *gomock.Call {
+func (mr *MockIStoreMockRecorder) WhitelistTwitterAccount(campaignID, twitterID, twitterName, authorizedDiscordID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WhitelistTwitterAccount", reflect.TypeOf((*MockIStore)(nil).WhitelistTwitterAccount), twitterID, twitterName, authorizedDiscordID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WhitelistTwitterAccount", reflect.TypeOf((*MockIStore)(nil).WhitelistTwitterAccount), campaignID, twitterID, twitterName, authorizedDiscordID)
 }
@@ -5,20 +5,27 @@ import (
 	"fmt"
 	"os"
 	"path"
-	"strings"
+	"sort"
+	"sync"
 
 	"github.com/kehiy/RoboPac/log"
+	"github.com/pactus-project/pactus/util"
 	"github.com/pactus-project/pactus/util/logger"
 )
 
 // Store is a thread-safe cache.
 type Store struct {
-	claimers             map[string]*Claimer
-	twitterParties       map[string]*TwitterParty
+	claimers map[string]*Claimer
+	// The packages are reserved without holding the engine lock, so the campaigns have their own lock.
+	campaignsLock sync.RWMutex
+	campaigns     map[string]*campaignParties
+	// The whitelist is read by the campaign status, under the campaigns lock, so it has its own lock.
+	whitelistLock        sync.RWMutex
 	twitterWhitelisted   map[string]*WhitelistInfo
+	transactions         map[string]*Transaction
 	claimersPath         string
-	twitterPartiesPath   string
 	twitterWhitelistPath string
+	transactionsPath     string
 	logger               *log.SubLogger
 }
 
@@ -48,42 +55,111 @@ func saveMap[T any](path string, mapObj map[string]*T) error {
 	return os.WriteFile(path, data, 0o600)
 }
 
-func NewStore(storePath string, logger *log.SubLogger) (IStore, error) {
+// NewStore loads the store from the storePath directory.
+// Each campaign has its own participants file, that is created on the first participant.
+// In dry-run mode the store is loaded from the real data, but all changes are written
+// to separate `*.dryrun.json` files, so the simulated transactions never mix with the real ones.
+// The next dry-run continues from the dry-run files.
+func NewStore(storePath string, dryRun bool, campaignIDs []string, logger *log.SubLogger) (IStore, error) {
 	claimers := make(map[string]*Claimer)
-	twitterParties := make(map[string]*TwitterParty)
+	campaigns := make(map[string]*campaignParties)
 	twitterWhitelisted := make(map[string]*WhitelistInfo)
+	transactions := make(map[string]*Transaction)
 
-	claimersPath := path.Join(storePath, "claimers.json")
-	twitterPartiesPath := path.Join(storePath, "twitter_campaign.json")
-	twitterWhitelistPath := path.Join(storePath, "twitter_whitelisted.json")
+	claimersPath := dataPath(storePath, "claimers", dryRun)
+	twitterWhitelistPath := dataPath(storePath, "twitter_whitelisted", dryRun)
+	transactionsPath := dataPath(storePath, "transactions", dryRun)
 
-	err := loadMap(claimersPath, claimers)
+	err := loadMap(loadPath(storePath, "claimers", claimersPath), claimers)
 	if err != nil {
 		return nil, err
 	}
 
-	err = loadMap(twitterPartiesPath, twitterParties)
-	if err != nil {
-		return nil, err
+	for _, id := range campaignIDs {
+		name := campaignFileName(id)
+		cp := &campaignParties{
+			parties:     make(map[string]*TwitterParty),
+			path:        dataPath(storePath, name, dryRun),
+			expired:     make(map[string]*TwitterParty),
+			expiredPath: dataPath(storePath, name+"_expired", dryRun),
+		}
+
+		if partiesPath := loadPath(storePath, name, cp.path); util.PathExists(partiesPath) {
+			err = loadMap(partiesPath, cp.parties)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// The expired reservations file is created when an expired party reserves again.
+		if expiredPath := loadPath(storePath, name+"_expired", cp.expiredPath); util.PathExists(expiredPath) {
+			err = loadMap(expiredPath, cp.expired)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, party := range cp.parties {
+			party.normalizeStatus()
+		}
+		campaigns[id] = cp
 	}
 
-	err = loadMap(twitterWhitelistPath, twitterWhitelisted)
+	err = loadMap(loadPath(storePath, "twitter_whitelisted", twitterWhitelistPath), twitterWhitelisted)
 	if err != nil {
 		return nil, err
 	}
+	twitterWhitelisted = keyWhitelist(twitterWhitelisted)
+
+	// The transactions file is created on the first payout.
+	if txPath := loadPath(storePath, "transactions", transactionsPath); util.PathExists(txPath) {
+		err = loadMap(txPath, transactions)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	ss := &Store{
 		claimers:             claimers,
-		twitterParties:       twitterParties,
+		campaigns:            campaigns,
 		twitterWhitelisted:   twitterWhitelisted,
+		transactions:         transactions,
 		claimersPath:         claimersPath,
-		twitterPartiesPath:   twitterPartiesPath,
 		twitterWhitelistPath: twitterWhitelistPath,
+		transactionsPath:     transactionsPath,
 		logger:               logger,
 	}
 	return ss, nil
 }
 
+// dataPath returns the path of the file that the data is saved to.
+func dataPath(storePath, name string, dryRun bool) string {
+	if dryRun {
+		return path.Join(storePath, name+".dryrun.json")
+	}
+
+	return path.Join(storePath, name+".json")
+}
+
+// campaignFileName returns the name of the participants file of the campaign.
+// The booster campaign keeps the file name it had before the campaigns were added.
+func campaignFileName(campaignID string) string {
+	if campaignID == "booster" {
+		return "twitter_campaign"
+	}
+
+	return "campaign_" + campaignID
+}
+
+// loadPath returns the saved data file if it exists, otherwise the real data file.
+func loadPath(storePath, name, savePath string) string {
+	if util.PathExists(savePath) {
+		return savePath
+	}
+
+	return path.Join(storePath, name+".json")
+}
+
 func (s *Store) ClaimerInfo(testnetAddr string) *Claimer {
 	entry, found := s.claimers[testnetAddr]
 	if !found {
@@ -132,73 +208,130 @@ func (s *Store) saveClaimers() error {
 	return saveMap(s.claimersPath, s.claimers)
 }
 
-func (s *Store) saveTwitterParties() error {
-	return saveMap(s.twitterPartiesPath, s.twitterParties)
+// whitelistKey is the key of a whitelisted Twitter account in the whitelist of the campaign.
+func whitelistKey(campaignID, twitterID string) string {
+	return fmt.Sprintf("%s/%s", campaignID, twitterID)
 }
 
-func (s *Store) saveTwitterWhitelist() error {
-	return saveMap(s.twitterWhitelistPath, s.twitterWhitelisted)
-}
-
-func (s *Store) SaveTwitterParty(party *TwitterParty) error {
-	s.twitterParties[party.TwitterID] = party
+// keyWhitelist keys the loaded whitelist by the campaign. The accounts that have no campaign
+// are whitelisted before the campaigns are added, so they are for the booster campaign.
+func keyWhitelist(whitelist map[string]*WhitelistInfo) map[string]*WhitelistInfo {
+	keyed := make(map[string]*WhitelistInfo, len(whitelist))
+	for _, info := range whitelist {
+		if info.CampaignID == "" {
+			info.CampaignID = "booster"
+		}
+		keyed[whitelistKey(info.CampaignID, info.TwitterID)] = info
+	}
 
-	return s.saveTwitterParties()
+	return keyed
 }
 
-func (s *Store) FindTwitterParty(twitterName string) *TwitterParty {
-	for _, party := range s.twitterParties {
-		if strings.EqualFold(party.TwitterName, twitterName) {
-			return party
+// whitelistCount returns the number of the Twitter accounts that are whitelisted for the campaign.
+func (s *Store) whitelistCount(campaignID string) int {
+	s.whitelistLock.RLock()
+	defer s.whitelistLock.RUnlock()
+
+	count := 0
+	for _, info := range s.twitterWhitelisted {
+		if info.CampaignID == campaignID {
+			count++
 		}
 	}
-	return nil
+
+	return count
+}
+
+// saveTwitterWhitelist saves the whitelist, the caller should hold the whitelist lock.
+func (s *Store) saveTwitterWhitelist() error {
+	return saveMap(s.twitterWhitelistPath, s.twitterWhitelisted)
+}
+
+func (s *Store) saveTransactions() error {
+	return saveMap(s.transactionsPath, s.transactions)
 }
 
-func (s *Store) WhitelistTwitterAccount(twitterID, twitterName, authorizedDiscordID string) error {
-	_, exists := s.twitterWhitelisted[twitterID]
+// WhitelistTwitterAccount whitelists the Twitter account for the campaign.
+func (s *Store) WhitelistTwitterAccount(campaignID, twitterID, twitterName, authorizedDiscordID string) error {
+	s.whitelistLock.Lock()
+	defer s.whitelistLock.Unlock()
+
+	key := whitelistKey(campaignID, twitterID)
+	_, exists := s.twitterWhitelisted[key]
 	if exists {
-		return fmt.Errorf("the Twitter `%v` is already whitelisted", twitterName)
+		return fmt.Errorf("the Twitter `%v` is already whitelisted for the campaign `%s`", twitterName, campaignID)
 	}
 
-	s.twitterWhitelisted[twitterID] = &WhitelistInfo{
+	s.twitterWhitelisted[key] = &WhitelistInfo{
+		CampaignID:    campaignID,
 		TwitterID:     twitterID,
 		TwitterName:   twitterName,
 		WhitelistedBy: authorizedDiscordID,
 	}
 
-	return s.saveTwitterWhitelist()
+	if err := s.saveTwitterWhitelist(); err != nil {
+		delete(s.twitterWhitelisted, key)
+
+		return err
+	}
+
+	return nil
 }
 
-func (s *Store) IsWhitelisted(twitterID string) bool {
-	_, exists := s.twitterWhitelisted[twitterID]
+// IsWhitelisted returns true if the Twitter account is whitelisted for the campaign.
+func (s *Store) IsWhitelisted(campaignID, twitterID string) bool {
+	s.whitelistLock.RLock()
+	defer s.whitelistLock.RUnlock()
+
+	_, exists := s.twitterWhitelisted[whitelistKey(campaignID, twitterID)]
 
 	return exists
 }
 
-func (s *Store) BoosterStatus() *BoosterStatus {
-	bs := BoosterStatus{}
+func (s *Store) SaveTransaction(tx *Transaction) error {
+	s.transactions[tx.TxID] = tx
 
-	for _, p := range s.twitterParties {
-		bs.AllPkgs++
-		bs.Pac += int(p.AmountInPAC)
-		bs.Usdt += p.TotalPrice
-		if p.NowPaymentsFinished {
-			bs.PaymentDone++
-		} else {
-			bs.PaymentWaiting++
-		}
+	return s.saveTransactions()
+}
 
-		if p.TransactionID != "" {
-			bs.ClaimedPkgs++
-		} else {
-			bs.UnClaimedPkgs++
+func (s *Store) TransactionInfo(txID string) *Transaction {
+	tx, found := s.transactions[txID]
+	if !found {
+		return nil
+	}
+
+	return tx
+}
+
+// PendingTransactions returns the transactions that are not confirmed or failed yet.
+func (s *Store) PendingTransactions() []*Transaction {
+	txs := []*Transaction{}
+	for _, tx := range s.transactions {
+		if tx.Status == TxStatusPending {
+			txs = append(txs, tx)
 		}
 	}
+	sortTransactions(txs)
+
+	return txs
+}
 
-	for range s.twitterWhitelisted {
-		bs.Whitelists++
+// ReviewTransactions returns the transactions flagged for operator review.
+func (s *Store) ReviewTransactions() []*Transaction {
+	txs := []*Transaction{}
+	for _, tx := range s.transactions {
+		if tx.NeedsReview {
+			txs = append(txs, tx)
+		}
 	}
+	sortTransactions(txs)
+
+	return txs
+}
 
-	return &bs
+// sortTransactions sorts the transactions from the oldest to the newest.
+func sortTransactions(txs []*Transaction) {
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].SentAt < txs[j].SentAt
+	})
 }
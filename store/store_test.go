@@ -6,6 +6,8 @@ import (
 	"io"
 	"os"
 	"path"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/kehiy/RoboPac/log"
@@ -40,6 +42,15 @@ func copy(src, dst string) (int64, error) {
 }
 
 func setup(t *testing.T) store.IStore {
+	tempDir := setupDir(t)
+
+	store, err := store.NewStore(tempDir, false, []string{"booster", "new-campaign"}, log.NewSubLogger("store_test"))
+	require.NoError(t, err)
+
+	return store
+}
+
+func setupDir(t *testing.T) string {
 	tempDir, err := os.MkdirTemp("", "RoboPAC")
 	require.NoError(t, err)
 
@@ -56,12 +67,8 @@ func setup(t *testing.T) store.IStore {
 	require.NoError(t, err)
 
 	log.InitGlobalLogger()
-	logger := log.NewSubLogger("store_test")
 
-	store, err := store.NewStore(tempDir, logger)
-	require.NoError(t, err)
-
-	return store
+	return tempDir
 }
 
 func TestStore(t *testing.T) {
@@ -113,7 +120,7 @@ func TestStoreTwitterCampaign(t *testing.T) {
 	mockStore := setup(t)
 
 	t.Run("not found", func(t *testing.T) {
-		p := mockStore.FindTwitterParty("robopac-twitter")
+		p := mockStore.FindTwitterParty("booster", "robopac-twitter")
 		assert.Nil(t, p)
 	})
 
@@ -123,15 +130,332 @@ func TestStoreTwitterCampaign(t *testing.T) {
 			TwitterName: "AbCd123",
 		}
 
-		err := mockStore.SaveTwitterParty(p)
+		_, err := mockStore.ReservePackage("booster", 0, p)
 		assert.NoError(t, err)
 
-		tp := mockStore.FindTwitterParty("abcd123")
+		tp := mockStore.FindTwitterParty("booster", "abcd123")
 		assert.Equal(t, "123456789", tp.TwitterID)
 		assert.Equal(t, "AbCd123", tp.TwitterName)
 
-		tp = mockStore.FindTwitterParty("abCd123")
+		tp = mockStore.FindTwitterParty("booster", "abCd123")
 		assert.Equal(t, "123456789", tp.TwitterID)
 		assert.Equal(t, "AbCd123", tp.TwitterName)
 	})
+
+	t.Run("campaigns have their own parties", func(t *testing.T) {
+		assert.NotNil(t, mockStore.FindTwitterParty("booster", "jack"))
+		assert.Nil(t, mockStore.FindTwitterParty("new-campaign", "jack"))
+
+		p := &store.TwitterParty{
+			TwitterID:   "123456",
+			TwitterName: "jack",
+			AmountInPAC: 100,
+		}
+		_, err := mockStore.ReservePackage("new-campaign", 0, p)
+		assert.NoError(t, err)
+
+		assert.Equal(t, int64(100), mockStore.FindTwitterParty("new-campaign", "jack").AmountInPAC)
+		assert.Equal(t, int64(200), mockStore.FindTwitterParty("booster", "jack").AmountInPAC)
+		assert.Equal(t, 1, mockStore.CampaignStatus("new-campaign").AllPkgs)
+		assert.Equal(t, 100, mockStore.CampaignStatus("new-campaign").Pac)
+	})
+
+	t.Run("find by discount code", func(t *testing.T) {
+		assert.Equal(t, "jack", mockStore.FindTwitterPartyByDiscountCode("booster", "344775").TwitterName)
+		assert.Nil(t, mockStore.FindTwitterPartyByDiscountCode("new-campaign", "344775"))
+		assert.Nil(t, mockStore.FindTwitterPartyByDiscountCode("booster", ""))
+	})
+
+	t.Run("legacy parties are reserved", func(t *testing.T) {
+		assert.Equal(t, store.PartyReserved, mockStore.FindTwitterParty("booster", "jack").Status)
+	})
+
+	t.Run("unknown campaign", func(t *testing.T) {
+		assert.Empty(t, mockStore.UnpaidTwitterParties("unknown"))
+
+		_, err := mockStore.ReservePackage("unknown", 0, &store.TwitterParty{TwitterID: "1"})
+		assert.ErrorContains(t, err, "unknown campaign")
+		err = mockStore.SaveTwitterParty("unknown", &store.TwitterParty{TwitterID: "1"})
+		assert.ErrorContains(t, err, "unknown campaign")
+		assert.Nil(t, mockStore.FindTwitterParty("unknown", "jack"))
+		assert.Zero(t, mockStore.CampaignStatus("unknown").AllPkgs)
+	})
+}
+
+func TestUnpaidTwitterParties(t *testing.T) {
+	mockStore := setup(t)
+
+	parties := []*store.TwitterParty{
+		{TwitterID: "1", TwitterName: "new", CreatedAt: 3, AmountInPAC: 100},
+		{TwitterID: "2", TwitterName: "old", CreatedAt: 1},
+		{TwitterID: "3", TwitterName: "paid", CreatedAt: 2},
+		{TwitterID: "4", TwitterName: "expired", CreatedAt: 2, AmountInPAC: 50},
+	}
+	for _, p := range parties {
+		_, err := mockStore.ReservePackage("new-campaign", 0, p)
+		assert.NoError(t, err)
+	}
+
+	parties[2].Status = store.PartyPaid
+	assert.NoError(t, mockStore.SaveTwitterParty("new-campaign", parties[2]))
+	parties[3].Status = store.PartyExpired
+	assert.NoError(t, mockStore.SaveTwitterParty("new-campaign", parties[3]))
+
+	unpaids := mockStore.UnpaidTwitterParties("new-campaign")
+	assert.Len(t, unpaids, 2)
+	assert.Equal(t, "old", unpaids[0].TwitterName)
+	assert.Equal(t, "new", unpaids[1].TwitterName)
+
+	all := mockStore.TwitterParties("new-campaign")
+	assert.Len(t, all, 4)
+	assert.Equal(t, "old", all[0].TwitterName)
+	assert.Equal(t, "new", all[3].TwitterName)
+
+	// The package of the expired party is released.
+	status := mockStore.CampaignStatus("new-campaign")
+	assert.Equal(t, 3, status.AllPkgs)
+	assert.Equal(t, 1, status.Expired)
+	assert.Equal(t, 100, status.Pac)
+}
+
+func TestReservePackage(t *testing.T) {
+	mockStore := setup(t)
+
+	t.Run("the cap is exact", func(t *testing.T) {
+		var wg sync.WaitGroup
+		var reservedCount atomic.Int32
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				p := &store.TwitterParty{TwitterID: fmt.Sprintf("id-%d", i), TwitterName: fmt.Sprintf("name-%d", i)}
+				_, err := mockStore.ReservePackage("new-campaign", 5, p)
+				if err == nil {
+					reservedCount.Add(1)
+				} else {
+					assert.ErrorIs(t, err, store.ErrCampaignFull)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(5), reservedCount.Load())
+		assert.Equal(t, 5, mockStore.CampaignStatus("new-campaign").AllPkgs)
+	})
+
+	t.Run("reserved before", func(t *testing.T) {
+		p := &store.TwitterParty{TwitterID: "123456", TwitterName: "jack"}
+		_, err := mockStore.ReservePackage("booster", 0, p)
+		assert.ErrorIs(t, err, store.ErrAlreadyReserved)
+	})
+
+	t.Run("expired party can reserve again", func(t *testing.T) {
+		p := &store.TwitterParty{TwitterID: "1", TwitterName: "alice"}
+		reserved, err := mockStore.ReservePackage("booster", 0, p)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, reserved)
+		assert.Equal(t, store.PartyReserved, p.Status)
+
+		p.Status = store.PartyExpired
+		p.ExpiredAt = 10
+		assert.NoError(t, mockStore.SaveTwitterParty("booster", p))
+
+		reserved, err = mockStore.ReservePackage("booster", 0, p)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, reserved)
+		assert.False(t, mockStore.FindTwitterParty("booster", "alice").IsExpired())
+	})
+
+	t.Run("status can only move forward", func(t *testing.T) {
+		p := mockStore.FindTwitterParty("booster", "alice")
+		p.Status = store.PartyPaid
+		assert.NoError(t, mockStore.SaveTwitterParty("booster", p))
+
+		p.Status = store.PartyExpired
+		assert.ErrorContains(t, mockStore.SaveTwitterParty("booster", p), "can't change from paid to expired")
+
+		err := mockStore.SaveTwitterParty("booster", &store.TwitterParty{TwitterID: "2", TwitterName: "bob"})
+		assert.ErrorContains(t, err, "no package is reserved for `bob`")
+	})
+
+	t.Run("release the package", func(t *testing.T) {
+		assert.ErrorContains(t, mockStore.ReleasePackage("booster", "1"), "can't be released")
+
+		assert.NoError(t, mockStore.ReleasePackage("booster", "123456"))
+		assert.Nil(t, mockStore.FindTwitterParty("booster", "jack"))
+
+		// Releasing again does nothing.
+		assert.NoError(t, mockStore.ReleasePackage("booster", "123456"))
+	})
+
+	t.Run("the returned parties are copies", func(t *testing.T) {
+		p := mockStore.FindTwitterParty("booster", "alice")
+		p.Status = store.PartyExpired
+
+		assert.Equal(t, store.PartyPaid, mockStore.FindTwitterParty("booster", "alice").Status)
+	})
+
+	t.Run("the adjustments are copied", func(t *testing.T) {
+		p := mockStore.FindTwitterParty("booster", "alice")
+		p.PaidAmount = 45.5
+		p.Adjustments = []*store.PaymentAdjustment{{Case: store.PaymentOverpaid, Resolution: store.ResolutionRefund}}
+		assert.NoError(t, mockStore.SaveTwitterParty("booster", p))
+
+		p.Adjustments[0] = &store.PaymentAdjustment{}
+		found := mockStore.FindTwitterParty("booster", "alice")
+		assert.InDelta(t, 45.5, found.PaidAmount, 0)
+		assert.Equal(t, store.ResolutionRefund, found.Adjustments[0].Resolution)
+	})
+}
+
+func TestExpiredReservations(t *testing.T) {
+	tempDir := setupDir(t)
+	logger := log.NewSubLogger("store_test")
+	mockStore, err := store.NewStore(tempDir, false, []string{"booster"}, logger)
+	require.NoError(t, err)
+
+	old := &store.TwitterParty{TwitterID: "1", TwitterName: "alice", DiscountCode: "1111", InvoiceID: "41", CreatedAt: 1}
+	_, err = mockStore.ReservePackage("booster", 0, old)
+	require.NoError(t, err)
+	old.Status = store.PartyExpired
+	old.ExpiredAt = 10
+	require.NoError(t, mockStore.SaveTwitterParty("booster", old))
+
+	t.Run("expired party reserves again", func(t *testing.T) {
+		p := &store.TwitterParty{TwitterID: "1", TwitterName: "alice", DiscountCode: "2222", InvoiceID: "42", CreatedAt: 2}
+		_, err := mockStore.ReservePackage("booster", 0, p)
+		require.NoError(t, err)
+
+		assert.Equal(t, "2222", mockStore.FindTwitterParty("booster", "alice").DiscountCode)
+		assert.Equal(t, "41", mockStore.FindTwitterPartyByDiscountCode("booster", "1111").InvoiceID)
+		assert.Len(t, mockStore.TwitterParties("booster"), 3)
+	})
+
+	t.Run("old invoice is paid", func(t *testing.T) {
+		late := mockStore.FindTwitterPartyByDiscountCode("booster", "1111")
+		late.PaidAmount = 30
+		late.PaymentCase = store.PaymentLate
+		require.NoError(t, mockStore.SaveTwitterParty("booster", late))
+
+		assert.Equal(t, store.PaymentLate, mockStore.FindTwitterPartyByDiscountCode("booster", "1111").PaymentCase)
+		current := mockStore.FindTwitterParty("booster", "alice")
+		assert.Equal(t, "2222", current.DiscountCode)
+		assert.Equal(t, store.PartyReserved, current.Status)
+		assert.Zero(t, current.PaidAmount)
+	})
+
+	t.Run("expired reservations are loaded again", func(t *testing.T) {
+		reloaded, err := store.NewStore(tempDir, false, []string{"booster"}, logger)
+		require.NoError(t, err)
+
+		assert.Equal(t, store.PaymentLate, reloaded.FindTwitterPartyByDiscountCode("booster", "1111").PaymentCase)
+	})
+
+	t.Run("released package brings the expired reservation back", func(t *testing.T) {
+		require.NoError(t, mockStore.ReleasePackage("booster", "1"))
+
+		p := mockStore.FindTwitterParty("booster", "alice")
+		assert.Equal(t, "1111", p.DiscountCode)
+		assert.Equal(t, store.PartyExpired, p.Status)
+		assert.Nil(t, mockStore.FindTwitterPartyByDiscountCode("booster", "2222"))
+		assert.Len(t, mockStore.TwitterParties("booster"), 2)
+	})
+}
+
+func TestConcurrentWhitelist(t *testing.T) {
+	mockStore := setup(t)
+	whitelists := mockStore.CampaignStatus("booster").Whitelists
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			twitterID := fmt.Sprintf("whitelist-%d", i)
+			assert.NoError(t, mockStore.WhitelistTwitterAccount("booster", twitterID, twitterID, "admin"))
+			assert.True(t, mockStore.IsWhitelisted("booster", twitterID))
+			mockStore.CampaignStatus("booster")
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, whitelists+20, mockStore.CampaignStatus("booster").Whitelists)
+}
+
+func TestCampaignWhitelist(t *testing.T) {
+	tempDir := setupDir(t)
+	logger := log.NewSubLogger("store_test")
+	mockStore, err := store.NewStore(tempDir, false, []string{"booster", "new-campaign"}, logger)
+	require.NoError(t, err)
+
+	// The accounts that are whitelisted before the campaigns are added are for the booster campaign.
+	assert.True(t, mockStore.IsWhitelisted("booster", "123456"))
+	assert.False(t, mockStore.IsWhitelisted("new-campaign", "123456"))
+	assert.Equal(t, 1, mockStore.CampaignStatus("booster").Whitelists)
+	assert.Zero(t, mockStore.CampaignStatus("new-campaign").Whitelists)
+
+	assert.NoError(t, mockStore.WhitelistTwitterAccount("new-campaign", "123456", "jack", "admin"))
+	assert.ErrorContains(t, mockStore.WhitelistTwitterAccount("new-campaign", "123456", "jack", "admin"),
+		"already whitelisted for the campaign `new-campaign`")
+	assert.True(t, mockStore.IsWhitelisted("new-campaign", "123456"))
+	assert.Equal(t, 1, mockStore.CampaignStatus("booster").Whitelists)
+	assert.Equal(t, 1, mockStore.CampaignStatus("new-campaign").Whitelists)
+
+	reloaded, err := store.NewStore(tempDir, false, []string{"booster", "new-campaign"}, logger)
+	require.NoError(t, err)
+	assert.True(t, reloaded.IsWhitelisted("booster", "123456"))
+	assert.True(t, reloaded.IsWhitelisted("new-campaign", "123456"))
+}
+
+func TestDryRun(t *testing.T) {
+	tempDir := setupDir(t)
+	logger := log.NewSubLogger("store_test")
+	testnetAddr := "tpc1pqn7uaeduklpg00rqt6uq0m9wy5txnyt0kmxmgf"
+
+	dryRunStore, err := store.NewStore(tempDir, true, []string{"booster"}, logger)
+	require.NoError(t, err)
+
+	err = dryRunStore.AddClaimTransaction(testnetAddr, "simulated-tx-id")
+	assert.NoError(t, err)
+	assert.FileExists(t, path.Join(tempDir, "claimers.dryrun.json"))
+
+	t.Run("real data is not changed", func(t *testing.T) {
+		realStore, err := store.NewStore(tempDir, false, []string{"booster"}, logger)
+		require.NoError(t, err)
+
+		assert.False(t, realStore.ClaimerInfo(testnetAddr).IsClaimed())
+	})
+
+	t.Run("dry-run continues from the dry-run data", func(t *testing.T) {
+		dryRunStore, err := store.NewStore(tempDir, true, []string{"booster"}, logger)
+		require.NoError(t, err)
+
+		assert.Equal(t, "simulated-tx-id", dryRunStore.ClaimerInfo(testnetAddr).ClaimedTxID)
+	})
+}
+
+func TestStoreTransactions(t *testing.T) {
+	mockStore := setup(t)
+
+	assert.Nil(t, mockStore.TransactionInfo("tx-1"))
+
+	err := mockStore.SaveTransaction(&store.Transaction{TxID: "tx-2", Status: store.TxStatusPending, SentAt: 2})
+	assert.NoError(t, err)
+	err = mockStore.SaveTransaction(&store.Transaction{TxID: "tx-1", Status: store.TxStatusPending, SentAt: 1})
+	assert.NoError(t, err)
+	err = mockStore.SaveTransaction(&store.Transaction{TxID: "tx-3", Status: store.TxStatusFailed, NeedsReview: true})
+	assert.NoError(t, err)
+
+	pendings := mockStore.PendingTransactions()
+	assert.Len(t, pendings, 2)
+	assert.Equal(t, "tx-1", pendings[0].TxID)
+	assert.Equal(t, "tx-2", pendings[1].TxID)
+
+	reviews := mockStore.ReviewTransactions()
+	assert.Len(t, reviews, 1)
+	assert.Equal(t, "tx-3", reviews[0].TxID)
+
+	assert.Equal(t, store.TxStatusPending, mockStore.TransactionInfo("tx-1").Status)
 }
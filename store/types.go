@@ -1,34 +1,145 @@
 package store
 
+import (
+	"maps"
+	"slices"
+)
+
 type Claimer struct {
 	DiscordID   string `json:"did"`
 	TotalReward int64  `json:"r"`
 	ClaimedTxID string `json:"tx_id"`
 }
 
+// PartyStatus is the status of the package of a party.
+type PartyStatus string
+
+const (
+	PartyReserved  PartyStatus = "reserved"
+	PartyPaid      PartyStatus = "paid"
+	PartyFulfilled PartyStatus = "fulfilled"
+	PartyExpired   PartyStatus = "expired"
+)
+
+// PaymentCase is a payment that doesn't match the total price of the party, it is resolved by the admins.
+type PaymentCase string
+
+const (
+	// PaymentUnderpaid is a payment that is less than the total price.
+	PaymentUnderpaid PaymentCase = "underpaid"
+	// PaymentOverpaid is a payment that is more than the total price.
+	PaymentOverpaid PaymentCase = "overpaid"
+	// PaymentLate is a payment that is made after the discount code is expired and its package is released.
+	PaymentLate PaymentCase = "late"
+)
+
+// Resolution is how the admins resolve a payment case.
+type Resolution string
+
+const (
+	// ResolutionTopUp creates a new invoice for the rest of the total price.
+	ResolutionTopUp Resolution = "top-up"
+	// ResolutionConvert sends a package for the amount that is paid.
+	ResolutionConvert Resolution = "convert"
+	// ResolutionRefund sends the payment back to the user.
+	ResolutionRefund Resolution = "refund"
+)
+
+// PaymentAdjustment is the record of a resolved payment case.
+type PaymentAdjustment struct {
+	Case       PaymentCase `json:"case"`
+	Resolution Resolution  `json:"resolution"`
+	// Amount is the amount of the top-up invoice or the refund, in USD.
+	Amount float64 `json:"amount,omitempty"`
+	// AmountInPAC is the package of the party before it is converted.
+	AmountInPAC int64  `json:"amount_in_pac,omitempty"`
+	InvoiceID   string `json:"invoice_id,omitempty"`
+	RefundID    string `json:"refund_id,omitempty"`
+	ResolvedBy  string `json:"resolved_by,omitempty"`
+	ResolvedAt  int64  `json:"resolved_at"`
+
+	// RefundKey is the idempotency key of the refund, that is sent to the provider.
+	RefundKey string `json:"refund_key,omitempty"`
+	// RefundAddress and RefundCurrency are the destination of the refund, the retries are sent to the same one.
+	RefundAddress  string `json:"refund_address,omitempty"`
+	RefundCurrency string `json:"refund_currency,omitempty"`
+	// Pending is set while the refund is not confirmed by the provider.
+	// A pending refund is sent again with the same key, so it is never paid twice.
+	Pending bool `json:"pending,omitempty"`
+}
+
 type TwitterParty struct {
-	TwitterID            string `json:"twitter_id"`
-	TwitterName          string `json:"twitter_name"`
-	RetweetID            string `json:"retweet_id"`
-	ValAddr              string `json:"val_addr"`
-	ValPubKey            string `json:"val_pub"`
-	DiscordID            string `json:"discord_id"`
-	DiscountCode         string `json:"discount_code"`
-	TotalPrice           int    `json:"total_price"`
-	AmountInPAC          int64  `json:"amount_in_pac"`
-	CreatedAt            int64  `json:"created_at"`
-	NowPaymentsInvoiceID string `json:"nowpayments_id"`
-	NowPaymentsFinished  bool   `json:"nowpayments_finished"`
-	TransactionID        string `json:"tx_id"`
+	TwitterID     string      `json:"twitter_id"`
+	TwitterName   string      `json:"twitter_name"`
+	RetweetID     string      `json:"retweet_id"`
+	ValAddr       string      `json:"val_addr"`
+	ValPubKey     string      `json:"val_pub"`
+	DiscordID     string      `json:"discord_id"`
+	DiscountCode  string      `json:"discount_code"`
+	TotalPrice    int         `json:"total_price"`
+	AmountInPAC   int64       `json:"amount_in_pac"`
+	CreatedAt     int64       `json:"created_at"`
+	TransactionID string      `json:"tx_id"`
+	Status        PartyStatus `json:"status"`
+	// InvoiceID is the invoice of the party in the payment provider of the campaign.
+	// The JSON names are kept from the time that NowPayments was the only provider.
+	InvoiceID       string `json:"nowpayments_id"`
+	PaymentFinished bool   `json:"nowpayments_finished"`
+	// PaymentStatus is the status of the invoice in the payment provider, e.g. waiting or partially_paid.
+	PaymentStatus string `json:"payment_status,omitempty"`
+	// Frontend is the frontend that the party is registered from, Discord if it is empty.
+	Frontend string `json:"frontend,omitempty"`
+	// ExpiredAt is set when the discount code is expired before the payment and the package is released.
+	ExpiredAt int64 `json:"expired_at,omitempty"`
+	// PaidAmount is the amount that is actually paid in USD, through all the invoices of the party.
+	PaidAmount float64 `json:"paid_amount,omitempty"`
+	// PaidBefore is the amount that is paid through the previous invoices, before a top-up invoice is created.
+	PaidBefore float64 `json:"paid_before,omitempty"`
+	// Payments are the amounts that are paid in USD by each payment of the current invoice, by the payment ID.
+	// The same payment can be reported many times, so the payments are added up once.
+	Payments map[string]float64 `json:"payments,omitempty"`
+	// RefundedAmount is the amount that is refunded to the user in USD.
+	RefundedAmount float64 `json:"refunded_amount,omitempty"`
+	// PaymentCase is set when the payment doesn't match the total price, until it is resolved by the admins.
+	PaymentCase PaymentCase          `json:"payment_case,omitempty"`
+	Adjustments []*PaymentAdjustment `json:"adjustments,omitempty"`
 }
 
+type TxStatus string
+
+const (
+	TxStatusPending   TxStatus = "pending"
+	TxStatusConfirmed TxStatus = "confirmed"
+	TxStatusFailed    TxStatus = "failed"
+)
+
+// TxKindClaim is the kind of the claim transactions.
+// The transactions of the campaigns have the campaign ID as their kind.
+const TxKindClaim = "claim"
+
+// Transaction is a payout transaction issued by the bot, tracked until it is confirmed.
+// Ref is the key of the related record, the testnet address for claims and the Twitter name for campaigns.
+type Transaction struct {
+	TxID        string   `json:"tx_id"`
+	Kind        string   `json:"kind"`
+	Ref         string   `json:"ref"`
+	Status      TxStatus `json:"status"`
+	Height      uint32   `json:"height,omitempty"`
+	SentAt      int64    `json:"sent_at"`
+	CheckedAt   int64    `json:"checked_at,omitempty"`
+	NeedsReview bool     `json:"needs_review,omitempty"`
+}
+
+// WhitelistInfo is a Twitter account that is whitelisted for a campaign.
+// The accounts that are whitelisted before the campaigns are added have no campaign, they are for the booster campaign.
 type WhitelistInfo struct {
+	CampaignID    string `json:"campaign_id,omitempty"`
 	TwitterID     string `json:"twitter_id"`
 	TwitterName   string `json:"twitter_name"`
 	WhitelistedBy string `json:"whitelisted_by"`
 }
 
-type BoosterStatus struct {
+type CampaignStatus struct {
 	Pac            int
 	Usdt           int
 	AllPkgs        int
@@ -36,6 +147,7 @@ type BoosterStatus struct {
 	UnClaimedPkgs  int
 	PaymentDone    int
 	PaymentWaiting int
+	Expired        int
 	Whitelists     int
 }
 
@@ -49,3 +161,38 @@ type ClaimStatus struct {
 func (c *Claimer) IsClaimed() bool {
 	return c.ClaimedTxID != ""
 }
+
+func (p *TwitterParty) IsExpired() bool {
+	return p.Status == PartyExpired
+}
+
+// clone returns a copy of the party, that doesn't share its adjustments with it.
+func (p *TwitterParty) clone() *TwitterParty {
+	cpy := *p
+	cpy.Payments = maps.Clone(p.Payments)
+	cpy.Adjustments = slices.Clone(p.Adjustments)
+	for i, adjustment := range p.Adjustments {
+		adjCpy := *adjustment
+		cpy.Adjustments[i] = &adjCpy
+	}
+
+	return &cpy
+}
+
+// normalizeStatus sets the status of the parties that are saved before the status is added.
+func (p *TwitterParty) normalizeStatus() {
+	if p.Status != "" {
+		return
+	}
+
+	switch {
+	case p.TransactionID != "":
+		p.Status = PartyFulfilled
+	case p.PaymentFinished:
+		p.Status = PartyPaid
+	case p.ExpiredAt != 0:
+		p.Status = PartyExpired
+	default:
+		p.Status = PartyReserved
+	}
+}
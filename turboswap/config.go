@@ -0,0 +1,7 @@
+package turboswap
+
+type Config struct {
+	APIToken   string
+	APIUrl     string
+	PaymentURL string
+}
@@ -5,27 +5,107 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 
+	"github.com/kehiy/RoboPac/payment"
 	"github.com/kehiy/RoboPac/store"
 	"github.com/pactus-project/pactus/util/logger"
 )
 
+// ProviderName is the name that the campaigns choose Turboswap by.
+const ProviderName = "turboswap"
+
+// The statuses of the discount codes, from the Turboswap API.
+// Turboswap sends the bond transaction itself, once the discount code is used.
+const (
+	DiscountExpired = "expired"
+	DiscountFailed  = "failed"
+)
+
+type DiscountStatus struct {
+	Status          string `json:"status"`
+	TransactionHash string `json:"transactionHash"`
+}
+
+// Turboswap sells the packages through the discount codes. The user buys the stake coins on Turboswap
+// with the discount code, so the discount code is the invoice of the party.
 type Turboswap struct {
-	APIToken string
-	url      string
+	apiToken   string
+	apiURL     string
+	paymentURL string
 }
 
-func NewTurboswap(apiToken, url string) (*Turboswap, error) {
+func NewTurboswap(cfg *Config) (*Turboswap, error) {
 	return &Turboswap{
-		APIToken: apiToken,
-		url:      url,
+		apiToken:   cfg.APIToken,
+		apiURL:     cfg.APIUrl,
+		paymentURL: cfg.PaymentURL,
 	}, nil
 }
 
+func (*Turboswap) Name() string {
+	return ProviderName
+}
+
+// CreateInvoice sends the discount code of the party to Turboswap.
+func (ts *Turboswap) CreateInvoice(ctx context.Context, party *store.TwitterParty) error {
+	url := fmt.Sprintf("%s/pactus/discount", ts.apiURL)
+	jsonStr := fmt.Sprintf(`{"api_key":"%v","code":"%v","validator_public_key":"%v","total_coins":"%v","total_price_in_usd":"%v"}`,
+		ts.apiToken, party.DiscountCode, party.ValPubKey, party.AmountInPAC, party.TotalPrice)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(jsonStr)))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	logger.Info("calling Turboswap/discount", "twitter", party.TwitterName)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// http.StatusOK = 200
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to call Turboswap/discount. Status code: %v", resp.StatusCode)
+	}
+	party.InvoiceID = party.DiscountCode
+
+	return nil
+}
+
+// InvoiceStatus returns the status of the discount code of the party.
+// The discount code is paid once Turboswap sends its bond transaction.
+func (ts *Turboswap) InvoiceStatus(ctx context.Context, party *store.TwitterParty) (*payment.InvoiceStatus, error) {
+	discountStatus, err := ts.GetStatus(ctx, party)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case discountStatus.TransactionHash != "":
+		// Turboswap sends the bond transaction only if the total price is paid.
+		return &payment.InvoiceStatus{
+			Status:     payment.StatusFinished,
+			PaidAmount: float64(party.TotalPrice),
+			TxID:       discountStatus.TransactionHash,
+		}, nil
+	case discountStatus.Status == DiscountExpired:
+		return &payment.InvoiceStatus{Status: payment.StatusExpired}, nil
+	case discountStatus.Status == DiscountFailed:
+		return &payment.InvoiceStatus{Status: payment.StatusFailed}, nil
+	default:
+		return &payment.InvoiceStatus{Status: payment.StatusWaiting}, nil
+	}
+}
+
 func (ts *Turboswap) GetStatus(ctx context.Context, party *store.TwitterParty) (*DiscountStatus, error) {
-	url := fmt.Sprintf("%s/pactus/discount/status/%v", ts.url, party.DiscountCode)
-	req, err := http.NewRequest("GET", url, bytes.NewBuffer(nil))
+	url := fmt.Sprintf("%s/pactus/discount/status/%v", ts.apiURL, party.DiscountCode)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, http.NoBody)
 	if err != nil {
 		return nil, err
 	}
@@ -43,15 +123,14 @@ func (ts *Turboswap) GetStatus(ctx context.Context, party *store.TwitterParty) (
 		return nil, fmt.Errorf("failed to call Turboswap/status. Status code: %v", resp.StatusCode)
 	}
 
-	buf := make([]byte, 0, 1024)
-	_, err = resp.Body.Read(buf)
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
-	logger.Info("Turboswap call successful", "res", string(buf))
+	logger.Info("Turboswap call successful", "res", string(data))
 
 	res := &DiscountStatus{}
-	err = json.Unmarshal(buf, res)
+	err = json.Unmarshal(data, res)
 	if err != nil {
 		return nil, err
 	}
@@ -59,30 +138,20 @@ func (ts *Turboswap) GetStatus(ctx context.Context, party *store.TwitterParty) (
 	return res, nil
 }
 
-func (ts *Turboswap) SendDiscountCode(ctx context.Context, party *store.TwitterParty) error {
-	url := fmt.Sprintf("%s/pactus/discount", ts.url)
-	jsonStr := fmt.Sprintf(`{"api_key":"%v","code":"%v","validator_public_key":"%v","total_coins":"%v","total_price_in_usd":"%v"}`,
-		ts.APIToken, party.DiscountCode, party.ValPubKey, party.AmountInPAC, party.TotalPrice)
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(jsonStr)))
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	logger.Info("calling Turboswap/discount", "twitter", party.TwitterName)
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// PaymentLink returns the Turboswap page that the discount code is used on.
+func (ts *Turboswap) PaymentLink(_ *store.TwitterParty) string {
+	return ts.paymentURL
+}
 
-	// http.StatusOK = 200
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to call Turboswap/discount. Status code: %v", resp.StatusCode)
-	}
+// Refund can't be done through the API.
+func (*Turboswap) Refund(_ context.Context, _ *store.TwitterParty, _ *payment.Refund) (string, error) {
+	return "", payment.ErrRefundNotSupported
+}
 
+// WebhookHandler returns nil, Turboswap has no callbacks and its discount codes are checked periodically.
+func (*Turboswap) WebhookHandler() http.Handler {
 	return nil
 }
+
+// SetEventHandler does nothing, Turboswap has no callbacks.
+func (*Turboswap) SetEventHandler(_ payment.Handler) {}
@@ -0,0 +1,93 @@
+package turboswap
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kehiy/RoboPac/payment"
+	"github.com/kehiy/RoboPac/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTurboswap is a local Turboswap API that has one discount code with the given status.
+func fakeTurboswap(t *testing.T, status string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pactus/discount", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{"api_key":"api-key","code":"1111","validator_public_key":"public-key",`+
+			`"total_coins":"150","total_price_in_usd":"30"}`, string(body))
+	})
+	mux.HandleFunc("/pactus/discount/status/1111", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(status))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestTurboswap(t *testing.T) {
+	newClient := func(apiURL string) *Turboswap {
+		ts, err := NewTurboswap(&Config{APIToken: "api-key", APIUrl: apiURL, PaymentURL: "https://turboswap/pactus"})
+		require.NoError(t, err)
+
+		return ts
+	}
+	party := &store.TwitterParty{DiscountCode: "1111", ValPubKey: "public-key", AmountInPAC: 150, TotalPrice: 30}
+
+	t.Run("create invoice", func(t *testing.T) {
+		server := fakeTurboswap(t, "")
+
+		err := newClient(server.URL).CreateInvoice(context.Background(), party)
+		assert.NoError(t, err)
+		assert.Equal(t, "1111", party.InvoiceID)
+		assert.Equal(t, "https://turboswap/pactus", newClient(server.URL).PaymentLink(party))
+	})
+
+	tests := []struct {
+		name   string
+		status string
+		want   payment.Status
+		paid   float64
+		txID   string
+	}{
+		{"waiting", `{"status":"pending","transactionHash":""}`, payment.StatusWaiting, 0, ""},
+		{"expired", `{"status":"expired"}`, payment.StatusExpired, 0, ""},
+		{"failed", `{"status":"failed"}`, payment.StatusFailed, 0, ""},
+		{"bond transaction is sent", `{"status":"done","transactionHash":"tx-1"}`, payment.StatusFinished, 30, "tx-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeTurboswap(t, tt.status)
+
+			status, err := newClient(server.URL).InvoiceStatus(context.Background(), party)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, status.Status)
+			assert.InDelta(t, tt.paid, status.PaidAmount, 0)
+			assert.Equal(t, tt.txID, status.TxID)
+		})
+	}
+
+	t.Run("unknown discount code", func(t *testing.T) {
+		server := fakeTurboswap(t, "")
+
+		_, err := newClient(server.URL).InvoiceStatus(context.Background(), &store.TwitterParty{DiscountCode: "2222"})
+		assert.ErrorContains(t, err, "Status code: 404")
+	})
+
+	t.Run("no webhook and refunds", func(t *testing.T) {
+		assert.Nil(t, newClient("").WebhookHandler())
+		_, err := newClient("").Refund(context.Background(), party, &payment.Refund{})
+		assert.ErrorIs(t, err, payment.ErrRefundNotSupported)
+	})
+}
@@ -0,0 +1,120 @@
+package twitter_api
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheConfig is how long the responses of each lookup are cached. Zero disables the cache of the lookup.
+type CacheConfig struct {
+	UserInfoTTL      time.Duration
+	RetweetSearchTTL time.Duration
+}
+
+type cacheEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+// ttlCache keeps the values until their TTL is passed.
+type ttlCache[T any] struct {
+	lk sync.Mutex
+
+	ttl     time.Duration
+	entries map[string]cacheEntry[T]
+}
+
+func newTTLCache[T any](ttl time.Duration) *ttlCache[T] {
+	return &ttlCache[T]{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry[T]),
+	}
+}
+
+func (c *ttlCache[T]) get(key string) (T, bool) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero T
+
+		return zero, false
+	}
+
+	return entry.value, true
+}
+
+// set caches the value, and removes the expired values.
+func (c *ttlCache[T]) set(key string, value T) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = cacheEntry[T]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+// CachedClient caches the successful responses of another client.
+// The errors are not cached, so the users can retry after fixing them, e.g. by quoting the tweet.
+type CachedClient struct {
+	client   IClient
+	users    *ttlCache[*UserInfo]
+	retweets *ttlCache[*TweetInfo]
+}
+
+func NewCachedClient(client IClient, cfg CacheConfig) *CachedClient {
+	return &CachedClient{
+		client:   client,
+		users:    newTTLCache[*UserInfo](cfg.UserInfoTTL),
+		retweets: newTTLCache[*TweetInfo](cfg.RetweetSearchTTL),
+	}
+}
+
+func (c *CachedClient) UserInfo(ctx context.Context, twitterName string) (*UserInfo, error) {
+	// The Twitter usernames are case-insensitive.
+	key := strings.ToLower(twitterName)
+	if info, ok := c.users.get(key); ok {
+		cached := *info
+
+		return &cached, nil
+	}
+
+	info, err := c.client.UserInfo(ctx, twitterName)
+	if err != nil {
+		return nil, err
+	}
+	cached := *info
+	c.users.set(key, &cached)
+
+	return info, nil
+}
+
+func (c *CachedClient) RetweetSearch(ctx context.Context, discordID, twitterName string) (*TweetInfo, error) {
+	key := discordID + "/" + strings.ToLower(twitterName)
+	if info, ok := c.retweets.get(key); ok {
+		cached := *info
+
+		return &cached, nil
+	}
+
+	info, err := c.client.RetweetSearch(ctx, discordID, twitterName)
+	if err != nil {
+		return nil, err
+	}
+	cached := *info
+	c.retweets.set(key, &cached)
+
+	return info, nil
+}
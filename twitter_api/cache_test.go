@@ -0,0 +1,79 @@
+package twitter_api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCachedClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("users are cached by their lowercase name", func(t *testing.T) {
+		client := NewMockIClient(gomock.NewController(t))
+		cached := NewCachedClient(client, CacheConfig{UserInfoTTL: time.Hour})
+
+		client.EXPECT().UserInfo(ctx, "Pactus").Return(&UserInfo{TwitterID: "1", Followers: 10}, nil)
+
+		info, err := cached.UserInfo(ctx, "Pactus")
+		assert.NoError(t, err)
+		info.Followers = 20
+
+		info, err = cached.UserInfo(ctx, "pactus")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", info.TwitterID)
+		assert.Equal(t, 10, info.Followers)
+	})
+
+	t.Run("expired responses are fetched again", func(t *testing.T) {
+		client := NewMockIClient(gomock.NewController(t))
+		cached := NewCachedClient(client, CacheConfig{RetweetSearchTTL: 10 * time.Millisecond})
+
+		client.EXPECT().RetweetSearch(ctx, "123", "pactus").Return(&TweetInfo{ID: "1"}, nil)
+		client.EXPECT().RetweetSearch(ctx, "123", "pactus").Return(&TweetInfo{ID: "2"}, nil)
+
+		info, err := cached.RetweetSearch(ctx, "123", "pactus")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", info.ID)
+
+		info, err = cached.RetweetSearch(ctx, "123", "Pactus")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", info.ID)
+
+		time.Sleep(20 * time.Millisecond)
+		info, err = cached.RetweetSearch(ctx, "123", "pactus")
+		assert.NoError(t, err)
+		assert.Equal(t, "2", info.ID)
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		client := NewMockIClient(gomock.NewController(t))
+		cached := NewCachedClient(client, CacheConfig{RetweetSearchTTL: time.Hour})
+
+		client.EXPECT().RetweetSearch(ctx, "123", "pactus").Return(nil, errors.New("no eligible quote tweet found"))
+		client.EXPECT().RetweetSearch(ctx, "123", "pactus").Return(&TweetInfo{ID: "1"}, nil)
+
+		_, err := cached.RetweetSearch(ctx, "123", "pactus")
+		assert.Error(t, err)
+
+		info, err := cached.RetweetSearch(ctx, "123", "pactus")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", info.ID)
+	})
+
+	t.Run("zero TTL disables the cache", func(t *testing.T) {
+		client := NewMockIClient(gomock.NewController(t))
+		cached := NewCachedClient(client, CacheConfig{})
+
+		client.EXPECT().UserInfo(ctx, "pactus").Return(&UserInfo{TwitterID: "1"}, nil).Times(2)
+
+		_, err := cached.UserInfo(ctx, "pactus")
+		assert.NoError(t, err)
+		_, err = cached.UserInfo(ctx, "pactus")
+		assert.NoError(t, err)
+	})
+}
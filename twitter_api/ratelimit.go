@@ -0,0 +1,206 @@
+package twitter_api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/g8rswimmer/go-twitter/v2"
+)
+
+const (
+	minBackoff = 5 * time.Second
+	maxBackoff = 15 * time.Minute
+)
+
+// ErrRateLimited is returned when the rate limit of the Twitter API is exhausted.
+var ErrRateLimited = errors.New("the rate limit of the Twitter API is exhausted")
+
+// RateLimitError is returned when the rate limit of the Twitter API is exhausted,
+// and it is not reset soon enough to wait for it.
+type RateLimitError struct {
+	ResetAt time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("the Twitter API is busy, please try again at %s UTC", e.ResetAt.UTC().Format("15:04"))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// rateLimiter tracks the rate limit of an endpoint of the Twitter API, by the headers of its responses.
+// When the quota is exhausted, the calls wait for it to reset, if it is reset before the max wait.
+// After the reset, one call is released to learn the new quota from its response, and the others
+// are released one by one while the quota lasts.
+type rateLimiter struct {
+	lk sync.Mutex
+
+	maxWait time.Duration
+	// remaining is the number of the calls left until the reset, -1 if it is unknown.
+	remaining int
+	resetAt   time.Time
+	// backoff is how long the calls are paused, when the API refuses them without the rate limit headers.
+	backoff time.Duration
+
+	// probing is set while the first call after the reset is waiting for its response.
+	probing bool
+	// changed is closed when a response changes the quota, to wake up the waiting calls.
+	changed chan struct{}
+}
+
+func newRateLimiter(maxWait time.Duration) *rateLimiter {
+	return &rateLimiter{
+		maxWait:   maxWait,
+		remaining: -1,
+		changed:   make(chan struct{}),
+	}
+}
+
+// wait waits until the endpoint can be called, and takes one call of the quota.
+// If the quota is reset after the max wait or the deadline of the context, a *RateLimitError is returned right away.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		resetAt, changed, taken := l.take(time.Now())
+		if taken {
+			return nil
+		}
+
+		if time.Until(resetAt) > l.maxWait {
+			return &RateLimitError{ResetAt: resetAt}
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && deadline.Before(resetAt) {
+			return &RateLimitError{ResetAt: resetAt}
+		}
+
+		if err := waitReset(ctx, resetAt, changed); err != nil {
+			return err
+		}
+	}
+}
+
+// waitReset waits until the quota is reset or changed by a response.
+// The zero reset time means the quota is only changed by a response.
+func waitReset(ctx context.Context, resetAt time.Time, changed <-chan struct{}) error {
+	var reset <-chan time.Time
+	if !resetAt.IsZero() {
+		timer := time.NewTimer(time.Until(resetAt))
+		defer timer.Stop()
+
+		reset = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-reset:
+	case <-changed:
+	}
+
+	return nil
+}
+
+// take takes one call of the quota. If the quota is exhausted, it returns the time that it is reset,
+// and a channel that is closed when the quota is changed by a response.
+// After the reset, only one call is taken until its response tells the new quota.
+func (l *rateLimiter) take(now time.Time) (time.Time, <-chan struct{}, bool) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	if l.remaining == 0 {
+		if now.Before(l.resetAt) {
+			return l.resetAt, l.changed, false
+		}
+
+		if l.probing {
+			return time.Time{}, l.changed, false
+		}
+		l.probing = true
+
+		return time.Time{}, nil, true
+	}
+
+	if l.remaining > 0 {
+		l.remaining--
+	}
+
+	return time.Time{}, nil, true
+}
+
+// notify wakes up the waiting calls to take the quota again. The caller should hold the lock.
+func (l *rateLimiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// update updates the quota by the rate limit headers of a successful response.
+func (l *rateLimiter) update(rate *twitter.RateLimit) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	defer l.notify()
+
+	probing := l.probing
+	l.probing = false
+	l.backoff = 0
+	if rate == nil {
+		// The quota is still unknown after the reset, so the calls are not held anymore.
+		if probing {
+			l.remaining = -1
+		}
+
+		return
+	}
+
+	l.remaining = rate.Remaining
+	l.resetAt = rate.Reset.Time()
+}
+
+// failed updates the quota by the error of a call.
+// If the API refused the call for the rate limit, a *RateLimitError is returned instead of the error.
+// If the rate limit headers are missing, the calls are paused with an exponential backoff.
+func (l *rateLimiter) failed(err error) error {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	defer l.notify()
+
+	// If the call after the reset fails without the headers, the next waiting call takes its place.
+	l.probing = false
+	rate, hasRate := twitter.RateLimitFromError(err)
+	if !isTooManyRequests(err) {
+		if hasRate {
+			l.remaining = rate.Remaining
+			l.resetAt = rate.Reset.Time()
+		}
+
+		return err
+	}
+
+	l.remaining = 0
+	if hasRate && rate.Reset.Time().After(time.Now()) {
+		l.resetAt = rate.Reset.Time()
+	} else {
+		l.backoff = min(max(2*l.backoff, minBackoff), maxBackoff)
+		l.resetAt = time.Now().Add(l.backoff)
+	}
+
+	return &RateLimitError{ResetAt: l.resetAt}
+}
+
+func isTooManyRequests(err error) bool {
+	var errResp *twitter.ErrorResponse
+	var httpErr *twitter.HTTPError
+
+	switch {
+	case errors.As(err, &errResp):
+		return errResp.StatusCode == http.StatusTooManyRequests
+	case errors.As(err, &httpErr):
+		return httpErr.StatusCode == http.StatusTooManyRequests
+	default:
+		return false
+	}
+}
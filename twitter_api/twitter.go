@@ -19,17 +19,26 @@ func (a authorize) Add(req *http.Request) {
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", a.Token))
 }
 
+// requestTimeout is the timeout of the requests to the Twitter API.
+const requestTimeout = 30 * time.Second
+
 type Client struct {
 	client    *twitter.Client
 	twitterID string
+
+	// The user lookup and the recent search endpoints have separate rate limits.
+	userLimiter   *rateLimiter
+	searchLimiter *rateLimiter
 }
 
-func NewClient(bearerToken string, twitterID string) (*Client, error) {
+// NewClient creates a client of the Twitter API.
+// When the rate limit of an endpoint is exhausted, the calls wait up to maxWait for it to reset.
+func NewClient(bearerToken string, twitterID string, maxWait time.Duration) (*Client, error) {
 	client := &twitter.Client{
 		Authorizer: authorize{
 			Token: bearerToken,
 		},
-		Client: http.DefaultClient,
+		Client: &http.Client{Timeout: requestTimeout},
 		Host:   "https://api.twitter.com",
 	}
 
@@ -47,8 +56,10 @@ func NewClient(bearerToken string, twitterID string) (*Client, error) {
 	logger.Info("found twitter", "name", res.Raw.Users[0].Name, "id", twitterID)
 
 	return &Client{
-		client:    client,
-		twitterID: twitterID,
+		client:        client,
+		twitterID:     twitterID,
+		userLimiter:   newRateLimiter(maxWait),
+		searchLimiter: newRateLimiter(maxWait),
 	}, nil
 }
 
@@ -56,11 +67,16 @@ func (c *Client) UserInfo(ctx context.Context, twitterName string) (*UserInfo, e
 	opts := twitter.UserLookupOpts{
 		UserFields: []twitter.UserField{twitter.UserFieldCreatedAt, twitter.UserFieldVerified, twitter.UserFieldPublicMetrics},
 	}
+	if err := c.userLimiter.wait(ctx); err != nil {
+		return nil, err
+	}
+
 	res, err := c.client.UserNameLookup(ctx, []string{twitterName}, opts)
 	if err != nil {
 		logger.Error("user lookup error", "error", err)
-		return nil, err
+		return nil, c.userLimiter.failed(err)
 	}
+	c.userLimiter.update(res.RateLimit)
 	if len(res.Raw.Errors) > 0 {
 		return nil, fmt.Errorf("the Twitter API returned error: %v", res.Raw.Errors[0].Detail)
 	}
@@ -97,11 +113,16 @@ func (c *Client) RetweetSearch(ctx context.Context, discordID string, twitterNam
 	query := fmt.Sprintf("%v (#Pactus OR #PactusBoosterProgram) from:%v is:quote", discordID, twitterName)
 	logger.Debug("search query", "query", query)
 
+	if err := c.searchLimiter.wait(ctx); err != nil {
+		return nil, err
+	}
+
 	res, err := c.client.TweetRecentSearch(ctx, query, opts)
 	if err != nil {
 		logger.Error("retweet lookup error", "error", err)
-		return nil, err
+		return nil, c.searchLimiter.failed(err)
 	}
+	c.searchLimiter.update(res.RateLimit)
 	if len(res.Raw.Errors) > 0 {
 		return nil, fmt.Errorf("the Twitter API returned error: %v", res.Raw.Errors[0].Detail)
 	}
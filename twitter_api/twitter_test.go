@@ -0,0 +1,181 @@
+package twitter_api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/g8rswimmer/go-twitter/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const userResponse = `{"data":{"id":"1","username":"pactus","created_at":"2020-01-01T00:00:00.000Z",` +
+	`"verified":true,"public_metrics":{"followers_count":300}}}`
+
+// fakeTwitter is a local Twitter API that answers the user lookups with the given status and rate limit headers.
+// It returns the number of the requests that it has received.
+func fakeTwitter(t *testing.T, status int, remaining int, resetAt time.Time) (*Client, *int) {
+	t.Helper()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		if !resetAt.IsZero() {
+			w.Header().Set("x-rate-limit-limit", "300")
+			w.Header().Set("x-rate-limit-remaining", strconv.Itoa(remaining))
+			w.Header().Set("x-rate-limit-reset", strconv.FormatInt(resetAt.Unix(), 10))
+		}
+		w.WriteHeader(status)
+
+		if status == http.StatusOK {
+			_, _ = w.Write([]byte(userResponse))
+		} else {
+			_, _ = w.Write([]byte(`{"title":"Too Many Requests","detail":"Too Many Requests","type":"about:blank"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := &Client{
+		client: &twitter.Client{
+			Authorizer: authorize{Token: "token"},
+			Client:     server.Client(),
+			Host:       server.URL,
+		},
+		userLimiter:   newRateLimiter(time.Second),
+		searchLimiter: newRateLimiter(time.Second),
+	}
+
+	return client, &requests
+}
+
+func TestUserInfo(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("user is found", func(t *testing.T) {
+		client, _ := fakeTwitter(t, http.StatusOK, 299, time.Now().Add(15*time.Minute))
+
+		info, err := client.UserInfo(ctx, "pactus")
+		require.NoError(t, err)
+		assert.Equal(t, "1", info.TwitterID)
+		assert.Equal(t, 300, info.Followers)
+		assert.True(t, info.IsVerified)
+		assert.Equal(t, 2020, info.CreatedAt.Year())
+	})
+
+	t.Run("exhausted quota is not called", func(t *testing.T) {
+		resetAt := time.Now().Add(15 * time.Minute)
+		client, requests := fakeTwitter(t, http.StatusOK, 0, resetAt)
+
+		_, err := client.UserInfo(ctx, "pactus")
+		assert.NoError(t, err)
+
+		_, err = client.UserInfo(ctx, "pactus")
+		assert.EqualError(t, err, "the Twitter API is busy, please try again at "+resetAt.UTC().Format("15:04")+" UTC")
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.Equal(t, 1, *requests)
+	})
+
+	t.Run("too many requests", func(t *testing.T) {
+		resetAt := time.Now().Add(time.Hour)
+		client, requests := fakeTwitter(t, http.StatusTooManyRequests, 0, resetAt)
+
+		_, err := client.UserInfo(ctx, "pactus")
+		rateErr := &RateLimitError{}
+		require.ErrorAs(t, err, &rateErr)
+		assert.Equal(t, resetAt.Unix(), rateErr.ResetAt.Unix())
+
+		_, err = client.UserInfo(ctx, "pactus")
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.Equal(t, 1, *requests)
+	})
+
+	t.Run("too many requests without rate limit headers", func(t *testing.T) {
+		client, _ := fakeTwitter(t, http.StatusTooManyRequests, 0, time.Time{})
+
+		_, err := client.UserInfo(ctx, "pactus")
+		rateErr := &RateLimitError{}
+		require.ErrorAs(t, err, &rateErr)
+		assert.WithinDuration(t, time.Now().Add(minBackoff), rateErr.ResetAt, time.Second)
+	})
+}
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("calls wait for the quota to reset", func(t *testing.T) {
+		limiter := newRateLimiter(time.Second)
+		limiter.update(&twitter.RateLimit{Remaining: 1, Reset: twitter.Epoch(time.Now().Add(time.Hour).Unix())})
+
+		assert.NoError(t, limiter.wait(ctx))
+
+		limiter.resetAt = time.Now().Add(50 * time.Millisecond)
+		start := time.Now()
+		assert.NoError(t, limiter.wait(ctx))
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("waiting calls are released one at a time after the reset", func(t *testing.T) {
+		limiter := newRateLimiter(time.Minute)
+		limiter.remaining = 0
+		limiter.resetAt = time.Now().Add(20 * time.Millisecond)
+
+		waitCtx, cancel := context.WithCancel(ctx)
+		released := make(chan error, 3)
+		for i := 0; i < 3; i++ {
+			go func() {
+				released <- limiter.wait(waitCtx)
+			}()
+		}
+
+		// Only one call is released, until its response tells the new quota.
+		assert.NoError(t, <-released)
+		assert.Never(t, func() bool { return len(released) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+
+		limiter.update(&twitter.RateLimit{Remaining: 1, Reset: twitter.Epoch(time.Now().Add(30 * time.Second).Unix())})
+		assert.NoError(t, <-released)
+		assert.Never(t, func() bool { return len(released) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+		assert.Zero(t, limiter.remaining)
+
+		cancel()
+		assert.ErrorIs(t, <-released, context.Canceled)
+	})
+
+	t.Run("calls don't wait after the context deadline", func(t *testing.T) {
+		limiter := newRateLimiter(time.Minute)
+		limiter.remaining = 0
+		limiter.resetAt = time.Now().Add(time.Second)
+
+		deadlineCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, limiter.wait(deadlineCtx), ErrRateLimited)
+	})
+
+	t.Run("backoff is doubled", func(t *testing.T) {
+		limiter := newRateLimiter(time.Minute)
+		tooMany := &twitter.HTTPError{StatusCode: http.StatusTooManyRequests}
+
+		assert.ErrorIs(t, limiter.failed(tooMany), ErrRateLimited)
+		assert.Equal(t, minBackoff, limiter.backoff)
+		assert.ErrorIs(t, limiter.failed(tooMany), ErrRateLimited)
+		assert.Equal(t, 2*minBackoff, limiter.backoff)
+
+		limiter.update(nil)
+		assert.Zero(t, limiter.backoff)
+	})
+
+	t.Run("other errors are returned", func(t *testing.T) {
+		limiter := newRateLimiter(time.Minute)
+		err := errors.New("connection refused")
+
+		assert.Equal(t, err, limiter.failed(err))
+		assert.NoError(t, limiter.wait(ctx))
+	})
+}
@@ -1,8 +1,12 @@
 package wallet
 
+import "context"
+
 type IWallet interface {
-	BondTransaction(string, string, string, int64) (string, error)
-	TransferTransaction(string, string, string, int64) (string, error)
+	BondTransaction(context.Context, string, string, string, int64) (string, error)
+	SignBondTransaction(context.Context, string, string, string, int64) (string, []byte, error)
+	BroadcastTransaction(context.Context, []byte) (string, error)
+	TransferTransaction(context.Context, string, string, string, int64) (string, error)
 	Address() string
-	Balance() int64
+	Balance(context.Context) int64
 }
@@ -10,6 +10,7 @@
 package wallet
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "go.uber.org/mock/gomock"
@@ -53,45 +54,76 @@ func (mr *MockIWalletMockRecorder) Address() *gomock.Call {
 }
 
 // Balance mocks base method.
-func (m *MockIWallet) Balance() int64 {
+func (m *MockIWallet) Balance(arg0 context.Context) int64 {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Balance")
+	ret := m.ctrl.Call(m, "Balance", arg0)
 	ret0, _ := ret[0].(int64)
 	return ret0
 }
 
 // Balance indicates an expected call of Balance.
-func (mr *MockIWalletMockRecorder) Balance() *gomock.Call {
+func (mr *MockIWalletMockRecorder) Balance(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Balance", reflect.TypeOf((*MockIWallet)(nil).Balance))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Balance", reflect.TypeOf((*MockIWallet)(nil).Balance), arg0)
 }
 
 // BondTransaction mocks base method.
-func (m *MockIWallet) BondTransaction(arg0, arg1, arg2 string, arg3 int64) (string, error) {
+func (m *MockIWallet) BondTransaction(arg0 context.Context, arg1, arg2, arg3 string, arg4 int64) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "BondTransaction", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "BondTransaction", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // BondTransaction indicates an expected call of BondTransaction.
-func (mr *MockIWalletMockRecorder) BondTransaction(arg0, arg1, arg2, arg3 any) *gomock.Call {
+func (mr *MockIWalletMockRecorder) BondTransaction(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BondTransaction", reflect.TypeOf((*MockIWallet)(nil).BondTransaction), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BondTransaction", reflect.TypeOf((*MockIWallet)(nil).BondTransaction), arg0, arg1, arg2, arg3, arg4)
+}
+
+// BroadcastTransaction mocks base method.
+func (m *MockIWallet) BroadcastTransaction(arg0 context.Context, arg1 []byte) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BroadcastTransaction", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BroadcastTransaction indicates an expected call of BroadcastTransaction.
+func (mr *MockIWalletMockRecorder) BroadcastTransaction(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BroadcastTransaction", reflect.TypeOf((*MockIWallet)(nil).BroadcastTransaction), arg0, arg1)
+}
+
+// SignBondTransaction mocks base method.
+func (m *MockIWallet) SignBondTransaction(arg0 context.Context, arg1, arg2, arg3 string, arg4 int64) (string, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignBondTransaction", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SignBondTransaction indicates an expected call of SignBondTransaction.
+func (mr *MockIWalletMockRecorder) SignBondTransaction(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignBondTransaction", reflect.TypeOf((*MockIWallet)(nil).SignBondTransaction), arg0, arg1, arg2, arg3, arg4)
 }
 
 // TransferTransaction mocks base method.
-func (m *MockIWallet) TransferTransaction(arg0, arg1, arg2 string, arg3 int64) (string, error) {
+func (m *MockIWallet) TransferTransaction(arg0 context.Context, arg1, arg2, arg3 string, arg4 int64) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "TransferTransaction", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "TransferTransaction", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // TransferTransaction indicates an expected call of TransferTransaction.
-func (mr *MockIWalletMockRecorder) TransferTransaction(arg0, arg1, arg2, arg3 any) *gomock.Call {
+func (mr *MockIWalletMockRecorder) TransferTransaction(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferTransaction", reflect.TypeOf((*MockIWallet)(nil).TransferTransaction), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferTransaction", reflect.TypeOf((*MockIWallet)(nil).TransferTransaction), arg0, arg1, arg2, arg3, arg4)
 }
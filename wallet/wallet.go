@@ -1,15 +1,20 @@
 package wallet
 
 import (
+	"context"
 	"os"
 
+	"github.com/kehiy/RoboPac/client"
 	"github.com/kehiy/RoboPac/config"
 	"github.com/kehiy/RoboPac/log"
 	"github.com/kehiy/RoboPac/utils"
 	"github.com/pactus-project/pactus/crypto"
 	"github.com/pactus-project/pactus/crypto/bls"
+	"github.com/pactus-project/pactus/types/tx"
 	"github.com/pactus-project/pactus/types/tx/payload"
 	pwallet "github.com/pactus-project/pactus/wallet"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Balance struct {
@@ -22,6 +27,13 @@ type Wallet struct {
 	password string
 	wallet   *pwallet.Wallet
 	logger   *log.SubLogger
+
+	// node is the connection to the local node, with its TLS and metadata options.
+	// The pactus wallet can only connect without them, so it is kept offline and only signs the transactions.
+	node *client.Client
+
+	// in dry-run mode transactions are signed but not broadcast.
+	dryRun bool
 }
 
 func Open(cfg *config.Config, logger *log.SubLogger) IWallet {
@@ -32,16 +44,22 @@ func Open(cfg *config.Config, logger *log.SubLogger) IWallet {
 			logger.Fatal("error opening existing wallet", "err", err)
 		}
 
-		err = wt.Connect(cfg.LocalNode)
+		node, err := client.NewClient(cfg.LocalNode, cfg.NodeOptionsOf(cfg.LocalNode), &cfg.NodeConn)
 		if err != nil {
 			logger.Fatal("error establishing connection", "err", err)
 		}
 
+		if _, err := node.GetBlockchainInfo(context.Background()); err != nil {
+			logger.Fatal("error establishing connection", "err", err)
+		}
+
 		return &Wallet{
 			wallet:   wt,
+			node:     node,
 			address:  cfg.WalletAddress,
 			password: cfg.WalletPassword,
 			logger:   logger,
+			dryRun:   cfg.DryRun,
 		}
 	}
 
@@ -49,48 +67,78 @@ func Open(cfg *config.Config, logger *log.SubLogger) IWallet {
 	return nil
 }
 
-func (w *Wallet) BondTransaction(pubKey, toAddress, memo string, amount int64) (string, error) {
-	opts := []pwallet.TxOption{
-		pwallet.OptionMemo(memo),
+func (w *Wallet) BondTransaction(ctx context.Context, pubKey, toAddress, memo string, amount int64) (string, error) {
+	_, rawTx, err := w.SignBondTransaction(ctx, pubKey, toAddress, memo, amount)
+	if err != nil {
+		return "", err
 	}
-	tx, err := w.wallet.MakeBondTx(w.address, toAddress, pubKey,
-		amount, opts...)
+
+	return w.BroadcastTransaction(ctx, rawTx)
+}
+
+// SignBondTransaction makes and signs a bond transaction without broadcasting it.
+// It returns the transaction ID and the signed transaction bytes.
+func (w *Wallet) SignBondTransaction(ctx context.Context, pubKey, toAddress, memo string,
+	amount int64,
+) (string, []byte, error) {
+	tx, err := w.makeBondTx(ctx, pubKey, toAddress, memo, amount)
 	if err != nil {
 		w.logger.Error("error creating bond transaction", "err", err, "to",
 			toAddress, "amount", utils.ChangeToCoin(amount))
-		return "", err
+		return "", nil, err
 	}
 	// sign transaction
 	err = w.wallet.SignTransaction(w.password, tx)
 	if err != nil {
 		w.logger.Error("error signing bond transaction", "err", err,
 			"to", toAddress, "amount", utils.ChangeToCoin(amount))
-		return "", err
+		return "", nil, err
 	}
 
-	// broadcast transaction
-	res, err := w.wallet.BroadcastTransaction(tx)
+	rawTx, err := tx.Bytes()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tx.ID().String(), rawTx, nil
+}
+
+// BroadcastTransaction broadcasts a signed transaction.
+// Broadcasting the same transaction again doesn't send the coins twice, since it has the same ID.
+func (w *Wallet) BroadcastTransaction(ctx context.Context, rawTx []byte) (string, error) {
+	trx, err := tx.FromBytes(rawTx)
 	if err != nil {
-		w.logger.Error("error broadcasting bond transaction", "err", err,
-			"to", toAddress, "amount", utils.ChangeToCoin(amount))
 		return "", err
 	}
 
-	err = w.wallet.Save()
+	if w.dryRun {
+		w.logger.Info("dry-run: transaction signed but not broadcast", "txID", trx.ID().String())
+		return trx.ID().String(), nil
+	}
+
+	// broadcast transaction
+	res, err := w.node.BroadcastTransaction(ctx, rawTx)
 	if err != nil {
-		w.logger.Error("error saving wallet transaction history", "err", err,
-			"to", toAddress, "amount", utils.ChangeToCoin(amount))
+		w.logger.Error("error broadcasting transaction", "err", err, "txID", trx.ID().String())
+		return "", err
 	}
+
 	return res, nil // return transaction hash
 }
 
-func (w *Wallet) TransferTransaction(pubKey, toAddress, memo string, amount int64) (string, error) {
-	fee, err := w.wallet.CalculateFee(int64(amount), payload.TypeTransfer)
+func (w *Wallet) TransferTransaction(ctx context.Context, pubKey, toAddress, memo string, amount int64) (string, error) {
+	fee, err := w.node.CalculateFee(ctx, int64(amount), payload.TypeTransfer)
+	if err != nil {
+		return "", err
+	}
+
+	height, err := w.node.GetBlockchainHeight(ctx)
 	if err != nil {
 		return "", err
 	}
 
 	opts := []pwallet.TxOption{
+		pwallet.OptionLockTime(height + 1),
 		pwallet.OptionFee(fee),
 		pwallet.OptionMemo(memo),
 	}
@@ -110,28 +158,72 @@ func (w *Wallet) TransferTransaction(pubKey, toAddress, memo string, amount int6
 		return "", err
 	}
 
+	if w.dryRun {
+		w.logger.Info("dry-run: transfer transaction signed but not broadcast", "txID", tx.ID().String(),
+			"to", toAddress, "amount", utils.ChangeToCoin(amount))
+		return tx.ID().String(), nil
+	}
+
+	rawTx, err := tx.Bytes()
+	if err != nil {
+		return "", err
+	}
+
 	// broadcast transaction
-	res, err := w.wallet.BroadcastTransaction(tx)
+	res, err := w.node.BroadcastTransaction(ctx, rawTx)
 	if err != nil {
 		w.logger.Error("error broadcasting transfer transaction", "err", err,
 			"to", toAddress, "amount", utils.ChangeToCoin(amount))
 		return "", err
 	}
 
-	err = w.wallet.Save()
+	return res, nil // return transaction hash
+}
+
+// makeBondTx makes a bond transaction, like the pactus wallet does, but through the node connection of the bot.
+// The public key is only sent if the node doesn't find the validator, any other error of the node is returned.
+func (w *Wallet) makeBondTx(ctx context.Context, pubKey, toAddress, memo string, amount int64) (*tx.Tx, error) {
+	sender, err := crypto.AddressFromString(w.address)
 	if err != nil {
-		w.logger.Error("error saving wallet transaction history", "err", err,
-			"to", toAddress, "amount", utils.ChangeToCoin(amount))
+		return nil, err
 	}
-	return res, nil // return transaction hash
+
+	receiver, err := crypto.AddressFromString(toAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	var pub *bls.PublicKey
+	if _, err = w.node.GetValidatorInfo(ctx, toAddress); err != nil {
+		if status.Code(err) != codes.NotFound {
+			return nil, err
+		}
+
+		pub, err = bls.PublicKeyFromString(pubKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	height, err := w.node.GetBlockchainHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := w.node.CalculateFee(ctx, amount, payload.TypeBond)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.NewBondTx(height+1, sender, receiver, pub, amount, fee, memo), nil
 }
 
 func (w *Wallet) Address() string {
 	return w.address
 }
 
-func (w *Wallet) Balance() int64 {
-	balance, _ := w.wallet.Balance(w.address)
+func (w *Wallet) Balance(ctx context.Context) int64 {
+	balance, _ := w.node.GetBalance(ctx, w.address)
 	return balance
 }
 
@@ -0,0 +1,21 @@
+package webhook
+
+import "time"
+
+type Config struct {
+	// ListenAddr is the address that the server listens on, e.g. `:50055`. The server is not started if it is empty.
+	ListenAddr string
+	// TLSCertFile and TLSKeyFile are the certificate and the key of the server. TLS is used if both are set.
+	TLSCertFile string
+	TLSKeyFile  string
+	// MaxBodySize is the maximum size of a request body in bytes.
+	MaxBodySize     int64
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	ShutdownTimeout time.Duration
+}
+
+// HasTLS returns true if the server should use TLS.
+func (c *Config) HasTLS() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
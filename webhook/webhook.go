@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/kehiy/RoboPac/log"
+)
+
+var (
+	// ErrAlreadyRegistered is returned when a handler is already registered with the same name.
+	ErrAlreadyRegistered = errors.New("webhook is already registered")
+	// ErrServerStarted is returned when a handler is registered after the server is started.
+	ErrServerStarted = errors.New("webhook server is already started")
+)
+
+// Server is the HTTP server of the inbound webhooks, like the IPN callbacks of the payment providers.
+// Each integration registers its handler by its name, and the handler is served on `/<name>`.
+type Server struct {
+	cfg      *Config
+	logger   *log.SubLogger
+	mux      *http.ServeMux
+	handlers map[string]http.Handler
+	server   *http.Server
+	listener net.Listener
+
+	lk sync.Mutex
+}
+
+func NewServer(cfg *Config, logger *log.SubLogger) *Server {
+	return &Server{
+		cfg:      cfg,
+		logger:   logger,
+		mux:      http.NewServeMux(),
+		handlers: make(map[string]http.Handler),
+	}
+}
+
+// Register registers the handler of the webhook on `/<name>`.
+// The handlers should be registered before the server is started.
+func (s *Server) Register(name string, handler http.Handler) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if s.server != nil {
+		return ErrServerStarted
+	}
+
+	if _, ok := s.handlers[name]; ok {
+		return fmt.Errorf("%w: %s", ErrAlreadyRegistered, name)
+	}
+
+	s.handlers[name] = handler
+	s.mux.Handle("/"+name, s.limit(name, handler))
+
+	return nil
+}
+
+// limit rejects the requests that are not POST and limits the size of the request body.
+func (s *Server) limit(name string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		if r.ContentLength > s.cfg.MaxBodySize {
+			s.logger.Warn("rejecting the webhook request, the body is too large",
+				"webhook", name, "size", r.ContentLength, "remote", r.RemoteAddr)
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxBodySize)
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Start listens on the address and serves the webhooks in the background.
+// If the address can't be listened on, the error is returned.
+func (s *Server) Start() error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if s.server != nil {
+		return ErrServerStarted
+	}
+
+	listener, err := net.Listen("tcp", s.cfg.ListenAddr)
+	if err != nil {
+		return err
+	}
+
+	s.listener = listener
+	s.server = &http.Server{
+		Handler:           s.mux,
+		ReadTimeout:       s.cfg.ReadTimeout,
+		ReadHeaderTimeout: s.cfg.ReadTimeout,
+		WriteTimeout:      s.cfg.WriteTimeout,
+	}
+
+	go func() {
+		s.logger.Info("starting the webhook server", "addr", listener.Addr().String(), "tls", s.cfg.HasTLS())
+
+		var err error
+		if s.cfg.HasTLS() {
+			err = s.server.ServeTLS(listener, s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+		} else {
+			err = s.server.Serve(listener)
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("webhook server is stopped", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Addr returns the address that the server listens on, or an empty string if it is not started.
+func (s *Server) Addr() string {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if s.listener == nil {
+		return ""
+	}
+
+	return s.listener.Addr().String()
+}
+
+// Stop shuts the server down. The requests in progress are waited for, up to the shutdown timeout.
+func (s *Server) Stop() error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if s.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	s.logger.Info("shutting the webhook server down...")
+
+	return s.server.Shutdown(ctx)
+}
@@ -0,0 +1,209 @@
+package webhook
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kehiy/RoboPac/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setup(t *testing.T, cfg *Config) *Server {
+	t.Helper()
+
+	if cfg.ListenAddr == "" {
+		cfg.ListenAddr = "127.0.0.1:0"
+	}
+	if cfg.MaxBodySize == 0 {
+		cfg.MaxBodySize = 16
+	}
+	cfg.ReadTimeout = time.Second
+	cfg.WriteTimeout = time.Second
+	cfg.ShutdownTimeout = time.Second
+
+	server := NewServer(cfg, log.NewSubLogger("webhook"))
+	t.Cleanup(func() { _ = server.Stop() })
+
+	return server
+}
+
+// echo returns the request body.
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+	_, _ = w.Write(data)
+})
+
+func post(t *testing.T, client *http.Client, url, body string) (int, string) {
+	t.Helper()
+
+	resp, err := client.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp.StatusCode, string(data)
+}
+
+func TestServer(t *testing.T) {
+	t.Run("routing", func(t *testing.T) {
+		server := setup(t, &Config{})
+		require.NoError(t, server.Register("echo", echo))
+		require.NoError(t, server.Register("teapot", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})))
+		require.NoError(t, server.Start())
+
+		url := "http://" + server.Addr()
+		code, body := post(t, http.DefaultClient, url+"/echo", "hello")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "hello", body)
+
+		code, _ = post(t, http.DefaultClient, url+"/teapot", "")
+		assert.Equal(t, http.StatusTeapot, code)
+
+		code, _ = post(t, http.DefaultClient, url+"/unknown", "")
+		assert.Equal(t, http.StatusNotFound, code)
+
+		resp, err := http.Get(url + "/echo")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+
+	t.Run("body size is limited", func(t *testing.T) {
+		server := setup(t, &Config{MaxBodySize: 4})
+		require.NoError(t, server.Register("echo", echo))
+		require.NoError(t, server.Start())
+
+		url := "http://" + server.Addr() + "/echo"
+		code, _ := post(t, http.DefaultClient, url, "1234")
+		assert.Equal(t, http.StatusOK, code)
+
+		code, _ = post(t, http.DefaultClient, url, "12345")
+		assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+
+		// Without the content length, the body is cut by the handler.
+		resp, err := http.DefaultClient.Post(url, "application/json", io.MultiReader(strings.NewReader("12345")))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("registering", func(t *testing.T) {
+		server := setup(t, &Config{})
+		require.NoError(t, server.Register("echo", echo))
+		assert.ErrorIs(t, server.Register("echo", echo), ErrAlreadyRegistered)
+
+		require.NoError(t, server.Start())
+		assert.ErrorIs(t, server.Register("other", echo), ErrServerStarted)
+		assert.ErrorIs(t, server.Start(), ErrServerStarted)
+	})
+
+	t.Run("port is taken", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		server := setup(t, &Config{ListenAddr: listener.Addr().String()})
+		assert.Error(t, server.Start())
+		assert.Empty(t, server.Addr())
+		assert.NoError(t, server.Stop())
+	})
+
+	t.Run("graceful shutdown", func(t *testing.T) {
+		started := make(chan struct{})
+		server := setup(t, &Config{})
+		require.NoError(t, server.Register("slow", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusAccepted)
+		})))
+		require.NoError(t, server.Start())
+		url := "http://" + server.Addr()
+
+		codes := make(chan int)
+		go func() {
+			resp, err := http.DefaultClient.Post(url+"/slow", "application/json", http.NoBody)
+			if err != nil {
+				codes <- 0
+
+				return
+			}
+			resp.Body.Close()
+			codes <- resp.StatusCode
+		}()
+
+		<-started
+		assert.NoError(t, server.Stop())
+		assert.Equal(t, http.StatusAccepted, <-codes)
+
+		_, err := http.DefaultClient.Post(url+"/slow", "application/json", http.NoBody)
+		assert.Error(t, err)
+	})
+
+	t.Run("TLS", func(t *testing.T) {
+		certFile, keyFile, pool := selfSignedCert(t)
+		server := setup(t, &Config{TLSCertFile: certFile, TLSKeyFile: keyFile})
+		require.NoError(t, server.Register("echo", echo))
+		require.NoError(t, server.Start())
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}}
+		code, body := post(t, client, "https://"+server.Addr()+"/echo", "secure")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "secure", body)
+	})
+}
+
+// selfSignedCert writes a self-signed certificate for 127.0.0.1 and returns its files and a pool that trusts it.
+func selfSignedCert(t *testing.T) (string, string, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := path.Join(dir, "cert.pem")
+	keyFile := path.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return certFile, keyFile, pool
+}